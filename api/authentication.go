@@ -5,17 +5,18 @@ import (
 	"fmt"
 	"net/http"
 	"onboarding/api/response"
+	"onboarding/internal/service"
 	"onboarding/pkg/token"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-func Authentication(jwt token.JWT) gin.HandlerFunc {
+func Authentication(authService service.AuthService) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var accessToken string
 
-		cookieToken, err := ctx.Cookie("access_token")
+		cookieToken, err := ctx.Cookie(token.AccessTokenCookie)
 		if err == nil && cookieToken != "" {
 			accessToken = cookieToken
 		} else {
@@ -44,7 +45,7 @@ func Authentication(jwt token.JWT) gin.HandlerFunc {
 			accessToken = fields[1]
 		}
 
-		claim, err := jwt.VerifyToken(accessToken, token.AccessTokenExpectation())
+		claim, err := authService.VerifyAccessToken(ctx.Request.Context(), accessToken)
 		if err != nil {
 			err = fmt.Errorf("Couldn't verify token: %w", err)
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, response.ErrorResponse(err))
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"onboarding/api/response"
+	"onboarding/internal/service"
+	"onboarding/pkg/authz"
+	"onboarding/pkg/token"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequirePermission only lets users granted the permission through. It reads
+// the claim set by Authentication, so it has to come after it, and before
+// Timeout since it writes the response itself.
+func RequirePermission(authorizer service.Authorizer, permission authz.Permission) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		claim, ok := claimFromContext(ctx)
+		if !ok {
+			return
+		}
+
+		if !authorize(ctx, authorizer, claim, permission) {
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// RequireSelfOrPermission lets users access their own resource, identified by
+// the UUID in the param path parameter, and users granted the permission
+// access anyone's.
+func RequireSelfOrPermission(authorizer service.Authorizer, param string, permission authz.Permission) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		claim, ok := claimFromContext(ctx)
+		if !ok {
+			return
+		}
+
+		id, err := uuid.Parse(ctx.Param(param))
+		self := err == nil && id == claim.UserID
+
+		if !self && !authorize(ctx, authorizer, claim, permission) {
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// authorize aborts the request unless the permission is granted.
+func authorize(
+	ctx *gin.Context,
+	authorizer service.Authorizer,
+	claim *token.CustomClaims,
+	permission authz.Permission,
+) bool {
+	allowed, err := authorizer.Authorize(ctx.Request.Context(), claim.UserID, permission)
+	if err != nil {
+		err = fmt.Errorf("Couldn't check permission: %w", err)
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, response.ErrorResponse(err))
+		return false
+	}
+
+	if !allowed {
+		err := errors.New("You don't have permission to access this resource")
+		ctx.AbortWithStatusJSON(http.StatusForbidden, response.ErrorResponse(err))
+		return false
+	}
+
+	return true
+}
+
+func claimFromContext(ctx *gin.Context) (*token.CustomClaims, bool) {
+	value, exists := ctx.Get(token.JWTClaim)
+	claim, ok := value.(*token.CustomClaims)
+	if !exists || !ok {
+		err := errors.New("Couldn't find token claim")
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, response.ErrorResponse(err))
+		return nil, false
+	}
+
+	return claim, true
+}
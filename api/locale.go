@@ -0,0 +1,20 @@
+package api
+
+import (
+	"onboarding/pkg/templates"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Locale keeps the languages of the Accept-Language header, so the messages
+// sent while handling the request are in one the client reads.
+func Locale() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		locales := templates.ParseAcceptLanguage(c.GetHeader("Accept-Language"))
+		if len(locales) > 0 {
+			c.Request = c.Request.WithContext(templates.WithLocales(c.Request.Context(), locales...))
+		}
+
+		c.Next()
+	}
+}
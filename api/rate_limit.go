@@ -0,0 +1,95 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"onboarding/api/response"
+	"onboarding/pkg/config"
+	"onboarding/pkg/ratelimit"
+	"onboarding/pkg/token"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitKey picks what the requests of a route are counted by.
+type RateLimitKey func(ctx *gin.Context) string
+
+// ByIP counts the requests of each client IP address.
+func ByIP() RateLimitKey {
+	return func(ctx *gin.Context) string {
+		return "ip:" + ctx.ClientIP()
+	}
+}
+
+// ByUser counts the requests of each authenticated user, so it has to come
+// after Authentication. Requests without a claim are counted by IP address.
+func ByUser() RateLimitKey {
+	return func(ctx *gin.Context) string {
+		value, _ := ctx.Get(token.JWTClaim)
+		if claim, ok := value.(*token.CustomClaims); ok {
+			return "user:" + claim.UserID.String()
+		}
+
+		return ByIP()(ctx)
+	}
+}
+
+// ByField counts the requests for each value of a form or query field, e.g.
+// the e-mail address a code is sent to. Requests without it are counted by IP
+// address.
+func ByField(name string) RateLimitKey {
+	return func(ctx *gin.Context) string {
+		value := strings.ToLower(strings.TrimSpace(ctx.Request.FormValue(name)))
+		if value == "" {
+			return ByIP()(ctx)
+		}
+
+		return name + ":" + value
+	}
+}
+
+// RateLimit turns requests away once the key has made too many. It writes the
+// response itself, so it has to come before Timeout. When the limiter fails
+// the request is let through.
+func RateLimit(limiter ratelimit.Limiter, name string, cfg config.RouteLimit, key RateLimitKey) gin.HandlerFunc {
+	limit := ratelimit.Limit{
+		Requests: cfg.Requests,
+		Period:   cfg.Period,
+	}
+	policy := fmt.Sprintf("%d;w=%d", limit.Requests, seconds(limit.Period))
+
+	return func(ctx *gin.Context) {
+		result, err := limiter.Allow(ctx.Request.Context(), name+":"+key(ctx), limit)
+		if err != nil {
+			log.Printf("Rate limit %s failed: %v", name, err)
+			ctx.Next()
+			return
+		}
+
+		ctx.Header("RateLimit-Policy", policy)
+		ctx.Header("RateLimit-Limit", strconv.FormatInt(result.Limit, 10))
+		ctx.Header("RateLimit-Remaining", strconv.FormatInt(result.Remaining, 10))
+		ctx.Header("RateLimit-Reset", strconv.Itoa(seconds(result.Reset)))
+
+		if !result.Allowed {
+			ctx.Header("Retry-After", strconv.Itoa(seconds(result.RetryAfter)))
+
+			err := errors.New("Too many requests, please try again later")
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, response.ErrorResponse(err))
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// seconds rounds up, so clients don't come back too early.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
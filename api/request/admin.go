@@ -0,0 +1,11 @@
+package request
+
+import "time"
+
+type ListUsersRequest struct {
+	Email         string     `form:"email"`
+	CreatedAfter  *time.Time `form:"created_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedBefore *time.Time `form:"created_before" time_format:"2006-01-02T15:04:05Z07:00"`
+	Page          int        `form:"page,default=1" binding:"min=1"`
+	PerPage       int        `form:"per_page,default=20" binding:"min=1,max=100"`
+}
@@ -9,3 +9,30 @@ type LoginRequest struct {
 	Email    string `form:"email" binding:"required,validEmail"`
 	Password string `form:"password" binding:"required"`
 }
+
+type LoginMFARequest struct {
+	MFAToken     string `form:"mfa_token" binding:"required"`
+	Code         string `form:"code" binding:"required_without=RecoveryCode"`
+	RecoveryCode string `form:"recovery_code" binding:"required_without=Code"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `form:"refresh_token" binding:"required"`
+}
+
+type VerifyEmailRequest struct {
+	Email string `form:"email" binding:"required,validEmail"`
+	OTP   string `form:"otp" binding:"required"`
+}
+
+type ResendVerifyEmailRequest struct {
+	Email string `form:"email" binding:"required,validEmail"`
+}
+
+type MagicLinkRequest struct {
+	Email string `form:"email" binding:"required,validEmail"`
+}
+
+type ConsumeMagicLinkRequest struct {
+	Token string `form:"token" binding:"required"`
+}
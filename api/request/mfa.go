@@ -0,0 +1,5 @@
+package request
+
+type TOTPCodeRequest struct {
+	Code string `form:"code" binding:"required"`
+}
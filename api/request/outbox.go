@@ -0,0 +1,7 @@
+package request
+
+type ListOutboxMessagesRequest struct {
+	Status  string `form:"status" binding:"omitempty,oneof=pending sending sent dead"`
+	Page    int    `form:"page,default=1" binding:"min=1"`
+	PerPage int    `form:"per_page,default=20" binding:"min=1,max=100"`
+}
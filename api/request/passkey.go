@@ -0,0 +1,5 @@
+package request
+
+type PasskeyRequest struct {
+	ID string `uri:"id" binding:"required,validUUID"`
+}
@@ -0,0 +1,28 @@
+package request
+
+type RoleRequest struct {
+	Name string `uri:"name" binding:"required"`
+}
+
+type CreateRoleRequest struct {
+	Name        string `form:"name" binding:"required,max=16,lowercase,alphanum"`
+	Description string `form:"description"`
+}
+
+type GrantPermissionRequest struct {
+	Permission string `form:"permission" binding:"required"`
+}
+
+type RolePermissionRequest struct {
+	Name       string `uri:"name" binding:"required"`
+	Permission string `uri:"permission" binding:"required"`
+}
+
+type AssignRoleRequest struct {
+	Role string `form:"role" binding:"required"`
+}
+
+type CheckPermissionRequest struct {
+	Token      string `form:"token" binding:"required"`
+	Permission string `form:"permission" binding:"required"`
+}
@@ -0,0 +1,5 @@
+package request
+
+type SessionRequest struct {
+	ID string `uri:"id" binding:"required,validUUID"`
+}
@@ -0,0 +1,34 @@
+package request
+
+type UpdatePasswordRequest struct {
+	CurrentPassword string `form:"current_password" binding:"required"`
+	NewPassword     string `form:"new_password" binding:"required,validPassword"`
+	VerifyPassword  string `form:"verify_password" binding:"required"`
+}
+
+type UpdateEmailRequest struct {
+	Password string `form:"password" binding:"required"`
+	NewEmail string `form:"new_email" binding:"required,validEmail"`
+}
+
+type ConfirmEmailRequest struct {
+	NewEmail string `form:"new_email" binding:"required,validEmail"`
+	OTP      string `form:"otp" binding:"required"`
+}
+
+type UpdatePhoneRequest struct {
+	Password string `form:"password" binding:"required"`
+	Phone    string `form:"phone" binding:"required,e164"`
+}
+
+type ConfirmPhoneRequest struct {
+	OTP string `form:"otp" binding:"required"`
+}
+
+type UpdateNotificationChannelRequest struct {
+	Channel string `form:"channel" binding:"required,oneof=email sms webhook"`
+}
+
+type UpdateLocaleRequest struct {
+	Locale string `form:"locale" binding:"max=35"`
+}
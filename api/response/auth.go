@@ -2,24 +2,41 @@ package response
 
 import (
 	entity "onboarding/internal/entity"
+	"onboarding/pkg/token"
 )
 
 type UserResponse struct {
-	Email string `json:"email"`
+	Email  string `json:"email"`
+	Status string `json:"status"`
 }
 
 func NewUserResponse(user entity.UserViewModel) UserResponse {
 	return UserResponse{
-		Email: user.Email,
+		Email:  user.Email,
+		Status: string(user.Status),
 	}
 }
 
 type LoginResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
 }
 
-func NewLoginResponse(token string) LoginResponse {
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+func NewMFAChallengeResponse(mfaToken *token.JWTToken) MFAChallengeResponse {
+	return MFAChallengeResponse{
+		MFARequired: true,
+		MFAToken:    mfaToken.SignedToken,
+	}
+}
+
+func NewLoginResponse(tokenPair *token.TokenPair) LoginResponse {
 	return LoginResponse{
-		Token: token,
+		Token:        tokenPair.AccessToken.SignedToken,
+		RefreshToken: tokenPair.RefreshToken.SignedToken,
 	}
 }
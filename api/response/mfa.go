@@ -0,0 +1,37 @@
+package response
+
+import (
+	entity "onboarding/internal/entity"
+)
+
+type TOTPEnrollmentResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+func NewTOTPEnrollmentResponse(enrollment entity.TOTPEnrollment) TOTPEnrollmentResponse {
+	return TOTPEnrollmentResponse{
+		Secret: enrollment.Secret,
+		URI:    enrollment.URI,
+	}
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func NewRecoveryCodesResponse(recoveryCodes []string) RecoveryCodesResponse {
+	return RecoveryCodesResponse{
+		RecoveryCodes: recoveryCodes,
+	}
+}
+
+type RecoveryCodeCountResponse struct {
+	Remaining int64 `json:"remaining"`
+}
+
+func NewRecoveryCodeCountResponse(remaining int64) RecoveryCodeCountResponse {
+	return RecoveryCodeCountResponse{
+		Remaining: remaining,
+	}
+}
@@ -0,0 +1,12 @@
+package response
+
+import (
+	entity "onboarding/internal/entity"
+)
+
+type OutboxMessageListResponse struct {
+	Messages []entity.OutboxMessageViewModel `json:"messages"`
+	Page     int                             `json:"page"`
+	PerPage  int                             `json:"per_page"`
+	Total    int64                           `json:"total"`
+}
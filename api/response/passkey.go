@@ -0,0 +1,31 @@
+package response
+
+import (
+	entity "onboarding/internal/entity"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PasskeyResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+func NewPasskeyResponse(passkey entity.PasskeyCredentialViewModel) PasskeyResponse {
+	return PasskeyResponse{
+		ID:         passkey.UUID,
+		CreatedAt:  passkey.CreatedAt,
+		LastUsedAt: passkey.LastUsedAt,
+	}
+}
+
+func NewPasskeyResponses(passkeys []entity.PasskeyCredentialViewModel) []PasskeyResponse {
+	result := make([]PasskeyResponse, 0, len(passkeys))
+	for _, passkey := range passkeys {
+		result = append(result, NewPasskeyResponse(passkey))
+	}
+
+	return result
+}
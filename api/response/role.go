@@ -0,0 +1,34 @@
+package response
+
+import (
+	entity "onboarding/internal/entity"
+)
+
+type RoleResponse struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	BuiltIn     bool     `json:"built_in"`
+	Permissions []string `json:"permissions"`
+}
+
+func NewRoleResponse(role entity.RoleViewModel) RoleResponse {
+	return RoleResponse{
+		Name:        string(role.Name),
+		Description: role.Description,
+		BuiltIn:     role.BuiltIn,
+		Permissions: role.Permissions,
+	}
+}
+
+func NewRoleResponses(roles []entity.RoleViewModel) []RoleResponse {
+	result := make([]RoleResponse, 0, len(roles))
+	for _, role := range roles {
+		result = append(result, NewRoleResponse(role))
+	}
+
+	return result
+}
+
+type PermissionCheckResponse struct {
+	Allowed bool `json:"allowed"`
+}
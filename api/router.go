@@ -1,8 +1,12 @@
 package api
 
 import (
+	"log"
+	"onboarding/internal/entity"
 	"onboarding/internal/handler"
+	"onboarding/internal/service"
 	"onboarding/pkg/config"
+	"onboarding/pkg/ratelimit"
 	"onboarding/pkg/token"
 	"onboarding/pkg/validation"
 
@@ -14,23 +18,59 @@ import (
 type Server struct {
 	router                *gin.Engine
 	jwtImpl               token.JWT
+	authService           service.AuthService
+	authorizer            service.Authorizer
+	limiter               ratelimit.Limiter
+	rateLimits            config.RateLimit
 	authHandler           *handler.AuthHandler
 	userHandler           *handler.UserHandler
+	sessionHandler        *handler.SessionHandler
+	mfaHandler            *handler.MFAHandler
+	passkeyHandler        *handler.PasskeyHandler
 	forgotPasswordHandler *handler.ForgotPasswordHandler
+	jwksHandler           *handler.JWKSHandler
+	roleHandler           *handler.RoleHandler
+	authzHandler          *handler.AuthzHandler
+	adminHandler          *handler.AdminHandler
+	outboxHandler         *handler.OutboxHandler
 }
 
 func NewServer(
 	cfg config.App,
+	rateLimits config.RateLimit,
 	jwtImpl token.JWT,
+	authService service.AuthService,
+	authorizer service.Authorizer,
+	limiter ratelimit.Limiter,
 	authHandler *handler.AuthHandler,
 	userHandler *handler.UserHandler,
+	sessionHandler *handler.SessionHandler,
+	mfaHandler *handler.MFAHandler,
+	passkeyHandler *handler.PasskeyHandler,
 	forgotPasswordHandler *handler.ForgotPasswordHandler,
+	jwksHandler *handler.JWKSHandler,
+	roleHandler *handler.RoleHandler,
+	authzHandler *handler.AuthzHandler,
+	adminHandler *handler.AdminHandler,
+	outboxHandler *handler.OutboxHandler,
 ) *Server {
 	server := &Server{
 		jwtImpl:               jwtImpl,
+		authService:           authService,
+		authorizer:            authorizer,
+		limiter:               limiter,
+		rateLimits:            rateLimits,
 		authHandler:           authHandler,
 		userHandler:           userHandler,
+		sessionHandler:        sessionHandler,
+		mfaHandler:            mfaHandler,
+		passkeyHandler:        passkeyHandler,
 		forgotPasswordHandler: forgotPasswordHandler,
+		jwksHandler:           jwksHandler,
+		roleHandler:           roleHandler,
+		authzHandler:          authzHandler,
+		adminHandler:          adminHandler,
+		outboxHandler:         outboxHandler,
 	}
 
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
@@ -50,39 +90,174 @@ func (server *Server) Start(port string) error {
 func (server *Server) setupRouter(cfg config.App) {
 	gin.SetMode(cfg.GinMode)
 	router := gin.Default()
+	router.Use(Locale())
+
+	router.GET("/.well-known/jwks.json", server.jwksHandler.GetJWKS)
 
 	formRoutes := router.Group("/").Use(
 		ContentTypeValidation(),
 		Timeout(cfg.Timeout),
 	)
 	{
-		formRoutes.POST("/auth/register", server.authHandler.Register)
-		formRoutes.POST("/auth/login", server.authHandler.Login)
-		formRoutes.POST("/forgot-password", server.forgotPasswordHandler.RequestResetPassword)
+		formRoutes.POST("/auth/verify-email", server.authHandler.VerifyEmail)
+		formRoutes.POST("/auth/magic-link/consume", server.authHandler.ConsumeMagicLink)
 		formRoutes.POST("/reset-password", server.forgotPasswordHandler.ResetPassword)
+		formRoutes.POST("/authz/check", server.authzHandler.CheckPermission)
+	}
+
+	registerRoutes := router.Group("/").Use(
+		ContentTypeValidation(),
+		server.rateLimit("register", ByIP()),
+		Timeout(cfg.Timeout),
+	)
+	{
+		registerRoutes.POST("/auth/register", server.authHandler.Register)
+	}
+
+	loginRoutes := router.Group("/").Use(
+		ContentTypeValidation(),
+		server.rateLimit("login", ByIP()),
+		Timeout(cfg.Timeout),
+	)
+	{
+		loginRoutes.POST("/auth/login", server.authHandler.Login)
+		loginRoutes.POST("/auth/login/mfa", server.authHandler.LoginMFA)
+	}
+
+	// Routes sending e-mails are limited per address before it's looked up,
+	// so the limit doesn't tell whether an account exists.
+	otpRoutes := router.Group("/").Use(
+		ContentTypeValidation(),
+		server.rateLimit("otp_send", ByIP()),
+		server.rateLimit("otp_email", ByField("email")),
+		Timeout(cfg.Timeout),
+	)
+	{
+		otpRoutes.POST("/auth/verify-email/resend", server.authHandler.ResendVerifyEmail)
+		otpRoutes.POST("/auth/magic-link", server.authHandler.RequestMagicLink)
+		otpRoutes.POST("/forgot-password", server.forgotPasswordHandler.RequestResetPassword)
+	}
+
+	// WebAuthn responses are JSON encoded and a refresh may carry nothing but
+	// the cookie, so these can't go through ContentTypeValidation.
+	publicRoutes := router.Group("/").Use(
+		Timeout(cfg.Timeout),
+	)
+	{
+		publicRoutes.POST("/auth/passkey/options", server.passkeyHandler.BeginLogin)
+		publicRoutes.POST("/auth/passkey", server.authHandler.LoginPasskey)
+		publicRoutes.POST("/auth/refresh", server.authHandler.Refresh)
 	}
 
 	authRoutes := router.Group("/").Use(
-		Authentication(server.jwtImpl),
+		Authentication(server.authService),
 		Timeout(cfg.Timeout),
 	)
 	{
 		authRoutes.DELETE("/auth/logout", server.authHandler.Logout)
+		authRoutes.GET("/user/sessions", server.sessionHandler.ListSessions)
+		authRoutes.DELETE("/user/sessions/:id", server.sessionHandler.RevokeSession)
+		authRoutes.DELETE("/user/sessions", server.sessionHandler.RevokeOtherSessions)
+		authRoutes.POST("/user/mfa/totp", server.mfaHandler.EnrollTOTP)
+		authRoutes.GET("/user/mfa/recovery-codes", server.mfaHandler.CountRecoveryCodes)
+		authRoutes.POST("/user/passkeys/options", server.passkeyHandler.BeginRegistration)
+		authRoutes.POST("/user/passkeys", server.passkeyHandler.FinishRegistration)
+		authRoutes.GET("/user/passkeys", server.passkeyHandler.ListPasskeys)
+		authRoutes.DELETE("/user/passkeys/:id", server.passkeyHandler.DeletePasskey)
 	}
 
 	authFormRoutes := router.Group("/").Use(
 		ContentTypeValidation(),
-		Authentication(server.jwtImpl),
+		Authentication(server.authService),
 		Timeout(cfg.Timeout),
 	)
 	{
 		authFormRoutes.GET("/user", server.userHandler.GetUser)
-		authFormRoutes.GET("/user/:uuid", server.userHandler.GetUser)
+		authFormRoutes.PUT("/user/password", server.userHandler.UpdatePassword)
+		authFormRoutes.POST("/user/email/confirm", server.userHandler.ConfirmEmail)
+		authFormRoutes.POST("/user/phone/confirm", server.userHandler.ConfirmPhone)
+		authFormRoutes.PUT("/user/notification-channel", server.userHandler.UpdateNotificationChannel)
+		authFormRoutes.PUT("/user/locale", server.userHandler.UpdateLocale)
+		authFormRoutes.POST("/user/mfa/totp/confirm", server.mfaHandler.ConfirmTOTP)
+		authFormRoutes.POST("/user/mfa/totp/disable", server.mfaHandler.DisableTOTP)
+		authFormRoutes.POST("/user/mfa/recovery-codes", server.mfaHandler.RegenerateRecoveryCodes)
+	}
+
+	otpUserRoutes := router.Group("/").Use(
+		ContentTypeValidation(),
+		Authentication(server.authService),
+		server.rateLimit("otp_user", ByUser()),
+		Timeout(cfg.Timeout),
+	)
+	{
+		otpUserRoutes.PUT("/user/email", server.userHandler.UpdateEmail)
+		otpUserRoutes.PUT("/user/phone", server.userHandler.UpdatePhone)
+	}
+
+	userRoutes := router.Group("/").Use(
+		ContentTypeValidation(),
+		Authentication(server.authService),
+		RequireSelfOrPermission(server.authorizer, "uuid", entity.PermissionUsersRead),
+		Timeout(cfg.Timeout),
+	)
+	{
+		userRoutes.GET("/user/:uuid", server.userHandler.GetUser)
+	}
+
+	// Each admin route checks its own permission, which has to run before
+	// Timeout.
+	adminRoutes := router.Group("/admin").Use(
+		ContentTypeValidation(),
+		Authentication(server.authService),
+	)
+	{
+		rolesRead := RequirePermission(server.authorizer, entity.PermissionRolesRead)
+		rolesWrite := RequirePermission(server.authorizer, entity.PermissionRolesWrite)
+		usersRead := RequirePermission(server.authorizer, entity.PermissionUsersRead)
+		usersWrite := RequirePermission(server.authorizer, entity.PermissionUsersWrite)
+		outboxRead := RequirePermission(server.authorizer, entity.PermissionOutboxRead)
+		outboxWrite := RequirePermission(server.authorizer, entity.PermissionOutboxWrite)
+		timeout := Timeout(cfg.Timeout)
+
+		adminRoutes.GET("/roles", rolesRead, timeout, server.roleHandler.ListRoles)
+		adminRoutes.POST("/roles", rolesWrite, timeout, server.roleHandler.CreateRole)
+		adminRoutes.DELETE("/roles/:name", rolesWrite, timeout, server.roleHandler.DeleteRole)
+		adminRoutes.POST("/roles/:name/permissions", rolesWrite, timeout, server.roleHandler.GrantPermission)
+		adminRoutes.DELETE(
+			"/roles/:name/permissions/:permission",
+			rolesWrite,
+			timeout,
+			server.roleHandler.RevokePermission,
+		)
+		adminRoutes.PUT("/users/:uuid/role", rolesWrite, timeout, server.roleHandler.AssignRole)
+
+		adminRoutes.GET("/users", usersRead, timeout, server.adminHandler.ListUsers)
+		adminRoutes.GET("/users/:uuid", usersRead, timeout, server.adminHandler.GetUser)
+		adminRoutes.POST("/users/:uuid/suspend", usersWrite, timeout, server.adminHandler.SuspendUser)
+		adminRoutes.POST("/users/:uuid/unsuspend", usersWrite, timeout, server.adminHandler.UnsuspendUser)
+		adminRoutes.POST("/users/:uuid/password-reset", usersWrite, timeout, server.adminHandler.SendPasswordReset)
+		adminRoutes.DELETE("/users/:uuid/sessions", usersWrite, timeout, server.adminHandler.RevokeSessions)
+		adminRoutes.DELETE("/users/:uuid/lockout", usersWrite, timeout, server.adminHandler.ClearLockout)
+		adminRoutes.DELETE("/users/:uuid", usersWrite, timeout, server.adminHandler.DeleteUser)
+
+		adminRoutes.GET("/outbox", outboxRead, timeout, server.outboxHandler.ListMessages)
+		adminRoutes.GET("/outbox/:uuid", outboxRead, timeout, server.outboxHandler.GetMessage)
+		adminRoutes.POST("/outbox/:uuid/replay", outboxWrite, timeout, server.outboxHandler.ReplayMessage)
 	}
 
 	server.router = router
 }
 
+// rateLimit limits a route with the configured limit of the given name.
+func (server *Server) rateLimit(name string, key RateLimitKey) gin.HandlerFunc {
+	limit, ok := server.rateLimits.Routes[name]
+	if !ok {
+		log.Fatalf("Rate limit %s is not configured", name)
+	}
+
+	return RateLimit(server.limiter, name, limit, key)
+}
+
 func (server *Server) start(port string) error {
 	return server.router.Run(port)
 }
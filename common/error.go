@@ -1,24 +1,33 @@
 package common
 
 import (
-	"database/sql"
 	"errors"
 	"fmt"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/mattn/go-sqlite3"
+	"gorm.io/gorm"
 )
 
 const (
 	ErrUniqueViolation = "2067"
 )
 
-var ErrRecordNotFound = sql.ErrNoRows
+var ErrRecordNotFound = gorm.ErrRecordNotFound
 
 type Error int
 
 const (
 	ErrCredentiials Error = iota
+	// ErrAccountNotVerified is returned on login until the e-mail address of
+	// the account has been verified.
+	ErrAccountNotVerified
+	// ErrAccountDisabled is returned on login for suspended or deleted
+	// accounts.
+	ErrAccountDisabled
+	// ErrAccountLocked is returned on login while the account or the IP
+	// address is locked out after too many failed attempts.
+	ErrAccountLocked
 )
 
 func ErrorCode(err error) string {
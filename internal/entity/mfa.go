@@ -0,0 +1,23 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+type MFARecoveryCode struct {
+	ID       int64
+	UserUUID uuid.UUID
+	// Lookup is the start of the code, which finds it without checking
+	// every hash.
+	Lookup    string
+	CodeHash  string
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
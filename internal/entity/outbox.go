@@ -0,0 +1,81 @@
+package entity
+
+import (
+	"onboarding/pkg/notify"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OutboxStatus string
+
+const (
+	OutboxStatusPending OutboxStatus = "pending"
+	// OutboxStatusSending is the status of a message a worker has leased.
+	OutboxStatusSending OutboxStatus = "sending"
+	OutboxStatusSent    OutboxStatus = "sent"
+	// OutboxStatusDead is the status of a message that ran out of retries.
+	// It stays there until an admin replays it.
+	OutboxStatusDead OutboxStatus = "dead"
+)
+
+type OutboxMessage struct {
+	ID        int64
+	UUID      uuid.UUID
+	Channel   notify.Channel
+	Recipient string
+	Subject   string
+	// HTML and Text are cleared once the message is sent, or dead-lettered
+	// when it expires.
+	HTML          string
+	Text          string
+	Status        OutboxStatus
+	Attempts      int64
+	LastError     string
+	NextAttemptAt time.Time
+	// ExpiresAt is set on messages carrying a one-time code or link.
+	ExpiresAt *time.Time
+	SentAt    *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// OutboxFilter narrows down the messages listed to admins. Zero fields don't
+// filter.
+type OutboxFilter struct {
+	Status OutboxStatus
+	Limit  int
+	Offset int
+}
+
+// OutboxMessageViewModel leaves out the bodies, which may hold one-time
+// codes.
+type OutboxMessageViewModel struct {
+	UUID          uuid.UUID
+	Channel       notify.Channel `json:"channel"`
+	Recipient     string         `json:"recipient"`
+	Subject       string         `json:"subject"`
+	Status        OutboxStatus   `json:"status"`
+	Attempts      int64          `json:"attempts"`
+	LastError     string         `json:"last_error"`
+	NextAttemptAt time.Time      `json:"next_attempt_at"`
+	ExpiresAt     *time.Time     `json:"expires_at"`
+	SentAt        *time.Time     `json:"sent_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+}
+
+func (e OutboxMessage) ToViewModel() OutboxMessageViewModel {
+	return OutboxMessageViewModel{
+		UUID:          e.UUID,
+		Channel:       e.Channel,
+		Recipient:     e.Recipient,
+		Subject:       e.Subject,
+		Status:        e.Status,
+		Attempts:      e.Attempts,
+		LastError:     e.LastError,
+		NextAttemptAt: e.NextAttemptAt,
+		ExpiresAt:     e.ExpiresAt,
+		SentAt:        e.SentAt,
+		CreatedAt:     e.CreatedAt,
+	}
+}
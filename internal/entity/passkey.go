@@ -0,0 +1,32 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PasskeyCredential struct {
+	UUID         uuid.UUID
+	UserUUID     uuid.UUID
+	CredentialID []byte
+	// Credential is the JSON encoded webauthn.Credential, including the
+	// public key and the signature counter.
+	Credential []byte
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+}
+
+type PasskeyCredentialViewModel struct {
+	UUID       uuid.UUID
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+}
+
+func (e PasskeyCredential) ToViewModel() PasskeyCredentialViewModel {
+	return PasskeyCredentialViewModel{
+		UUID:       e.UUID,
+		CreatedAt:  e.CreatedAt,
+		LastUsedAt: e.LastUsedAt,
+	}
+}
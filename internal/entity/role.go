@@ -0,0 +1,56 @@
+package entity
+
+import (
+	"onboarding/pkg/authz"
+	"time"
+)
+
+// Permissions checked by the routes of this service.
+var (
+	PermissionUsersRead   = authz.NewPermission("users", "read")
+	PermissionUsersWrite  = authz.NewPermission("users", "write")
+	PermissionRolesRead   = authz.NewPermission("roles", "read")
+	PermissionRolesWrite  = authz.NewPermission("roles", "write")
+	PermissionOutboxRead  = authz.NewPermission("outbox", "read")
+	PermissionOutboxWrite = authz.NewPermission("outbox", "write")
+)
+
+type Role struct {
+	Name        UserRole
+	Description string
+	// BuiltIn roles are referenced by the code and can't be deleted.
+	BuiltIn   bool
+	CreatedAt time.Time
+}
+
+type RolePermission struct {
+	RoleName UserRole
+	Resource string
+	Action   string
+}
+
+func (e RolePermission) Permission() authz.Permission {
+	return authz.NewPermission(e.Resource, e.Action)
+}
+
+type RoleViewModel struct {
+	Name        UserRole
+	Description string
+	BuiltIn     bool
+	Permissions []string
+}
+
+func (e Role) ToViewModel(permissions []RolePermission) RoleViewModel {
+	result := RoleViewModel{
+		Name:        e.Name,
+		Description: e.Description,
+		BuiltIn:     e.BuiltIn,
+		Permissions: make([]string, 0, len(permissions)),
+	}
+
+	for _, p := range permissions {
+		result.Permissions = append(result.Permissions, p.Permission().String())
+	}
+
+	return result
+}
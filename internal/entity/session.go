@@ -0,0 +1,41 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Session struct {
+	UUID       uuid.UUID
+	UserUUID   uuid.UUID
+	UserAgent  string
+	IP         string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	RevokedAt  *time.Time
+}
+
+// SessionClient describes the device a session is created from.
+type SessionClient struct {
+	UserAgent string
+	IP        string
+}
+
+type SessionViewModel struct {
+	UUID       uuid.UUID
+	UserAgent  string
+	IP         string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+}
+
+func (e Session) ToViewModel() SessionViewModel {
+	return SessionViewModel{
+		UUID:       e.UUID,
+		UserAgent:  e.UserAgent,
+		IP:         e.IP,
+		CreatedAt:  e.CreatedAt,
+		LastSeenAt: e.LastSeenAt,
+	}
+}
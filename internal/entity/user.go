@@ -1,21 +1,100 @@
 package entity
 
-import "github.com/google/uuid"
+import (
+	"onboarding/pkg/notify"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type UserStatus string
+
+const (
+	// UserStatusPendingVerification is the status of a new account until the
+	// e-mail address has been verified.
+	UserStatusPendingVerification UserStatus = "pending_verification"
+	UserStatusActive              UserStatus = "active"
+	UserStatusSuspended           UserStatus = "suspended"
+	UserStatusDeleted             UserStatus = "deleted"
+)
+
+type UserRole string
+
+const (
+	UserRoleUser UserRole = "user"
+	// UserRoleSupport can read other users' records to help them out.
+	UserRoleSupport UserRole = "support"
+	UserRoleAdmin   UserRole = "admin"
+)
 
 type User struct {
 	UUID     uuid.UUID
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string     `json:"email"`
+	Password string     `json:"password"`
+	Status   UserStatus `json:"status"`
+	Role     UserRole   `json:"role"`
+	// TokensValidAfter rejects every token issued before it, e.g. after the
+	// password has been changed.
+	TokensValidAfter *time.Time
+	// TOTPSecret is set as soon as enrollment starts, TOTPEnabled only once
+	// the first code has been confirmed.
+	TOTPSecret  string
+	TOTPEnabled bool
+	// Phone is in E.164 format. It's only used for notifications once
+	// PhoneVerifiedAt is set.
+	Phone           string
+	PhoneVerifiedAt *time.Time
+	// NotificationChannel is where codes and notices go when their service
+	// doesn't ask for a specific channel.
+	NotificationChannel notify.Channel
+	// Locale is the BCP 47 tag of the language messages are sent in. When
+	// it's empty they follow the client's Accept-Language.
+	Locale    string
+	CreatedAt time.Time
+}
+
+// VerifiedPhone returns the phone number if it has been verified.
+func (e User) VerifiedPhone() string {
+	if e.PhoneVerifiedAt == nil {
+		return ""
+	}
+
+	return e.Phone
+}
+
+// UserFilter narrows down the users listed to admins. Zero fields don't
+// filter.
+type UserFilter struct {
+	// Email matches any part of the address, case-insensitively.
+	Email         string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Limit         int
+	Offset        int
 }
 
 type UserViewModel struct {
-	UUID  uuid.UUID
-	Email string `json:"email"`
+	UUID                uuid.UUID
+	Email               string         `json:"email"`
+	Status              UserStatus     `json:"status"`
+	Role                UserRole       `json:"role"`
+	Phone               string         `json:"phone"`
+	PhoneVerified       bool           `json:"phone_verified"`
+	NotificationChannel notify.Channel `json:"notification_channel"`
+	Locale              string         `json:"locale"`
+	CreatedAt           time.Time      `json:"created_at"`
 }
 
 func (e User) ToViewModel() UserViewModel {
 	return UserViewModel{
-		UUID:  e.UUID,
-		Email: e.Email,
+		UUID:                e.UUID,
+		Email:               e.Email,
+		Status:              e.Status,
+		Role:                e.Role,
+		Phone:               e.Phone,
+		PhoneVerified:       e.PhoneVerifiedAt != nil,
+		NotificationChannel: e.NotificationChannel,
+		Locale:              e.Locale,
+		CreatedAt:           e.CreatedAt,
 	}
 }
@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	apiHelper "onboarding/api/helper"
+	"onboarding/api/request"
+	"onboarding/api/response"
+	"onboarding/common"
+	"onboarding/internal/entity"
+	"onboarding/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AdminHandler serves the admin endpoints managing users. Who may call them
+// is checked by the routes' RequirePermission.
+type AdminHandler struct {
+	adminService service.AdminService
+}
+
+func NewAdminHandler(adminService service.AdminService) *AdminHandler {
+	return &AdminHandler{adminService: adminService}
+}
+
+func (h *AdminHandler) ListUsers(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		var req request.ListUsersRequest
+		if err := ctx.ShouldBindQuery(&req); err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      common.ErrorValidation(err),
+			}
+			return
+		}
+
+		users, total, err := h.adminService.ListUsers(c, entity.UserFilter{
+			Email:         req.Email,
+			CreatedAfter:  req.CreatedAfter,
+			CreatedBefore: req.CreatedBefore,
+			Limit:         req.PerPage,
+			Offset:        (req.Page - 1) * req.PerPage,
+		})
+		if err != nil {
+			resChan <- adminErrorResponse(err)
+			return
+		}
+
+		resChan <- apiHelper.ResponseData{
+			StatusCode: http.StatusOK,
+			Message:    "Users retrieved successfully.",
+			Data: response.UserListResponse{
+				Users:   users,
+				Page:    req.Page,
+				PerPage: req.PerPage,
+				Total:   total,
+			},
+		}
+	})
+}
+
+func (h *AdminHandler) GetUser(ctx *gin.Context) {
+	h.withUser(ctx, func(c context.Context, userUUID uuid.UUID) apiHelper.ResponseData {
+		user, err := h.adminService.GetUser(c, userUUID)
+		if err != nil {
+			return adminErrorResponse(err)
+		}
+
+		return apiHelper.ResponseData{
+			StatusCode: http.StatusOK,
+			Message:    "User retrieved successfully.",
+			Data:       user,
+		}
+	})
+}
+
+func (h *AdminHandler) SuspendUser(ctx *gin.Context) {
+	h.withUser(ctx, func(c context.Context, userUUID uuid.UUID) apiHelper.ResponseData {
+		if err := h.adminService.SuspendUser(c, userUUID); err != nil {
+			return adminErrorResponse(err)
+		}
+
+		return apiHelper.ResponseData{
+			StatusCode: http.StatusOK,
+			Message:    "User suspended successfully.",
+		}
+	})
+}
+
+func (h *AdminHandler) UnsuspendUser(ctx *gin.Context) {
+	h.withUser(ctx, func(c context.Context, userUUID uuid.UUID) apiHelper.ResponseData {
+		if err := h.adminService.UnsuspendUser(c, userUUID); err != nil {
+			return adminErrorResponse(err)
+		}
+
+		return apiHelper.ResponseData{
+			StatusCode: http.StatusOK,
+			Message:    "User unsuspended successfully.",
+		}
+	})
+}
+
+func (h *AdminHandler) SendPasswordReset(ctx *gin.Context) {
+	h.withUser(ctx, func(c context.Context, userUUID uuid.UUID) apiHelper.ResponseData {
+		if err := h.adminService.SendPasswordReset(c, userUUID); err != nil {
+			return adminErrorResponse(err)
+		}
+
+		return apiHelper.ResponseData{
+			StatusCode: http.StatusAccepted,
+			Message:    "Password reset e-mail sent successfully.",
+		}
+	})
+}
+
+func (h *AdminHandler) RevokeSessions(ctx *gin.Context) {
+	h.withUser(ctx, func(c context.Context, userUUID uuid.UUID) apiHelper.ResponseData {
+		if err := h.adminService.RevokeSessions(c, userUUID); err != nil {
+			return adminErrorResponse(err)
+		}
+
+		return apiHelper.ResponseData{
+			StatusCode: http.StatusOK,
+			Message:    "Sessions revoked successfully.",
+		}
+	})
+}
+
+func (h *AdminHandler) ClearLockout(ctx *gin.Context) {
+	h.withUser(ctx, func(c context.Context, userUUID uuid.UUID) apiHelper.ResponseData {
+		if err := h.adminService.ClearLockout(c, userUUID); err != nil {
+			return adminErrorResponse(err)
+		}
+
+		return apiHelper.ResponseData{
+			StatusCode: http.StatusOK,
+			Message:    "Lockout cleared successfully.",
+		}
+	})
+}
+
+func (h *AdminHandler) DeleteUser(ctx *gin.Context) {
+	h.withUser(ctx, func(c context.Context, userUUID uuid.UUID) apiHelper.ResponseData {
+		if err := h.adminService.DeleteUser(c, userUUID); err != nil {
+			return adminErrorResponse(err)
+		}
+
+		return apiHelper.ResponseData{
+			StatusCode: http.StatusOK,
+			Message:    "User deleted successfully.",
+		}
+	})
+}
+
+// withUser runs handle with the user in the :uuid path parameter and sends
+// what it returns.
+func (h *AdminHandler) withUser(
+	ctx *gin.Context,
+	handle func(c context.Context, userUUID uuid.UUID) apiHelper.ResponseData,
+) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		var req request.GetDataByUUIDRequest
+		if err := ctx.ShouldBindUri(&req); err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      common.ErrorValidation(err),
+			}
+			return
+		}
+
+		userUUID, err := uuid.Parse(req.UUID)
+		if err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      err,
+			}
+			return
+		}
+
+		resChan <- handle(c, userUUID)
+	})
+}
+
+// adminErrorResponse maps the errors of the user management.
+func adminErrorResponse(err error) apiHelper.ResponseData {
+	var statusCode = http.StatusInternalServerError
+	switch {
+	case errors.Is(err, common.ErrRecordNotFound):
+		err = errors.New("User is not found.")
+		statusCode = http.StatusNotFound
+	case errors.Is(err, service.ErrUserNotActive), errors.Is(err, service.ErrUserNotSuspended):
+		statusCode = http.StatusConflict
+	}
+
+	return apiHelper.ResponseData{
+		StatusCode: statusCode,
+		Error:      err,
+	}
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"onboarding/common"
+	"onboarding/internal/service"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAdminService keeps whether each user it knows is suspended.
+type fakeAdminService struct {
+	service.AdminService
+	suspended map[uuid.UUID]bool
+}
+
+func (s *fakeAdminService) SuspendUser(ctx context.Context, userUUID uuid.UUID) error {
+	suspended, ok := s.suspended[userUUID]
+	switch {
+	case !ok:
+		return common.ErrRecordNotFound
+	case suspended:
+		return service.ErrUserNotActive
+	}
+
+	s.suspended[userUUID] = true
+	return nil
+}
+
+func TestSuspendUser(t *testing.T) {
+	userUUID := uuid.New()
+	h := NewAdminHandler(&fakeAdminService{suspended: map[uuid.UUID]bool{userUUID: false}})
+
+	testCases := []struct {
+		uuid       string
+		statusCode int
+	}{
+		{"not-a-uuid", http.StatusBadRequest},
+		{uuid.NewString(), http.StatusNotFound},
+		{userUUID.String(), http.StatusOK},
+		{userUUID.String(), http.StatusConflict},
+	}
+
+	for _, tc := range testCases {
+		responses, _ := serve(h.SuspendUser, testRequest{
+			method: http.MethodPost,
+			target: "/admin/users/" + tc.uuid + "/suspend",
+			params: gin.Params{{Key: "uuid", Value: tc.uuid}},
+		})
+
+		require.Len(t, responses, 1, tc.uuid)
+		require.Equal(t, tc.statusCode, responses[0].StatusCode, tc.uuid)
+	}
+}
@@ -4,13 +4,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
+	"math"
 	"net/http"
 	apiHelper "onboarding/api/helper"
 	"onboarding/api/request"
 	"onboarding/api/response"
 	"onboarding/common"
+	"onboarding/internal/entity"
+	"onboarding/internal/repository/otp"
+	"onboarding/internal/repository/refresh"
 	"onboarding/internal/service"
 	"onboarding/pkg/token"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -18,10 +24,14 @@ import (
 
 type AuthHandler struct {
 	authService service.AuthService
+	otpService  service.OtpService
 }
 
-func NewAuthHandler(authService service.AuthService) *AuthHandler {
-	return &AuthHandler{authService: authService}
+func NewAuthHandler(authService service.AuthService, otpService service.OtpService) *AuthHandler {
+	return &AuthHandler{
+		authService: authService,
+		otpService:  otpService,
+	}
 }
 
 func (h *AuthHandler) Register(ctx *gin.Context) {
@@ -32,29 +42,90 @@ func (h *AuthHandler) Register(ctx *gin.Context) {
 				StatusCode: http.StatusBadRequest,
 				Error:      common.ErrorValidation(err),
 			}
+			return
 		}
 
-		result, err := h.authService.Register(c, req.Email, req.Password)
+		result, err := h.authService.Register(c, req.Email, req.Password, ctx.ClientIP())
 
 		if err != nil {
 			err := err
-			if common.ErrorCode(err) == common.ErrUniqueViolation {
+			statusCode := http.StatusInternalServerError
+			switch {
+			case common.ErrorCode(err) == common.ErrUniqueViolation:
 				err = errors.New("E-mail is already registered.")
+			case errors.Is(err, otp.ErrOtpCooldown), errors.Is(err, otp.ErrOtpQuotaExceeded):
+				statusCode = http.StatusTooManyRequests
 			}
 			resChan <- apiHelper.ResponseData{
-				StatusCode: http.StatusInternalServerError,
+				StatusCode: statusCode,
 				Error:      err,
 			}
+			return
 		}
 
 		resChan <- apiHelper.ResponseData{
 			StatusCode: http.StatusCreated,
-			Message:    "Registration completed successfully.",
+			Message:    "Registration completed successfully. Verify your e-mail with the code we sent you.",
 			Data:       response.NewUserResponse(result),
 		}
 	})
 }
 
+func (h *AuthHandler) VerifyEmail(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		var req request.VerifyEmailRequest
+		if err := ctx.ShouldBind(&req); err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      common.ErrorValidation(err),
+			}
+			return
+		}
+
+		if err := h.otpService.VerifyOtpVerifyEmail(c, req.Email, req.OTP); err != nil {
+			var statusCode = http.StatusBadRequest
+			switch {
+			case errors.Is(err, service.ErrEmailAlreadyVerified):
+				statusCode = http.StatusConflict
+			case errors.Is(err, common.ErrRecordNotFound):
+				err = otp.ErrOtpExpired
+			}
+			resChan <- apiHelper.ResponseData{
+				StatusCode: statusCode,
+				Error:      err,
+			}
+			return
+		}
+
+		resChan <- apiHelper.ResponseData{
+			StatusCode: http.StatusOK,
+			Message:    "E-mail verified successfully.",
+		}
+	})
+}
+
+func (h *AuthHandler) ResendVerifyEmail(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		var req request.ResendVerifyEmailRequest
+		if err := ctx.ShouldBind(&req); err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      common.ErrorValidation(err),
+			}
+			return
+		}
+
+		if err := h.otpService.SendOtpVerifyEmail(c, req.Email, ctx.ClientIP()); err != nil {
+			log.Printf("Verification email send failed for %s: %v", req.Email, err)
+		}
+
+		resChan <- apiHelper.ResponseData{
+			StatusCode: http.StatusCreated,
+			Message:    "If your email is awaiting verification, an OTP has been sent.",
+		}
+	})
+}
+
 func (h *AuthHandler) Login(ctx *gin.Context) {
 	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
 		var req request.LoginRequest
@@ -63,10 +134,19 @@ func (h *AuthHandler) Login(ctx *gin.Context) {
 				StatusCode: http.StatusBadRequest,
 				Error:      common.ErrorValidation(err),
 			}
+			return
 		}
 
-		token, err := h.authService.Login(c, req.Email, req.Password)
+		result, err := h.authService.Login(c, req.Email, req.Password, sessionClient(ctx))
 		if err != nil {
+			if res, ok := lockoutResponse(ctx, err); ok {
+				resChan <- res
+				return
+			}
+			if res, ok := accountStatusResponse(err); ok {
+				resChan <- res
+				return
+			}
 			var statusCode = http.StatusInternalServerError
 			if errors.Is(err, common.ErrRecordNotFound) || common.ErrorCode(err) == fmt.Sprint(common.ErrCredentiials) {
 				err = errors.New("E-mail or Password is incorrect")
@@ -76,36 +156,306 @@ func (h *AuthHandler) Login(ctx *gin.Context) {
 				StatusCode: statusCode,
 				Error:      err,
 			}
+			return
 		}
-		if token == nil {
+
+		resChan <- loginResultResponse(ctx, result)
+	})
+}
+
+func (h *AuthHandler) RequestMagicLink(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		var req request.MagicLinkRequest
+		if err := ctx.ShouldBind(&req); err != nil {
 			resChan <- apiHelper.ResponseData{
-				StatusCode: http.StatusInternalServerError,
-				Error:      errors.New("Failed to generate token."),
+				StatusCode: http.StatusBadRequest,
+				Error:      common.ErrorValidation(err),
 			}
+			return
 		}
 
-		setAuthCookies(ctx, token)
+		if err := h.otpService.SendMagicLink(c, req.Email, ctx.ClientIP()); err != nil {
+			log.Printf("Magic link email send failed for %s: %v", req.Email, err)
+		}
+
+		resChan <- apiHelper.ResponseData{
+			StatusCode: http.StatusCreated,
+			Message:    "If your email exists, a sign-in link has been sent.",
+		}
+	})
+}
+
+// ConsumeMagicLink only takes the token from a POST, so following the link
+// doesn't use it up.
+func (h *AuthHandler) ConsumeMagicLink(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		var req request.ConsumeMagicLinkRequest
+		if err := ctx.ShouldBind(&req); err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      common.ErrorValidation(err),
+			}
+			return
+		}
+
+		result, err := h.authService.LoginMagicLink(c, req.Token, sessionClient(ctx))
+		if err != nil {
+			if res, ok := accountStatusResponse(err); ok {
+				resChan <- res
+				return
+			}
+			var statusCode = http.StatusInternalServerError
+			if errors.Is(err, otp.ErrMagicLinkInvalid) || errors.Is(err, common.ErrRecordNotFound) {
+				err = otp.ErrMagicLinkInvalid
+				statusCode = http.StatusUnauthorized
+			}
+			resChan <- apiHelper.ResponseData{
+				StatusCode: statusCode,
+				Error:      err,
+			}
+			return
+		}
+
+		resChan <- loginResultResponse(ctx, result)
+	})
+}
+
+// loginResultResponse sets the cookies of a new session, or asks for the
+// second factor when the login isn't complete yet.
+func loginResultResponse(ctx *gin.Context, result *service.LoginResult) apiHelper.ResponseData {
+	if result == nil || (result.TokenPair == nil && result.MFAToken == nil) {
+		return apiHelper.ResponseData{
+			StatusCode: http.StatusInternalServerError,
+			Error:      errors.New("Failed to generate token."),
+		}
+	}
+
+	if result.MFAToken != nil {
+		return apiHelper.ResponseData{
+			StatusCode: http.StatusOK,
+			Message:    "Two-factor authentication is required.",
+			Data:       response.NewMFAChallengeResponse(result.MFAToken),
+		}
+	}
+
+	setAuthCookies(ctx, result.TokenPair)
+
+	return apiHelper.ResponseData{
+		StatusCode: http.StatusOK,
+		Message:    "Login successful.",
+		Data:       response.NewLoginResponse(result.TokenPair),
+	}
+}
+
+func (h *AuthHandler) LoginMFA(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		var req request.LoginMFARequest
+		if err := ctx.ShouldBind(&req); err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      common.ErrorValidation(err),
+			}
+			return
+		}
+
+		tokenPair, err := h.authService.LoginMFA(c, req.MFAToken, req.Code, req.RecoveryCode, sessionClient(ctx))
+		if err != nil {
+			if res, ok := accountStatusResponse(err); ok {
+				resChan <- res
+				return
+			}
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusUnauthorized,
+				Error:      err,
+			}
+			return
+		}
+
+		setAuthCookies(ctx, tokenPair)
 
 		resChan <- apiHelper.ResponseData{
 			StatusCode: http.StatusOK,
 			Message:    "Login successful.",
-			Data:       response.NewLoginResponse(token.SignedToken),
+			Data:       response.NewLoginResponse(tokenPair),
 		}
 	})
 }
 
-func (h *AuthHandler) Logout(ctx *gin.Context) {
+// LoginPasskey finishes a login ceremony started at the passkey login options
+// endpoint. The body is the JSON encoded PublicKeyCredential.
+func (h *AuthHandler) LoginPasskey(ctx *gin.Context) {
 	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
-		setAuthCookies(ctx, nil)
+		body, err := ctx.GetRawData()
+		if err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      err,
+			}
+			return
+		}
+
+		tokenPair, err := h.authService.LoginPasskey(c, body, sessionClient(ctx))
+		if err != nil {
+			if res, ok := accountStatusResponse(err); ok {
+				resChan <- res
+				return
+			}
+			resChan <- passkeyErrorResponse(err)
+			return
+		}
+
+		setAuthCookies(ctx, tokenPair)
 
 		resChan <- apiHelper.ResponseData{
 			StatusCode: http.StatusOK,
-			Message:    "Logout successful.",
+			Message:    "Login successful.",
+			Data:       response.NewLoginResponse(tokenPair),
 		}
 	})
 }
 
-func setAuthCookies(ctx *gin.Context, token *token.JWTToken) {
+func (h *AuthHandler) Refresh(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		refreshToken, err := ctx.Cookie(token.RefreshTokenCookie)
+		if err != nil || refreshToken == "" {
+			var req request.RefreshTokenRequest
+			if err := ctx.ShouldBind(&req); err != nil {
+				resChan <- apiHelper.ResponseData{
+					StatusCode: http.StatusBadRequest,
+					Error:      common.ErrorValidation(err),
+				}
+				return
+			}
+
+			refreshToken = req.RefreshToken
+		}
+
+		tokenPair, err := h.authService.Refresh(c, refreshToken)
+		if err != nil {
+			resChan <- refreshErrorResponse(ctx, err)
+			return
+		}
+
+		setAuthCookies(ctx, tokenPair)
+
+		resChan <- apiHelper.ResponseData{
+			StatusCode: http.StatusOK,
+			Message:    "Token refreshed successfully.",
+			Data:       response.NewLoginResponse(tokenPair),
+		}
+	})
+}
+
+// refreshErrorResponse clears the cookies when the refresh token is no good
+// anymore. They're kept on other errors, so the client can try again.
+func refreshErrorResponse(ctx *gin.Context, err error) apiHelper.ResponseData {
+	if res, ok := accountStatusResponse(err); ok {
+		setAuthCookies(ctx, nil)
+		return res
+	}
+
+	var statusCode = http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrTokenInvalid),
+		errors.Is(err, service.ErrSessionRevoked),
+		errors.Is(err, service.ErrCredentialsChanged),
+		errors.Is(err, refresh.ErrTokenRevoked),
+		errors.Is(err, refresh.ErrTokenReused):
+		setAuthCookies(ctx, nil)
+		statusCode = http.StatusUnauthorized
+	}
+
+	return apiHelper.ResponseData{
+		StatusCode: statusCode,
+		Error:      fmt.Errorf("Couldn't refresh token: %w", err),
+	}
+}
+
+func (h *AuthHandler) Logout(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		apiHelper.HandleWithClaim(
+			ctx,
+			func(claim *token.CustomClaims) {
+				if err := h.authService.Logout(c, claim); err != nil {
+					resChan <- apiHelper.ResponseData{
+						StatusCode: http.StatusInternalServerError,
+						Error:      err,
+					}
+					return
+				}
+
+				setAuthCookies(ctx, nil)
+
+				resChan <- apiHelper.ResponseData{
+					StatusCode: http.StatusOK,
+					Message:    "Logout successful.",
+				}
+			},
+			func() { claimNotFound(resChan) },
+		)
+	})
+}
+
+// accountStatusResponse maps the errors of accounts that aren't allowed to
+// log in. They are forbidden rather than unauthorized, the credentials were
+// right.
+func accountStatusResponse(err error) (apiHelper.ResponseData, bool) {
+	switch common.ErrorCode(err) {
+	case fmt.Sprint(common.ErrAccountNotVerified):
+		return apiHelper.ResponseData{
+			StatusCode: http.StatusForbidden,
+			Error:      errors.New("E-mail address is not verified."),
+		}, true
+	case fmt.Sprint(common.ErrAccountDisabled):
+		return apiHelper.ResponseData{
+			StatusCode: http.StatusForbidden,
+			Error:      errors.New("Account is disabled."),
+		}, true
+	}
+
+	return apiHelper.ResponseData{}, false
+}
+
+// lockoutResponse tells a client locked out by failed logins when it may try
+// again.
+func lockoutResponse(ctx *gin.Context, err error) (apiHelper.ResponseData, bool) {
+	var lockoutErr *service.LockoutError
+	if !errors.As(err, &lockoutErr) {
+		return apiHelper.ResponseData{}, false
+	}
+
+	retryAfter := int(math.Ceil(lockoutErr.RetryAfter.Seconds()))
+	ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+
+	return apiHelper.ResponseData{
+		StatusCode: http.StatusTooManyRequests,
+		Error:      fmt.Errorf("Too many failed login attempts, try again in %d seconds.", retryAfter),
+	}, true
+}
+
+func sessionClient(ctx *gin.Context) entity.SessionClient {
+	return entity.SessionClient{
+		UserAgent: ctx.Request.UserAgent(),
+		IP:        ctx.ClientIP(),
+	}
+}
+
+func setAuthCookies(ctx *gin.Context, tokenPair *token.TokenPair) {
+	if tokenPair == nil {
+		setCookie(ctx, token.AccessTokenCookie, "/", nil)
+		setCookie(ctx, token.RefreshTokenCookie, refreshTokenCookiePath, nil)
+		return
+	}
+
+	setCookie(ctx, token.AccessTokenCookie, "/", tokenPair.AccessToken)
+	setCookie(ctx, token.RefreshTokenCookie, refreshTokenCookiePath, tokenPair.RefreshToken)
+}
+
+// refreshTokenCookiePath keeps the refresh token from being sent along with
+// every request; only the /auth endpoints need to see it.
+const refreshTokenCookiePath = "/auth"
+
+func setCookie(ctx *gin.Context, name string, path string, token *token.JWTToken) {
 	var (
 		value  string
 		maxAge int
@@ -123,9 +473,9 @@ func setAuthCookies(ctx *gin.Context, token *token.JWTToken) {
 	}
 
 	http.SetCookie(ctx.Writer, &http.Cookie{
-		Name:     "access_token",
+		Name:     name,
 		Value:    value,
-		Path:     "/",
+		Path:     path,
 		HttpOnly: true,
 		Secure:   true,
 		SameSite: http.SameSiteStrictMode,
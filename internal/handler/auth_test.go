@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"onboarding/internal/repository/refresh"
+	"onboarding/internal/service"
+	"onboarding/pkg/token"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAuthService struct {
+	service.AuthService
+	refreshErr error
+	verifyErr  error
+	userUUID   uuid.UUID
+}
+
+func (s *fakeAuthService) VerifyAccessToken(ctx context.Context, accessToken string) (*token.CustomClaims, error) {
+	if s.verifyErr != nil {
+		return nil, s.verifyErr
+	}
+
+	return &token.CustomClaims{UserID: s.userUUID}, nil
+}
+
+func (s *fakeAuthService) Refresh(ctx context.Context, refreshToken string) (*token.TokenPair, error) {
+	if s.refreshErr != nil {
+		return nil, s.refreshErr
+	}
+
+	expireAt := time.Now().Add(time.Hour)
+	return &token.TokenPair{
+		AccessToken:  &token.JWTToken{SignedToken: "access", ExpireAt: expireAt},
+		RefreshToken: &token.JWTToken{SignedToken: "refresh", ExpireAt: expireAt},
+	}, nil
+}
+
+func refreshRequest() testRequest {
+	return testRequest{
+		method:  http.MethodPost,
+		target:  "/auth/refresh",
+		cookies: []*http.Cookie{{Name: token.RefreshTokenCookie, Value: "presented"}},
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	h := NewAuthHandler(&fakeAuthService{}, nil)
+
+	responses, recorder := serve(h.Refresh, refreshRequest())
+
+	require.Len(t, responses, 1)
+	require.Equal(t, http.StatusOK, responses[0].StatusCode)
+	require.Equal(t, "refresh", cookie(recorder, token.RefreshTokenCookie).Value)
+	require.Equal(t, "access", cookie(recorder, token.AccessTokenCookie).Value)
+}
+
+func TestRefreshClearsCookiesOfDeadTokens(t *testing.T) {
+	testCases := []struct {
+		err        error
+		statusCode int
+		cleared    bool
+	}{
+		{refresh.ErrTokenReused, http.StatusUnauthorized, true},
+		{refresh.ErrTokenRevoked, http.StatusUnauthorized, true},
+		{service.ErrSessionRevoked, http.StatusUnauthorized, true},
+		{fmt.Errorf("%w: expired", service.ErrTokenInvalid), http.StatusUnauthorized, true},
+		{fmt.Errorf("redis error: %w", errors.New("connection refused")), http.StatusInternalServerError, false},
+	}
+
+	for _, tc := range testCases {
+		h := NewAuthHandler(&fakeAuthService{refreshErr: tc.err}, nil)
+
+		responses, recorder := serve(h.Refresh, refreshRequest())
+
+		require.Len(t, responses, 1, tc.err)
+		require.Equal(t, tc.statusCode, responses[0].StatusCode, tc.err)
+
+		if !tc.cleared {
+			require.Nil(t, cookie(recorder, token.RefreshTokenCookie), tc.err)
+			continue
+		}
+
+		cleared := cookie(recorder, token.RefreshTokenCookie)
+		require.NotNil(t, cleared, tc.err)
+		require.Empty(t, cleared.Value, tc.err)
+		require.Negative(t, cleared.MaxAge, tc.err)
+	}
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	apiHelper "onboarding/api/helper"
+	"onboarding/api/request"
+	"onboarding/api/response"
+	"onboarding/common"
+	"onboarding/internal/service"
+	"onboarding/pkg/authz"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthzHandler lets other services ask whether the bearer of an access token
+// is granted a permission, so they don't need their own copy of the roles.
+type AuthzHandler struct {
+	authService service.AuthService
+	authorizer  service.Authorizer
+}
+
+func NewAuthzHandler(authService service.AuthService, authorizer service.Authorizer) *AuthzHandler {
+	return &AuthzHandler{
+		authService: authService,
+		authorizer:  authorizer,
+	}
+}
+
+func (h *AuthzHandler) CheckPermission(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		var req request.CheckPermissionRequest
+		if err := ctx.ShouldBind(&req); err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      common.ErrorValidation(err),
+			}
+			return
+		}
+
+		permission, err := authz.ParsePermission(req.Permission)
+		if err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      err,
+			}
+			return
+		}
+
+		claim, err := h.authService.VerifyAccessToken(c, req.Token)
+		if err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusUnauthorized,
+				Error:      fmt.Errorf("Couldn't verify token: %w", err),
+			}
+			return
+		}
+
+		allowed, err := h.authorizer.Authorize(c, claim.UserID, permission)
+		if err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusInternalServerError,
+				Error:      err,
+			}
+			return
+		}
+
+		resChan <- apiHelper.ResponseData{
+			StatusCode: http.StatusOK,
+			Message:    "Permission checked successfully.",
+			Data:       response.PermissionCheckResponse{Allowed: allowed},
+		}
+	})
+}
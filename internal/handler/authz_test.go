@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"onboarding/api/response"
+	"onboarding/pkg/authz"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAuthorizer grants its user the permissions it lists.
+type fakeAuthorizer struct {
+	userUUID    uuid.UUID
+	permissions []authz.Permission
+}
+
+func (a *fakeAuthorizer) Authorize(ctx context.Context, userUUID uuid.UUID, permission authz.Permission) (bool, error) {
+	if userUUID != a.userUUID {
+		return false, nil
+	}
+
+	for _, p := range a.permissions {
+		if p == permission {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func TestCheckPermission(t *testing.T) {
+	userUUID := uuid.New()
+	authorizer := &fakeAuthorizer{
+		userUUID:    userUUID,
+		permissions: []authz.Permission{authz.NewPermission("users", "read")},
+	}
+
+	testCases := []struct {
+		permission string
+		verifyErr  error
+		statusCode int
+		allowed    bool
+	}{
+		{"users", nil, http.StatusBadRequest, false},
+		{"users:read", errors.New("expired"), http.StatusUnauthorized, false},
+		{"users:read", nil, http.StatusOK, true},
+		{"users:delete", nil, http.StatusOK, false},
+	}
+
+	for _, tc := range testCases {
+		h := NewAuthzHandler(&fakeAuthService{verifyErr: tc.verifyErr, userUUID: userUUID}, authorizer)
+
+		responses, _ := serve(h.CheckPermission, testRequest{
+			method: http.MethodPost,
+			target: "/authz/check",
+			form:   url.Values{"token": {"access"}, "permission": {tc.permission}},
+		})
+
+		require.Len(t, responses, 1, tc.permission)
+		require.Equal(t, tc.statusCode, responses[0].StatusCode, tc.permission)
+		if tc.statusCode == http.StatusOK {
+			require.Equal(t, response.PermissionCheckResponse{Allowed: tc.allowed}, responses[0].Data, tc.permission)
+		}
+	}
+}
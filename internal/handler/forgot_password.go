@@ -36,9 +36,10 @@ func (h *ForgotPasswordHandler) RequestResetPassword(ctx *gin.Context) {
 				StatusCode: http.StatusBadRequest,
 				Error:      common.ErrorValidation(err),
 			}
+			return
 		}
 
-		err := h.otpService.SendOtpForgotPassword(c, req.Email)
+		err := h.otpService.SendOtpForgotPassword(c, req.Email, ctx.ClientIP())
 		if err != nil {
 			log.Printf("OTP email send failed for %s: %v", req.Email, err)
 		}
@@ -58,29 +59,34 @@ func (h *ForgotPasswordHandler) ResetPassword(ctx *gin.Context) {
 				StatusCode: http.StatusBadRequest,
 				Error:      common.ErrorValidation(err),
 			}
+			return
 		}
 
-		err := h.otpService.VerifyOtpForgotPassword(c, req.Email, req.OTP)
-		if err != nil {
+		// A typo doesn't cost one of the code's attempts.
+		if req.NewPassword != req.VerifyPassword {
 			resChan <- apiHelper.ResponseData{
 				StatusCode: http.StatusBadRequest,
-				Error:      err,
+				Error:      errors.New("Verify password doesn't match."),
 			}
+			return
 		}
 
-		if req.NewPassword != req.VerifyPassword {
+		err := h.otpService.VerifyOtpForgotPassword(c, req.Email, req.OTP)
+		if err != nil {
 			resChan <- apiHelper.ResponseData{
 				StatusCode: http.StatusBadRequest,
-				Error:      errors.New("Verify password doesn't match."),
+				Error:      err,
 			}
+			return
 		}
 
-		err = h.userService.ChangeUserPassword(ctx, req.Email, req.NewPassword)
+		err = h.userService.ChangeUserPassword(c, req.Email, req.NewPassword)
 		if err != nil {
 			resChan <- apiHelper.ResponseData{
 				StatusCode: http.StatusBadRequest,
 				Error:      err,
 			}
+			return
 		}
 
 		resChan <- apiHelper.ResponseData{
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"onboarding/internal/repository/otp"
+	"onboarding/internal/service"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeOtpService struct {
+	service.OtpService
+	verifyErr error
+}
+
+func (s *fakeOtpService) VerifyOtpForgotPassword(ctx context.Context, email string, otpCode string) error {
+	return s.verifyErr
+}
+
+type fakeUserService struct {
+	service.UserService
+	passwords map[string]string
+	taken     map[string]bool
+}
+
+func (s *fakeUserService) ChangeUserPassword(ctx context.Context, email, newPassword string) error {
+	s.passwords[email] = newPassword
+	return nil
+}
+
+func resetPasswordForm(otpCode string) url.Values {
+	return url.Values{
+		"email":           {"user@example.com"},
+		"otp":             {otpCode},
+		"new_password":    {"N3w-Passw0rd!"},
+		"verify_password": {"N3w-Passw0rd!"},
+	}
+}
+
+func TestResetPasswordWrongOtpKeepsPassword(t *testing.T) {
+	userService := &fakeUserService{passwords: map[string]string{}}
+	h := NewForgotPasswordHandler(&fakeOtpService{verifyErr: otp.ErrOtpInvalid}, userService)
+
+	responses, _ := serve(h.ResetPassword, testRequest{
+		method: http.MethodPost,
+		target: "/reset-password",
+		form:   resetPasswordForm("000000"),
+	})
+
+	require.Len(t, responses, 1)
+	require.Equal(t, http.StatusBadRequest, responses[0].StatusCode)
+	require.ErrorIs(t, responses[0].Error, otp.ErrOtpInvalid)
+	require.Empty(t, userService.passwords)
+}
+
+func TestResetPassword(t *testing.T) {
+	userService := &fakeUserService{passwords: map[string]string{}}
+	h := NewForgotPasswordHandler(&fakeOtpService{}, userService)
+
+	responses, _ := serve(h.ResetPassword, testRequest{
+		method: http.MethodPost,
+		target: "/reset-password",
+		form:   resetPasswordForm("123456"),
+	})
+
+	require.Len(t, responses, 1)
+	require.Equal(t, http.StatusCreated, responses[0].StatusCode)
+	require.Equal(t, "N3w-Passw0rd!", userService.passwords["user@example.com"])
+}
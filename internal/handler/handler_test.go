@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	apiHelper "onboarding/api/helper"
+	"onboarding/pkg/token"
+	"onboarding/pkg/validation"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterValidation("validEmail", validation.ValidEmail)
+		v.RegisterValidation("validPassword", validation.ValidPassword)
+		v.RegisterValidation("validUUID", validation.ValidUUID)
+	}
+}
+
+// testRequest is a form request to a handler, made by the user of claims
+// unless it's nil.
+type testRequest struct {
+	method  string
+	target  string
+	form    url.Values
+	params  gin.Params
+	cookies []*http.Cookie
+	claims  *token.CustomClaims
+}
+
+// serve runs handler to the end and returns every response it sent, so a
+// test sees a handler that keeps going after answering. The recorder holds
+// the cookies it set.
+func serve(handler gin.HandlerFunc, req testRequest) ([]apiHelper.ResponseData, *httptest.ResponseRecorder) {
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+
+	ctx.Request = httptest.NewRequest(req.method, req.target, strings.NewReader(req.form.Encode()))
+	ctx.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ctx.Params = req.params
+	for _, c := range req.cookies {
+		ctx.Request.AddCookie(c)
+	}
+	if req.claims != nil {
+		ctx.Set(token.JWTClaim, req.claims)
+	}
+
+	resChan := make(chan apiHelper.ResponseData, 8)
+	ctx.Set(apiHelper.ResChan, resChan)
+	handler(ctx)
+	close(resChan)
+
+	var responses []apiHelper.ResponseData
+	for res := range resChan {
+		responses = append(responses, res)
+	}
+
+	return responses, recorder
+}
+
+// cookie returns the cookie called name that the recorder got, or nil.
+func cookie(recorder *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, c := range recorder.Result().Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"net/http"
+	"onboarding/pkg/token"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JWKSHandler struct {
+	jwtImpl token.JWT
+}
+
+func NewJWKSHandler(jwtImpl token.JWT) *JWKSHandler {
+	return &JWKSHandler{jwtImpl: jwtImpl}
+}
+
+// GetJWKS publishes the verification keys as a plain JWK Set (RFC 7517) so
+// other services can consume it without knowing our response envelope.
+func (h *JWKSHandler) GetJWKS(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, h.jwtImpl.JWKS())
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"onboarding/common"
+	"onboarding/pkg/config"
+	"onboarding/pkg/token"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetJWKS(t *testing.T) {
+	private, public := common.GenerateRSAKey(t)
+	jwtImpl, err := token.NewJWT(config.Token{
+		AccessTokenDuration: time.Minute,
+		PrivateKey:          private,
+		PublicKey:           public,
+		KeyID:               "current",
+	})
+	require.NoError(t, err)
+
+	responses, recorder := serve(NewJWKSHandler(jwtImpl).GetJWKS, testRequest{
+		method: http.MethodGet,
+		target: "/.well-known/jwks.json",
+	})
+
+	// The key set is written as it is, not in the response envelope.
+	require.Empty(t, responses)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NotEmpty(t, recorder.Header().Get("Cache-Control"))
+
+	var jwks jose.JSONWebKeySet
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &jwks))
+	require.Len(t, jwks.Key("current"), 1)
+	require.True(t, jwks.Keys[0].IsPublic())
+}
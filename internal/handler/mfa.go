@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	apiHelper "onboarding/api/helper"
+	"onboarding/api/request"
+	"onboarding/api/response"
+	"onboarding/common"
+	"onboarding/internal/service"
+	"onboarding/pkg/token"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MFAHandler struct {
+	mfaService service.MFAService
+}
+
+func NewMFAHandler(mfaService service.MFAService) *MFAHandler {
+	return &MFAHandler{mfaService: mfaService}
+}
+
+func (h *MFAHandler) EnrollTOTP(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		apiHelper.HandleWithClaim(
+			ctx,
+			func(claim *token.CustomClaims) {
+				enrollment, err := h.mfaService.EnrollTOTP(c, claim.UserID)
+				if err != nil {
+					resChan <- mfaErrorResponse(err)
+					return
+				}
+
+				resChan <- apiHelper.ResponseData{
+					StatusCode: http.StatusCreated,
+					Message:    "Scan the secret with your authenticator app and confirm with a code.",
+					Data:       response.NewTOTPEnrollmentResponse(enrollment),
+				}
+			},
+			func() { claimNotFound(resChan) },
+		)
+	})
+}
+
+func (h *MFAHandler) ConfirmTOTP(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		var req request.TOTPCodeRequest
+		if err := ctx.ShouldBind(&req); err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      common.ErrorValidation(err),
+			}
+			return
+		}
+
+		apiHelper.HandleWithClaim(
+			ctx,
+			func(claim *token.CustomClaims) {
+				recoveryCodes, err := h.mfaService.ConfirmTOTP(c, claim.UserID, req.Code)
+				if err != nil {
+					resChan <- mfaErrorResponse(err)
+					return
+				}
+
+				resChan <- apiHelper.ResponseData{
+					StatusCode: http.StatusOK,
+					Message:    "Two-factor authentication enabled successfully. Store the recovery codes somewhere safe.",
+					Data:       response.NewRecoveryCodesResponse(recoveryCodes),
+				}
+			},
+			func() { claimNotFound(resChan) },
+		)
+	})
+}
+
+func (h *MFAHandler) DisableTOTP(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		var req request.TOTPCodeRequest
+		if err := ctx.ShouldBind(&req); err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      common.ErrorValidation(err),
+			}
+			return
+		}
+
+		apiHelper.HandleWithClaim(
+			ctx,
+			func(claim *token.CustomClaims) {
+				if err := h.mfaService.DisableTOTP(c, claim.UserID, req.Code); err != nil {
+					resChan <- mfaErrorResponse(err)
+					return
+				}
+
+				resChan <- apiHelper.ResponseData{
+					StatusCode: http.StatusOK,
+					Message:    "Two-factor authentication disabled successfully.",
+				}
+			},
+			func() { claimNotFound(resChan) },
+		)
+	})
+}
+
+func (h *MFAHandler) RegenerateRecoveryCodes(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		var req request.TOTPCodeRequest
+		if err := ctx.ShouldBind(&req); err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      common.ErrorValidation(err),
+			}
+			return
+		}
+
+		apiHelper.HandleWithClaim(
+			ctx,
+			func(claim *token.CustomClaims) {
+				recoveryCodes, err := h.mfaService.RegenerateRecoveryCodes(c, claim.UserID, req.Code)
+				if err != nil {
+					resChan <- mfaErrorResponse(err)
+					return
+				}
+
+				resChan <- apiHelper.ResponseData{
+					StatusCode: http.StatusCreated,
+					Message:    "Recovery codes regenerated successfully. Previous codes no longer work.",
+					Data:       response.NewRecoveryCodesResponse(recoveryCodes),
+				}
+			},
+			func() { claimNotFound(resChan) },
+		)
+	})
+}
+
+func (h *MFAHandler) CountRecoveryCodes(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		apiHelper.HandleWithClaim(
+			ctx,
+			func(claim *token.CustomClaims) {
+				remaining, err := h.mfaService.CountRecoveryCodes(c, claim.UserID)
+				if err != nil {
+					resChan <- mfaErrorResponse(err)
+					return
+				}
+
+				resChan <- apiHelper.ResponseData{
+					StatusCode: http.StatusOK,
+					Message:    "Recovery codes retrieved successfully.",
+					Data:       response.NewRecoveryCodeCountResponse(remaining),
+				}
+			},
+			func() { claimNotFound(resChan) },
+		)
+	})
+}
+
+func mfaErrorResponse(err error) apiHelper.ResponseData {
+	var statusCode = http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrInvalidMFACode):
+		statusCode = http.StatusUnauthorized
+	case errors.Is(err, service.ErrTOTPAlreadyEnabled):
+		statusCode = http.StatusConflict
+	case errors.Is(err, service.ErrTOTPNotEnrolled):
+		statusCode = http.StatusBadRequest
+	}
+
+	return apiHelper.ResponseData{
+		StatusCode: statusCode,
+		Error:      err,
+	}
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"onboarding/internal/service"
+	"onboarding/pkg/token"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMFAService accepts only code.
+type fakeMFAService struct {
+	service.MFAService
+	code string
+}
+
+func (s *fakeMFAService) ConfirmTOTP(ctx context.Context, userUUID uuid.UUID, code string) ([]string, error) {
+	if code != s.code {
+		return nil, service.ErrInvalidMFACode
+	}
+
+	return []string{"recovery"}, nil
+}
+
+func TestConfirmTOTP(t *testing.T) {
+	h := NewMFAHandler(&fakeMFAService{code: "123456"})
+	claims := &token.CustomClaims{UserID: uuid.New()}
+
+	testCases := []struct {
+		form       url.Values
+		statusCode int
+	}{
+		{url.Values{}, http.StatusBadRequest},
+		{url.Values{"code": {"654321"}}, http.StatusUnauthorized},
+		{url.Values{"code": {"123456"}}, http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		responses, _ := serve(h.ConfirmTOTP, testRequest{
+			method: http.MethodPost,
+			target: "/mfa/totp/confirm",
+			form:   tc.form,
+			claims: claims,
+		})
+
+		require.Len(t, responses, 1, tc.form)
+		require.Equal(t, tc.statusCode, responses[0].StatusCode, tc.form)
+	}
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	apiHelper "onboarding/api/helper"
+	"onboarding/api/request"
+	"onboarding/api/response"
+	"onboarding/common"
+	"onboarding/internal/entity"
+	"onboarding/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// OutboxHandler serves the admin endpoints following message delivery.
+type OutboxHandler struct {
+	outboxService service.OutboxService
+}
+
+func NewOutboxHandler(outboxService service.OutboxService) *OutboxHandler {
+	return &OutboxHandler{outboxService: outboxService}
+}
+
+func (h *OutboxHandler) ListMessages(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		var req request.ListOutboxMessagesRequest
+		if err := ctx.ShouldBindQuery(&req); err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      common.ErrorValidation(err),
+			}
+			return
+		}
+
+		messages, total, err := h.outboxService.ListMessages(c, entity.OutboxFilter{
+			Status: entity.OutboxStatus(req.Status),
+			Limit:  req.PerPage,
+			Offset: (req.Page - 1) * req.PerPage,
+		})
+		if err != nil {
+			resChan <- outboxErrorResponse(err)
+			return
+		}
+
+		resChan <- apiHelper.ResponseData{
+			StatusCode: http.StatusOK,
+			Message:    "Messages retrieved successfully.",
+			Data: response.OutboxMessageListResponse{
+				Messages: messages,
+				Page:     req.Page,
+				PerPage:  req.PerPage,
+				Total:    total,
+			},
+		}
+	})
+}
+
+func (h *OutboxHandler) GetMessage(ctx *gin.Context) {
+	h.withMessage(ctx, func(c context.Context, messageUUID uuid.UUID) apiHelper.ResponseData {
+		message, err := h.outboxService.GetMessage(c, messageUUID)
+		if err != nil {
+			return outboxErrorResponse(err)
+		}
+
+		return apiHelper.ResponseData{
+			StatusCode: http.StatusOK,
+			Message:    "Message retrieved successfully.",
+			Data:       message,
+		}
+	})
+}
+
+func (h *OutboxHandler) ReplayMessage(ctx *gin.Context) {
+	h.withMessage(ctx, func(c context.Context, messageUUID uuid.UUID) apiHelper.ResponseData {
+		if err := h.outboxService.ReplayMessage(c, messageUUID); err != nil {
+			return outboxErrorResponse(err)
+		}
+
+		return apiHelper.ResponseData{
+			StatusCode: http.StatusAccepted,
+			Message:    "Message queued again successfully.",
+		}
+	})
+}
+
+// withMessage runs handle with the message in the :uuid path parameter and
+// sends what it returns.
+func (h *OutboxHandler) withMessage(
+	ctx *gin.Context,
+	handle func(c context.Context, messageUUID uuid.UUID) apiHelper.ResponseData,
+) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		var req request.GetDataByUUIDRequest
+		if err := ctx.ShouldBindUri(&req); err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      common.ErrorValidation(err),
+			}
+			return
+		}
+
+		messageUUID, err := uuid.Parse(req.UUID)
+		if err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      err,
+			}
+			return
+		}
+
+		resChan <- handle(c, messageUUID)
+	})
+}
+
+// outboxErrorResponse maps the errors of the outbox endpoints.
+func outboxErrorResponse(err error) apiHelper.ResponseData {
+	var statusCode = http.StatusInternalServerError
+	switch {
+	case errors.Is(err, common.ErrRecordNotFound):
+		err = errors.New("Message is not found.")
+		statusCode = http.StatusNotFound
+	case errors.Is(err, service.ErrOutboxMessageNotDead), errors.Is(err, service.ErrOutboxMessageExpiring):
+		statusCode = http.StatusConflict
+	}
+
+	return apiHelper.ResponseData{
+		StatusCode: statusCode,
+		Error:      err,
+	}
+}
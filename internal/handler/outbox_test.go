@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"onboarding/common"
+	"onboarding/internal/entity"
+	"onboarding/internal/service"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOutboxService keeps the status of each message it knows. Dead
+// messages in expiring carry a one-time code.
+type fakeOutboxService struct {
+	service.OutboxService
+	statuses map[uuid.UUID]entity.OutboxStatus
+	expiring map[uuid.UUID]bool
+}
+
+func (s *fakeOutboxService) ReplayMessage(ctx context.Context, messageUUID uuid.UUID) error {
+	status, ok := s.statuses[messageUUID]
+	switch {
+	case !ok:
+		return common.ErrRecordNotFound
+	case status != entity.OutboxStatusDead:
+		return service.ErrOutboxMessageNotDead
+	case s.expiring[messageUUID]:
+		return service.ErrOutboxMessageExpiring
+	}
+
+	s.statuses[messageUUID] = entity.OutboxStatusPending
+	return nil
+}
+
+func TestReplayMessage(t *testing.T) {
+	dead, expiring := uuid.New(), uuid.New()
+	outboxService := &fakeOutboxService{
+		statuses: map[uuid.UUID]entity.OutboxStatus{
+			dead:     entity.OutboxStatusDead,
+			expiring: entity.OutboxStatusDead,
+		},
+		expiring: map[uuid.UUID]bool{expiring: true},
+	}
+	h := NewOutboxHandler(outboxService)
+
+	testCases := []struct {
+		uuid       string
+		statusCode int
+	}{
+		{"not-a-uuid", http.StatusBadRequest},
+		{uuid.NewString(), http.StatusNotFound},
+		{expiring.String(), http.StatusConflict},
+		{dead.String(), http.StatusAccepted},
+		{dead.String(), http.StatusConflict},
+	}
+
+	for _, tc := range testCases {
+		responses, _ := serve(h.ReplayMessage, testRequest{
+			method: http.MethodPost,
+			target: "/admin/outbox/" + tc.uuid + "/replay",
+			params: gin.Params{{Key: "uuid", Value: tc.uuid}},
+		})
+
+		require.Len(t, responses, 1, tc.uuid)
+		require.Equal(t, tc.statusCode, responses[0].StatusCode, tc.uuid)
+	}
+}
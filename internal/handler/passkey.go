@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	apiHelper "onboarding/api/helper"
+	"onboarding/api/request"
+	"onboarding/api/response"
+	"onboarding/common"
+	"onboarding/internal/service"
+	"onboarding/pkg/token"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PasskeyHandler serves the WebAuthn ceremonies. Options are returned as the
+// browser expects them and responses are taken as the JSON encoded
+// PublicKeyCredential, so they aren't multipart forms like the other routes.
+type PasskeyHandler struct {
+	passkeyService service.PasskeyService
+}
+
+func NewPasskeyHandler(passkeyService service.PasskeyService) *PasskeyHandler {
+	return &PasskeyHandler{passkeyService: passkeyService}
+}
+
+func (h *PasskeyHandler) BeginRegistration(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		apiHelper.HandleWithClaim(
+			ctx,
+			func(claim *token.CustomClaims) {
+				options, err := h.passkeyService.BeginRegistration(c, claim.UserID)
+				if err != nil {
+					resChan <- passkeyErrorResponse(err)
+					return
+				}
+
+				resChan <- apiHelper.ResponseData{
+					StatusCode: http.StatusOK,
+					Message:    "Create the passkey with your authenticator.",
+					Data:       options,
+				}
+			},
+			func() { claimNotFound(resChan) },
+		)
+	})
+}
+
+func (h *PasskeyHandler) FinishRegistration(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		body, err := ctx.GetRawData()
+		if err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      err,
+			}
+			return
+		}
+
+		apiHelper.HandleWithClaim(
+			ctx,
+			func(claim *token.CustomClaims) {
+				passkey, err := h.passkeyService.FinishRegistration(c, claim.UserID, body)
+				if err != nil {
+					resChan <- passkeyErrorResponse(err)
+					return
+				}
+
+				resChan <- apiHelper.ResponseData{
+					StatusCode: http.StatusCreated,
+					Message:    "Passkey registered successfully.",
+					Data:       response.NewPasskeyResponse(passkey),
+				}
+			},
+			func() { claimNotFound(resChan) },
+		)
+	})
+}
+
+func (h *PasskeyHandler) ListPasskeys(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		apiHelper.HandleWithClaim(
+			ctx,
+			func(claim *token.CustomClaims) {
+				passkeys, err := h.passkeyService.ListPasskeys(c, claim.UserID)
+				if err != nil {
+					resChan <- passkeyErrorResponse(err)
+					return
+				}
+
+				resChan <- apiHelper.ResponseData{
+					StatusCode: http.StatusOK,
+					Message:    "Passkeys retrieved successfully.",
+					Data:       response.NewPasskeyResponses(passkeys),
+				}
+			},
+			func() { claimNotFound(resChan) },
+		)
+	})
+}
+
+func (h *PasskeyHandler) DeletePasskey(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		var req request.PasskeyRequest
+		if err := ctx.ShouldBindUri(&req); err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      common.ErrorValidation(err),
+			}
+			return
+		}
+
+		passkeyUUID, err := uuid.Parse(req.ID)
+		if err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      err,
+			}
+			return
+		}
+
+		apiHelper.HandleWithClaim(
+			ctx,
+			func(claim *token.CustomClaims) {
+				if err := h.passkeyService.DeletePasskey(c, claim.UserID, passkeyUUID); err != nil {
+					resChan <- passkeyErrorResponse(err)
+					return
+				}
+
+				resChan <- apiHelper.ResponseData{
+					StatusCode: http.StatusOK,
+					Message:    "Passkey deleted successfully.",
+				}
+			},
+			func() { claimNotFound(resChan) },
+		)
+	})
+}
+
+func (h *PasskeyHandler) BeginLogin(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		options, err := h.passkeyService.BeginLogin(c)
+		if err != nil {
+			resChan <- passkeyErrorResponse(err)
+			return
+		}
+
+		resChan <- apiHelper.ResponseData{
+			StatusCode: http.StatusOK,
+			Message:    "Sign in with your passkey.",
+			Data:       options,
+		}
+	})
+}
+
+func passkeyErrorResponse(err error) apiHelper.ResponseData {
+	var statusCode = http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrInvalidPasskey):
+		statusCode = http.StatusUnauthorized
+	case errors.Is(err, service.ErrPasskeyCeremonyExpired):
+		statusCode = http.StatusBadRequest
+	case errors.Is(err, common.ErrRecordNotFound):
+		err = errors.New("Passkey is not found.")
+		statusCode = http.StatusNotFound
+	}
+
+	return apiHelper.ResponseData{
+		StatusCode: statusCode,
+		Error:      err,
+	}
+}
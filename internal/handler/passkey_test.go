@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"onboarding/common"
+	"onboarding/internal/service"
+	"onboarding/pkg/token"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePasskeyService keeps the owner of each passkey it knows.
+type fakePasskeyService struct {
+	service.PasskeyService
+	owners map[uuid.UUID]uuid.UUID
+}
+
+func (s *fakePasskeyService) DeletePasskey(ctx context.Context, userUUID uuid.UUID, passkeyUUID uuid.UUID) error {
+	if owner, ok := s.owners[passkeyUUID]; !ok || owner != userUUID {
+		return common.ErrRecordNotFound
+	}
+
+	delete(s.owners, passkeyUUID)
+	return nil
+}
+
+func TestDeletePasskey(t *testing.T) {
+	userUUID, passkeyUUID := uuid.New(), uuid.New()
+	h := NewPasskeyHandler(&fakePasskeyService{owners: map[uuid.UUID]uuid.UUID{passkeyUUID: userUUID}})
+
+	testCases := []struct {
+		id         string
+		claims     *token.CustomClaims
+		statusCode int
+	}{
+		{"not-a-uuid", &token.CustomClaims{UserID: userUUID}, http.StatusBadRequest},
+		{passkeyUUID.String(), nil, http.StatusUnauthorized},
+		{passkeyUUID.String(), &token.CustomClaims{UserID: uuid.New()}, http.StatusNotFound},
+		{passkeyUUID.String(), &token.CustomClaims{UserID: userUUID}, http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		responses, _ := serve(h.DeletePasskey, testRequest{
+			method: http.MethodDelete,
+			target: "/passkeys/" + tc.id,
+			params: gin.Params{{Key: "id", Value: tc.id}},
+			claims: tc.claims,
+		})
+
+		require.Len(t, responses, 1, tc.id)
+		require.Equal(t, tc.statusCode, responses[0].StatusCode, tc.id)
+	}
+}
@@ -0,0 +1,239 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	apiHelper "onboarding/api/helper"
+	"onboarding/api/request"
+	"onboarding/api/response"
+	"onboarding/common"
+	"onboarding/internal/entity"
+	"onboarding/internal/service"
+	"onboarding/pkg/authz"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RoleHandler serves the admin endpoints managing roles. Who may call them is
+// checked by the routes' RequirePermission.
+type RoleHandler struct {
+	roleService service.RoleService
+}
+
+func NewRoleHandler(roleService service.RoleService) *RoleHandler {
+	return &RoleHandler{roleService: roleService}
+}
+
+func (h *RoleHandler) ListRoles(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		roles, err := h.roleService.ListRoles(c)
+		if err != nil {
+			resChan <- roleErrorResponse(err)
+			return
+		}
+
+		resChan <- apiHelper.ResponseData{
+			StatusCode: http.StatusOK,
+			Message:    "Roles retrieved successfully.",
+			Data:       response.NewRoleResponses(roles),
+		}
+	})
+}
+
+func (h *RoleHandler) CreateRole(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		var req request.CreateRoleRequest
+		if err := ctx.ShouldBind(&req); err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      common.ErrorValidation(err),
+			}
+			return
+		}
+
+		role, err := h.roleService.CreateRole(c, entity.UserRole(req.Name), req.Description)
+		if err != nil {
+			resChan <- roleErrorResponse(err)
+			return
+		}
+
+		resChan <- apiHelper.ResponseData{
+			StatusCode: http.StatusCreated,
+			Message:    "Role created successfully.",
+			Data:       response.NewRoleResponse(role),
+		}
+	})
+}
+
+func (h *RoleHandler) DeleteRole(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		var req request.RoleRequest
+		if err := ctx.ShouldBindUri(&req); err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      common.ErrorValidation(err),
+			}
+			return
+		}
+
+		if err := h.roleService.DeleteRole(c, entity.UserRole(req.Name)); err != nil {
+			resChan <- roleErrorResponse(err)
+			return
+		}
+
+		resChan <- apiHelper.ResponseData{
+			StatusCode: http.StatusOK,
+			Message:    "Role deleted successfully.",
+		}
+	})
+}
+
+func (h *RoleHandler) GrantPermission(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		var uri request.RoleRequest
+		if err := ctx.ShouldBindUri(&uri); err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      common.ErrorValidation(err),
+			}
+			return
+		}
+
+		var req request.GrantPermissionRequest
+		if err := ctx.ShouldBind(&req); err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      common.ErrorValidation(err),
+			}
+			return
+		}
+
+		permission, err := authz.ParsePermission(req.Permission)
+		if err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      err,
+			}
+			return
+		}
+
+		if err := h.roleService.GrantPermission(c, entity.UserRole(uri.Name), permission); err != nil {
+			resChan <- roleErrorResponse(err)
+			return
+		}
+
+		resChan <- apiHelper.ResponseData{
+			StatusCode: http.StatusOK,
+			Message:    "Permission granted successfully.",
+		}
+	})
+}
+
+func (h *RoleHandler) RevokePermission(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		var req request.RolePermissionRequest
+		if err := ctx.ShouldBindUri(&req); err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      common.ErrorValidation(err),
+			}
+			return
+		}
+
+		permission, err := authz.ParsePermission(req.Permission)
+		if err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      err,
+			}
+			return
+		}
+
+		err = h.roleService.RevokePermission(c, entity.UserRole(req.Name), permission)
+		if err != nil {
+			if errors.Is(err, common.ErrRecordNotFound) {
+				resChan <- apiHelper.ResponseData{
+					StatusCode: http.StatusNotFound,
+					Error:      errors.New("Role doesn't have the permission."),
+				}
+				return
+			}
+			resChan <- roleErrorResponse(err)
+			return
+		}
+
+		resChan <- apiHelper.ResponseData{
+			StatusCode: http.StatusOK,
+			Message:    "Permission revoked successfully.",
+		}
+	})
+}
+
+func (h *RoleHandler) AssignRole(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		var uri request.GetDataByUUIDRequest
+		if err := ctx.ShouldBindUri(&uri); err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      common.ErrorValidation(err),
+			}
+			return
+		}
+
+		var req request.AssignRoleRequest
+		if err := ctx.ShouldBind(&req); err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      common.ErrorValidation(err),
+			}
+			return
+		}
+
+		userUUID, err := uuid.Parse(uri.UUID)
+		if err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      err,
+			}
+			return
+		}
+
+		err = h.roleService.AssignRole(c, userUUID, entity.UserRole(req.Role))
+		if err != nil {
+			if errors.Is(err, common.ErrRecordNotFound) {
+				resChan <- apiHelper.ResponseData{
+					StatusCode: http.StatusNotFound,
+					Error:      errors.New("User is not found."),
+				}
+				return
+			}
+			resChan <- roleErrorResponse(err)
+			return
+		}
+
+		resChan <- apiHelper.ResponseData{
+			StatusCode: http.StatusOK,
+			Message:    "Role assigned successfully.",
+		}
+	})
+}
+
+// roleErrorResponse maps the errors of the role management.
+func roleErrorResponse(err error) apiHelper.ResponseData {
+	var statusCode = http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrRoleNotFound):
+		statusCode = http.StatusNotFound
+	case errors.Is(err, service.ErrRoleExists), errors.Is(err, service.ErrRoleInUse):
+		statusCode = http.StatusConflict
+	case errors.Is(err, service.ErrRoleBuiltIn):
+		statusCode = http.StatusForbidden
+	}
+
+	return apiHelper.ResponseData{
+		StatusCode: statusCode,
+		Error:      err,
+	}
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"onboarding/internal/entity"
+	"onboarding/internal/service"
+	"onboarding/pkg/authz"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRoleService keeps the permissions of the roles it knows.
+type fakeRoleService struct {
+	service.RoleService
+	permissions map[entity.UserRole][]authz.Permission
+}
+
+func (s *fakeRoleService) GrantPermission(ctx context.Context, name entity.UserRole, permission authz.Permission) error {
+	if _, ok := s.permissions[name]; !ok {
+		return service.ErrRoleNotFound
+	}
+
+	s.permissions[name] = append(s.permissions[name], permission)
+	return nil
+}
+
+func TestGrantPermission(t *testing.T) {
+	roleService := &fakeRoleService{permissions: map[entity.UserRole][]authz.Permission{"support": nil}}
+	h := NewRoleHandler(roleService)
+
+	testCases := []struct {
+		role       string
+		permission string
+		statusCode int
+	}{
+		{"support", "users", http.StatusBadRequest},
+		{"unknown", "users:read", http.StatusNotFound},
+		{"support", "users:read", http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		responses, _ := serve(h.GrantPermission, testRequest{
+			method: http.MethodPost,
+			target: "/admin/roles/" + tc.role + "/permissions",
+			form:   url.Values{"permission": {tc.permission}},
+			params: gin.Params{{Key: "name", Value: tc.role}},
+		})
+
+		require.Len(t, responses, 1, tc)
+		require.Equal(t, tc.statusCode, responses[0].StatusCode, tc)
+	}
+
+	require.Equal(t, []authz.Permission{authz.NewPermission("users", "read")}, roleService.permissions["support"])
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	apiHelper "onboarding/api/helper"
+	"onboarding/api/request"
+	"onboarding/api/response"
+	"onboarding/common"
+	"onboarding/internal/service"
+	"onboarding/pkg/token"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type SessionHandler struct {
+	sessionService service.SessionService
+}
+
+func NewSessionHandler(sessionService service.SessionService) *SessionHandler {
+	return &SessionHandler{sessionService: sessionService}
+}
+
+func (h *SessionHandler) ListSessions(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		apiHelper.HandleWithClaim(
+			ctx,
+			func(claim *token.CustomClaims) {
+				sessions, err := h.sessionService.ListSessions(c, claim.UserID)
+				if err != nil {
+					resChan <- apiHelper.ResponseData{
+						StatusCode: http.StatusInternalServerError,
+						Error:      err,
+					}
+					return
+				}
+
+				resChan <- apiHelper.ResponseData{
+					StatusCode: http.StatusOK,
+					Message:    "Sessions retrieved successfully.",
+					Data:       response.NewSessionResponses(sessions, claim.SessionID),
+				}
+			},
+			func() { claimNotFound(resChan) },
+		)
+	})
+}
+
+func (h *SessionHandler) RevokeSession(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		var req request.SessionRequest
+		if err := ctx.ShouldBindUri(&req); err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      common.ErrorValidation(err),
+			}
+			return
+		}
+
+		sessionUUID, err := uuid.Parse(req.ID)
+		if err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      err,
+			}
+			return
+		}
+
+		apiHelper.HandleWithClaim(
+			ctx,
+			func(claim *token.CustomClaims) {
+				err := h.sessionService.RevokeSession(c, claim.UserID, sessionUUID)
+				if err != nil {
+					var statusCode = http.StatusInternalServerError
+					if errors.Is(err, common.ErrRecordNotFound) {
+						err = errors.New("Session is not found.")
+						statusCode = http.StatusNotFound
+					}
+					resChan <- apiHelper.ResponseData{
+						StatusCode: statusCode,
+						Error:      err,
+					}
+					return
+				}
+
+				resChan <- apiHelper.ResponseData{
+					StatusCode: http.StatusOK,
+					Message:    "Session revoked successfully.",
+				}
+			},
+			func() { claimNotFound(resChan) },
+		)
+	})
+}
+
+func (h *SessionHandler) RevokeOtherSessions(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		apiHelper.HandleWithClaim(
+			ctx,
+			func(claim *token.CustomClaims) {
+				err := h.sessionService.RevokeOtherSessions(c, claim.UserID, claim.SessionID)
+				if err != nil {
+					resChan <- apiHelper.ResponseData{
+						StatusCode: http.StatusInternalServerError,
+						Error:      err,
+					}
+					return
+				}
+
+				resChan <- apiHelper.ResponseData{
+					StatusCode: http.StatusOK,
+					Message:    "Other sessions revoked successfully.",
+				}
+			},
+			func() { claimNotFound(resChan) },
+		)
+	})
+}
+
+func claimNotFound(resChan chan apiHelper.ResponseData) {
+	resChan <- apiHelper.ResponseData{
+		StatusCode: http.StatusUnauthorized,
+		Error:      errors.New("Couldn't find token claim"),
+	}
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"onboarding/common"
+	"onboarding/internal/service"
+	"onboarding/pkg/token"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSessionService knows only the sessions of its user.
+type fakeSessionService struct {
+	service.SessionService
+	userUUID uuid.UUID
+	sessions map[uuid.UUID]bool
+}
+
+func (s *fakeSessionService) RevokeSession(ctx context.Context, userUUID uuid.UUID, sessionUUID uuid.UUID) error {
+	if userUUID != s.userUUID || !s.sessions[sessionUUID] {
+		return common.ErrRecordNotFound
+	}
+
+	delete(s.sessions, sessionUUID)
+	return nil
+}
+
+func TestRevokeSession(t *testing.T) {
+	userUUID, sessionUUID := uuid.New(), uuid.New()
+	sessionService := &fakeSessionService{userUUID: userUUID, sessions: map[uuid.UUID]bool{sessionUUID: true}}
+	h := NewSessionHandler(sessionService)
+
+	revoke := func(id string, claims *token.CustomClaims) []int {
+		responses, _ := serve(h.RevokeSession, testRequest{
+			method: http.MethodDelete,
+			target: "/sessions/" + id,
+			params: gin.Params{{Key: "id", Value: id}},
+			claims: claims,
+		})
+
+		var statusCodes []int
+		for _, res := range responses {
+			statusCodes = append(statusCodes, res.StatusCode)
+		}
+		return statusCodes
+	}
+
+	require.Equal(t, []int{http.StatusBadRequest}, revoke("not-a-uuid", &token.CustomClaims{UserID: userUUID}))
+	require.Equal(t, []int{http.StatusUnauthorized}, revoke(sessionUUID.String(), nil))
+	require.Equal(t, []int{http.StatusNotFound}, revoke(sessionUUID.String(), &token.CustomClaims{UserID: uuid.New()}))
+	require.Equal(t, []int{http.StatusOK}, revoke(sessionUUID.String(), &token.CustomClaims{UserID: userUUID}))
+	require.Equal(t, []int{http.StatusNotFound}, revoke(sessionUUID.String(), &token.CustomClaims{UserID: userUUID}))
+}
@@ -2,11 +2,15 @@ package handler
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	apiHelper "onboarding/api/helper"
 	"onboarding/api/request"
 	"onboarding/common"
+	"onboarding/internal/repository/otp"
 	"onboarding/internal/service"
+	"onboarding/pkg/notify"
 	"onboarding/pkg/token"
 
 	"github.com/gin-gonic/gin"
@@ -21,13 +25,21 @@ func NewUserHandler(userService service.UserService) *UserHandler {
 	return &UserHandler{userService: userService}
 }
 
+// GetUser returns the user in the :uuid path parameter, or the caller when
+// there is none. Whether the caller may read someone else is checked by the
+// route's RequireSelfOrPermission.
 func (h *UserHandler) GetUser(ctx *gin.Context) {
 	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
 		getUser := func(id uuid.UUID) {
 			result, err := h.userService.GetUser(c, id)
 			if err != nil {
+				var statusCode = http.StatusInternalServerError
+				if errors.Is(err, common.ErrRecordNotFound) {
+					err = errors.New("User is not found.")
+					statusCode = http.StatusNotFound
+				}
 				resChan <- apiHelper.ResponseData{
-					StatusCode: http.StatusInternalServerError,
+					StatusCode: statusCode,
 					Error:      err,
 				}
 				return
@@ -40,31 +52,284 @@ func (h *UserHandler) GetUser(ctx *gin.Context) {
 			}
 		}
 
+		if ctx.Param("uuid") != "" {
+			var req request.GetDataByUUIDRequest
+			if err := ctx.ShouldBindUri(&req); err != nil {
+				resChan <- apiHelper.ResponseData{
+					StatusCode: http.StatusBadRequest,
+					Error:      common.ErrorValidation(err),
+				}
+				return
+			}
+
+			uuid, err := uuid.Parse(req.UUID)
+			if err != nil {
+				resChan <- apiHelper.ResponseData{
+					StatusCode: http.StatusBadRequest,
+					Error:      err,
+				}
+				return
+			}
+
+			getUser(uuid)
+			return
+		}
+
 		apiHelper.HandleWithClaim(
 			ctx,
 			func(claim *token.CustomClaims) {
 				getUser(claim.UserID)
 			},
-			func() {
-				var req request.GetDataByUUIDRequest
-				if err := ctx.ShouldBindUri(&req); err != nil {
-					resChan <- apiHelper.ResponseData{
-						StatusCode: http.StatusBadRequest,
-						Error:      common.ErrorValidation(err),
-					}
+			func() { claimNotFound(resChan) },
+		)
+	})
+}
+
+func (h *UserHandler) UpdatePassword(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		var req request.UpdatePasswordRequest
+		if err := ctx.ShouldBind(&req); err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      common.ErrorValidation(err),
+			}
+			return
+		}
+
+		if req.NewPassword != req.VerifyPassword {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      errors.New("Verify password doesn't match."),
+			}
+			return
+		}
+
+		apiHelper.HandleWithClaim(
+			ctx,
+			func(claim *token.CustomClaims) {
+				err := h.userService.UpdatePassword(c, claim.UserID, req.CurrentPassword, req.NewPassword)
+				if err != nil {
+					resChan <- userErrorResponse(err)
+					return
 				}
 
-				uuid, err := uuid.Parse(req.UUID)
+				setAuthCookies(ctx, nil)
+
+				resChan <- apiHelper.ResponseData{
+					StatusCode: http.StatusOK,
+					Message:    "Password changed successfully. Please login again.",
+				}
+			},
+			func() { claimNotFound(resChan) },
+		)
+	})
+}
+
+func (h *UserHandler) UpdateEmail(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		var req request.UpdateEmailRequest
+		if err := ctx.ShouldBind(&req); err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      common.ErrorValidation(err),
+			}
+			return
+		}
+
+		apiHelper.HandleWithClaim(
+			ctx,
+			func(claim *token.CustomClaims) {
+				err := h.userService.RequestEmailChange(c, claim.UserID, req.Password, req.NewEmail, ctx.ClientIP())
 				if err != nil {
-					resChan <- apiHelper.ResponseData{
-						StatusCode: http.StatusBadRequest,
-						Error:      err,
-					}
+					resChan <- userErrorResponse(err)
+					return
 				}
 
-				getUser(uuid)
+				resChan <- apiHelper.ResponseData{
+					StatusCode: http.StatusAccepted,
+					Message:    "Confirm the new e-mail with the code we sent to it.",
+				}
 			},
+			func() { claimNotFound(resChan) },
 		)
+	})
+}
 
+func (h *UserHandler) ConfirmEmail(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		var req request.ConfirmEmailRequest
+		if err := ctx.ShouldBind(&req); err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      common.ErrorValidation(err),
+			}
+			return
+		}
+
+		apiHelper.HandleWithClaim(
+			ctx,
+			func(claim *token.CustomClaims) {
+				err := h.userService.ConfirmEmailChange(c, claim.UserID, req.NewEmail, req.OTP)
+				if err != nil {
+					resChan <- userErrorResponse(err)
+					return
+				}
+
+				resChan <- apiHelper.ResponseData{
+					StatusCode: http.StatusOK,
+					Message:    "E-mail changed successfully.",
+				}
+			},
+			func() { claimNotFound(resChan) },
+		)
 	})
 }
+
+func (h *UserHandler) UpdatePhone(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		var req request.UpdatePhoneRequest
+		if err := ctx.ShouldBind(&req); err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      common.ErrorValidation(err),
+			}
+			return
+		}
+
+		apiHelper.HandleWithClaim(
+			ctx,
+			func(claim *token.CustomClaims) {
+				err := h.userService.RequestPhoneChange(c, claim.UserID, req.Password, req.Phone, ctx.ClientIP())
+				if err != nil {
+					resChan <- userErrorResponse(err)
+					return
+				}
+
+				resChan <- apiHelper.ResponseData{
+					StatusCode: http.StatusAccepted,
+					Message:    "Confirm the phone number with the code we texted to it.",
+				}
+			},
+			func() { claimNotFound(resChan) },
+		)
+	})
+}
+
+func (h *UserHandler) ConfirmPhone(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		var req request.ConfirmPhoneRequest
+		if err := ctx.ShouldBind(&req); err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      common.ErrorValidation(err),
+			}
+			return
+		}
+
+		apiHelper.HandleWithClaim(
+			ctx,
+			func(claim *token.CustomClaims) {
+				err := h.userService.ConfirmPhoneChange(c, claim.UserID, req.OTP)
+				if err != nil {
+					resChan <- userErrorResponse(err)
+					return
+				}
+
+				resChan <- apiHelper.ResponseData{
+					StatusCode: http.StatusOK,
+					Message:    "Phone number verified successfully.",
+				}
+			},
+			func() { claimNotFound(resChan) },
+		)
+	})
+}
+
+func (h *UserHandler) UpdateNotificationChannel(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		var req request.UpdateNotificationChannelRequest
+		if err := ctx.ShouldBind(&req); err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      common.ErrorValidation(err),
+			}
+			return
+		}
+
+		apiHelper.HandleWithClaim(
+			ctx,
+			func(claim *token.CustomClaims) {
+				err := h.userService.UpdateNotificationChannel(c, claim.UserID, notify.Channel(req.Channel))
+				if err != nil {
+					resChan <- userErrorResponse(err)
+					return
+				}
+
+				resChan <- apiHelper.ResponseData{
+					StatusCode: http.StatusOK,
+					Message:    "Notification channel updated successfully.",
+				}
+			},
+			func() { claimNotFound(resChan) },
+		)
+	})
+}
+
+func (h *UserHandler) UpdateLocale(ctx *gin.Context) {
+	apiHelper.ResponseHandler(ctx, func(c context.Context, resChan chan apiHelper.ResponseData) {
+		var req request.UpdateLocaleRequest
+		if err := ctx.ShouldBind(&req); err != nil {
+			resChan <- apiHelper.ResponseData{
+				StatusCode: http.StatusBadRequest,
+				Error:      common.ErrorValidation(err),
+			}
+			return
+		}
+
+		apiHelper.HandleWithClaim(
+			ctx,
+			func(claim *token.CustomClaims) {
+				if err := h.userService.UpdateLocale(c, claim.UserID, req.Locale); err != nil {
+					resChan <- userErrorResponse(err)
+					return
+				}
+
+				resChan <- apiHelper.ResponseData{
+					StatusCode: http.StatusOK,
+					Message:    "Locale updated successfully.",
+				}
+			},
+			func() { claimNotFound(resChan) },
+		)
+	})
+}
+
+// userErrorResponse maps the errors of the account changes.
+func userErrorResponse(err error) apiHelper.ResponseData {
+	var statusCode = http.StatusInternalServerError
+	switch {
+	case common.ErrorCode(err) == fmt.Sprint(common.ErrCredentiials):
+		err = errors.New("Password is incorrect.")
+		statusCode = http.StatusForbidden
+	case errors.Is(err, service.ErrEmailTaken), errors.Is(err, service.ErrPhoneTaken):
+		statusCode = http.StatusConflict
+	case errors.Is(err, service.ErrPhoneNotVerified),
+		errors.Is(err, service.ErrPhoneAlreadyVerified),
+		errors.Is(err, service.ErrInvalidLocale):
+		statusCode = http.StatusBadRequest
+	case errors.Is(err, notify.ErrChannelUnavailable):
+		err = errors.New("Text messages can't be sent right now.")
+		statusCode = http.StatusServiceUnavailable
+	case errors.Is(err, otp.ErrOtpExpired),
+		errors.Is(err, otp.ErrOtpInvalid),
+		errors.Is(err, otp.ErrOtpAttemptsExceeded):
+		statusCode = http.StatusBadRequest
+	case errors.Is(err, otp.ErrOtpCooldown), errors.Is(err, otp.ErrOtpQuotaExceeded):
+		statusCode = http.StatusTooManyRequests
+	}
+
+	return apiHelper.ResponseData{
+		StatusCode: statusCode,
+		Error:      err,
+	}
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"onboarding/common"
+	"onboarding/internal/service"
+	"onboarding/pkg/token"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// RequestEmailChange checks the password against passwords, keyed by the
+// user's UUID, and refuses the addresses in taken.
+func (s *fakeUserService) RequestEmailChange(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	password, newEmail, ip string,
+) error {
+	switch {
+	case s.passwords[userUUID.String()] != password:
+		return fmt.Errorf("%d", common.ErrCredentiials)
+	case s.taken[newEmail]:
+		return service.ErrEmailTaken
+	}
+
+	return nil
+}
+
+func TestUpdateEmail(t *testing.T) {
+	userUUID := uuid.New()
+	userService := &fakeUserService{
+		passwords: map[string]string{userUUID.String(): "Passw0rd!"},
+		taken:     map[string]bool{"taken@example.com": true},
+	}
+	h := NewUserHandler(userService)
+
+	testCases := []struct {
+		password   string
+		newEmail   string
+		claims     *token.CustomClaims
+		statusCode int
+	}{
+		{"Passw0rd!", "not-an-email", &token.CustomClaims{UserID: userUUID}, http.StatusBadRequest},
+		{"Passw0rd!", "new@example.com", nil, http.StatusUnauthorized},
+		{"wrong", "new@example.com", &token.CustomClaims{UserID: userUUID}, http.StatusForbidden},
+		{"Passw0rd!", "taken@example.com", &token.CustomClaims{UserID: userUUID}, http.StatusConflict},
+		{"Passw0rd!", "new@example.com", &token.CustomClaims{UserID: userUUID}, http.StatusAccepted},
+	}
+
+	for _, tc := range testCases {
+		responses, _ := serve(h.UpdateEmail, testRequest{
+			method: http.MethodPut,
+			target: "/user/email",
+			form:   url.Values{"password": {tc.password}, "new_email": {tc.newEmail}},
+			claims: tc.claims,
+		})
+
+		require.Len(t, responses, 1, tc)
+		require.Equal(t, tc.statusCode, responses[0].StatusCode, tc)
+	}
+}
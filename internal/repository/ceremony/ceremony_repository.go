@@ -0,0 +1,84 @@
+package ceremony
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/redis/go-redis/v9"
+)
+
+// Kind separates registration from login ceremonies, so a challenge issued
+// for one can't be answered in the other.
+type Kind string
+
+const (
+	KindRegistration Kind = "registration"
+	KindLogin        Kind = "login"
+)
+
+var ErrCeremonyNotFound = errors.New("ceremony not found")
+
+type CeremonyRepository interface {
+	// SaveCeremony keeps the WebAuthn session data keyed by its challenge
+	// until the ceremony expires.
+	SaveCeremony(ctx context.Context, kind Kind, session webauthn.SessionData) error
+	// TakeCeremony returns the session data of a challenge and deletes it, so
+	// every challenge can be answered only once.
+	TakeCeremony(ctx context.Context, kind Kind, challenge string) (webauthn.SessionData, error)
+}
+
+type ICeremonyRepository struct {
+	redis *redis.Client
+}
+
+func NewCeremonyRepository(redis *redis.Client) CeremonyRepository {
+	return &ICeremonyRepository{redis: redis}
+}
+
+func (i *ICeremonyRepository) SaveCeremony(ctx context.Context, kind Kind, session webauthn.SessionData) error {
+	ttl := time.Until(session.Expires)
+	if ttl <= 0 {
+		return errors.New("ceremony has already expired")
+	}
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	err = i.redis.Set(ctx, ceremonyKey(kind, session.Challenge), data, ttl).Err()
+	if err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+
+	return nil
+}
+
+func (i *ICeremonyRepository) TakeCeremony(
+	ctx context.Context,
+	kind Kind,
+	challenge string,
+) (webauthn.SessionData, error) {
+	var session webauthn.SessionData
+
+	data, err := i.redis.GetDel(ctx, ceremonyKey(kind, challenge)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return session, ErrCeremonyNotFound
+	} else if err != nil {
+		return session, fmt.Errorf("redis error: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &session); err != nil {
+		return session, err
+	}
+
+	return session, nil
+}
+
+func ceremonyKey(kind Kind, challenge string) string {
+	return fmt.Sprintf("webauthn:%s:%s", kind, challenge)
+}
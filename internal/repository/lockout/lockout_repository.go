@@ -0,0 +1,104 @@
+package lockout
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Subject is what failed logins are counted against, an account or an IP
+// address.
+type Subject string
+
+func AccountSubject(email string) Subject {
+	return Subject("account:" + strings.ToLower(email))
+}
+
+func IPSubject(ip string) Subject {
+	return Subject("ip:" + ip)
+}
+
+type LockoutRepository interface {
+	// CountFailure increments the failed logins of the subject and returns
+	// the new count. The count is reset window after the first failure.
+	CountFailure(ctx context.Context, subject Subject, window time.Duration) (int64, error)
+	// Lock keeps the subject from logging in for ttl. A longer lock that is
+	// already in place is kept.
+	Lock(ctx context.Context, subject Subject, ttl time.Duration) error
+	// LockedFor returns how long the subject is still locked, zero when it
+	// isn't.
+	LockedFor(ctx context.Context, subject Subject) (time.Duration, error)
+	// Clear forgets the failures and the lock of the subject.
+	Clear(ctx context.Context, subject Subject) error
+}
+
+type ILockoutRepository struct {
+	redis *redis.Client
+}
+
+func NewLockoutRepository(redis *redis.Client) LockoutRepository {
+	return &ILockoutRepository{redis: redis}
+}
+
+func (i *ILockoutRepository) CountFailure(
+	ctx context.Context,
+	subject Subject,
+	window time.Duration,
+) (int64, error) {
+	key := failuresKey(subject)
+
+	pipe := i.redis.TxPipeline()
+	count := pipe.Incr(ctx, key)
+	pipe.ExpireNX(ctx, key, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("redis error: %w", err)
+	}
+
+	return count.Val(), nil
+}
+
+func (i *ILockoutRepository) Lock(ctx context.Context, subject Subject, ttl time.Duration) error {
+	key := lockKey(subject)
+
+	pipe := i.redis.TxPipeline()
+	pipe.SetNX(ctx, key, 1, ttl)
+	pipe.ExpireGT(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+
+	return nil
+}
+
+func (i *ILockoutRepository) LockedFor(ctx context.Context, subject Subject) (time.Duration, error) {
+	ttl, err := i.redis.PTTL(ctx, lockKey(subject)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("redis error: %w", err)
+	}
+
+	// PTTL is negative when the key doesn't exist or has no expiry.
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+func (i *ILockoutRepository) Clear(ctx context.Context, subject Subject) error {
+	if err := i.redis.Del(ctx, failuresKey(subject), lockKey(subject)).Err(); err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+
+	return nil
+}
+
+func failuresKey(subject Subject) string {
+	return fmt.Sprintf("login_failures:%s", subject)
+}
+
+func lockKey(subject Subject) string {
+	return fmt.Sprintf("login_lock:%s", subject)
+}
@@ -0,0 +1,56 @@
+package mfa
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+type MFARepository interface {
+	// MarkTOTPStepUsed records that a code of the given time step was used and
+	// reports false if it already was.
+	MarkTOTPStepUsed(ctx context.Context, userUUID uuid.UUID, step int64, ttl time.Duration) (bool, error)
+	// CountFailedAttempt increments the failed attempts made with an MFA
+	// token and returns the new count.
+	CountFailedAttempt(ctx context.Context, jti uuid.UUID, ttl time.Duration) (int64, error)
+}
+
+type IMFARepository struct {
+	redis *redis.Client
+}
+
+func NewMFARepository(redis *redis.Client) MFARepository {
+	return &IMFARepository{redis: redis}
+}
+
+func (i *IMFARepository) MarkTOTPStepUsed(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	step int64,
+	ttl time.Duration,
+) (bool, error) {
+	key := fmt.Sprintf("totp_used:%s:%d", userUUID, step)
+
+	ok, err := i.redis.SetNX(ctx, key, 1, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("redis error: %w", err)
+	}
+
+	return ok, nil
+}
+
+func (i *IMFARepository) CountFailedAttempt(ctx context.Context, jti uuid.UUID, ttl time.Duration) (int64, error) {
+	key := fmt.Sprintf("mfa_attempts:%s", jti)
+
+	pipe := i.redis.TxPipeline()
+	count := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("redis error: %w", err)
+	}
+
+	return count.Val(), nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"onboarding/internal/entity"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type MFARecoveryCodeRepository interface {
+	ReplaceRecoveryCodes(ctx context.Context, userUUID uuid.UUID, codes []entity.MFARecoveryCode) error
+	// FindUnusedRecoveryCodes returns the unused code with the lookup, and
+	// the codes made before lookups were kept.
+	FindUnusedRecoveryCodes(ctx context.Context, userUUID uuid.UUID, lookup string) ([]entity.MFARecoveryCode, error)
+	CountUnusedRecoveryCodes(ctx context.Context, userUUID uuid.UUID) (int64, error)
+	MarkRecoveryCodeUsed(ctx context.Context, id int64) error
+	DeleteRecoveryCodes(ctx context.Context, userUUID uuid.UUID) error
+}
+
+type IMFARecoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+func NewMFARecoveryCodeRepository(db *gorm.DB) MFARecoveryCodeRepository {
+	return &IMFARecoveryCodeRepository{db: db}
+}
+
+func (r *IMFARecoveryCodeRepository) ReplaceRecoveryCodes(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	codes []entity.MFARecoveryCode,
+) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_uuid = ?", userUUID).Delete(&entity.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		for i := range codes {
+			codes[i].UserUUID = userUUID
+		}
+
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *IMFARecoveryCodeRepository) FindUnusedRecoveryCodes(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	lookup string,
+) ([]entity.MFARecoveryCode, error) {
+	var codes []entity.MFARecoveryCode
+	err := r.db.WithContext(ctx).
+		Where("user_uuid = ? AND lookup IN (?, '') AND used_at IS NULL", userUUID, lookup).
+		Find(&codes).Error
+
+	return codes, err
+}
+
+func (r *IMFARecoveryCodeRepository) CountUnusedRecoveryCodes(ctx context.Context, userUUID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&entity.MFARecoveryCode{}).
+		Where("user_uuid = ? AND used_at IS NULL", userUUID).
+		Count(&count).Error
+
+	return count, err
+}
+
+// MarkRecoveryCodeUsed fails with gorm.ErrRecordNotFound when the code was
+// used concurrently, so a code can't be redeemed twice.
+func (r *IMFARecoveryCodeRepository) MarkRecoveryCodeUsed(ctx context.Context, id int64) error {
+	result := r.db.WithContext(ctx).
+		Model(&entity.MFARecoveryCode{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *IMFARecoveryCodeRepository) DeleteRecoveryCodes(ctx context.Context, userUUID uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("user_uuid = ?", userUUID).Delete(&entity.MFARecoveryCode{}).Error
+}
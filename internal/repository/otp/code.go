@@ -0,0 +1,76 @@
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"onboarding/pkg/notify"
+	"strings"
+	"time"
+)
+
+// storedOtp is what's kept in Redis for a code. Only a keyed digest of the
+// code is stored, so a dump of Redis doesn't give the codes away.
+type storedOtp struct {
+	Digest    string    `json:"digest"`
+	Attempts  int64     `json:"attempts"`
+	CreatedAt time.Time `json:"created_at"`
+	// IP is the address the code was requested from, if any.
+	IP string `json:"ip,omitempty"`
+}
+
+// generateCode draws a code of the given length from the alphabet.
+func generateCode(length int, alphabet string) (string, error) {
+	// Bytes past the last multiple of the alphabet size are skipped so every
+	// character is equally likely.
+	limit := 256 - 256%len(alphabet)
+
+	var code strings.Builder
+	b := make([]byte, 1)
+	for code.Len() < length {
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+
+		if int(b[0]) >= limit {
+			continue
+		}
+
+		code.WriteByte(alphabet[int(b[0])%len(alphabet)])
+	}
+
+	return code.String(), nil
+}
+
+// subject is what a code is bound to. Codes are kept under the e-mail
+// address, and a code the service always sends elsewhere is bound to that
+// address too, so it can't confirm another one. A ForUser code is bound to
+// the user as well, so it only confirms the address for them.
+func subject(to Recipient, service ServiceType) string {
+	subject := to.Email
+	if service.ForUser {
+		subject = to.UserUUID.String() + "\x00" + subject
+	}
+
+	if service.Channel == "" || service.Channel == notify.ChannelEmail {
+		return subject
+	}
+
+	return subject + "\x00" + to.address(service.Channel)
+}
+
+// digest binds the code to the service and subject it was sent for, so a
+// digest can't be moved to another key.
+func digest(secret []byte, service ServiceType, subject string, code string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(service.Code + "\x00" + subject + "\x00" + code))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// matchDigest compares in constant time, so the time taken doesn't tell how
+// much of a guess was right.
+func matchDigest(secret []byte, service ServiceType, subject string, code string, stored string) bool {
+	return hmac.Equal([]byte(digest(secret, service, subject, code)), []byte(stored))
+}
@@ -0,0 +1,141 @@
+package otp
+
+import (
+	"context"
+	"encoding/json"
+	"onboarding/pkg/config"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func TestGenerateCode(t *testing.T) {
+	testCases := []struct {
+		length   int
+		alphabet string
+	}{
+		{6, "0123456789"},
+		{8, "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"},
+		{4, "01"},
+	}
+
+	for _, tc := range testCases {
+		seen := map[rune]bool{}
+		for range 200 {
+			code, err := generateCode(tc.length, tc.alphabet)
+			require.NoError(t, err)
+			require.Len(t, code, tc.length)
+
+			for _, c := range code {
+				require.True(t, strings.ContainsRune(tc.alphabet, c), "%q isn't in %q", c, tc.alphabet)
+				seen[c] = true
+			}
+		}
+
+		// Every character gets drawn eventually.
+		require.Len(t, seen, len(tc.alphabet), tc.alphabet)
+	}
+}
+
+func TestDigestBindsServiceAndEmail(t *testing.T) {
+	stored := digest(testSecret, ServiceVerifyEmail, "user@example.com", "123456")
+
+	require.True(t, matchDigest(testSecret, ServiceVerifyEmail, "user@example.com", "123456", stored))
+	require.False(t, matchDigest(testSecret, ServiceVerifyEmail, "user@example.com", "654321", stored))
+	require.False(t, matchDigest(testSecret, ServiceForgotPassword, "user@example.com", "123456", stored))
+	require.False(t, matchDigest(testSecret, ServiceVerifyEmail, "other@example.com", "123456", stored))
+	require.False(t, matchDigest([]byte("another secret"), ServiceVerifyEmail, "user@example.com", "123456", stored))
+}
+
+func newTestOtpRepository(t *testing.T) (*IOtpRepository, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return &IOtpRepository{
+		redis:  client,
+		otpCfg: config.OTP{MaxVerifyAttempts: 3, Secret: testSecret},
+	}, server
+}
+
+// storeCode keeps a code the way SendOtp does, without sending it.
+func storeCode(t *testing.T, server *miniredis.Miniredis, service ServiceType, to Recipient, code string) {
+	stored, err := json.Marshal(storedOtp{
+		Digest:    digest(testSecret, service, subject(to, service), code),
+		CreatedAt: time.Now(),
+	})
+	require.NoError(t, err)
+
+	key := codeKey(to, service)
+	require.NoError(t, server.Set(key, string(stored)))
+	server.SetTTL(key, otpTTL)
+}
+
+func TestVerifyOtpUsesCodeOnce(t *testing.T) {
+	repo, server := newTestOtpRepository(t)
+	ctx := context.Background()
+	user := Recipient{Email: "user@example.com"}
+	storeCode(t, server, ServiceVerifyEmail, user, "123456")
+
+	require.ErrorIs(t, repo.VerifyOtp(ctx, user, "123456", ServiceForgotPassword), ErrOtpExpired)
+	require.NoError(t, repo.VerifyOtp(ctx, user, "123456", ServiceVerifyEmail))
+	require.ErrorIs(t, repo.VerifyOtp(ctx, user, "123456", ServiceVerifyEmail), ErrOtpExpired)
+}
+
+func TestVerifyOtpBurnsCodeAfterMaxAttempts(t *testing.T) {
+	repo, server := newTestOtpRepository(t)
+	ctx := context.Background()
+	user := Recipient{Email: "user@example.com"}
+	storeCode(t, server, ServiceVerifyEmail, user, "123456")
+
+	require.ErrorIs(t, repo.VerifyOtp(ctx, user, "000000", ServiceVerifyEmail), ErrOtpInvalid)
+	require.ErrorIs(t, repo.VerifyOtp(ctx, user, "000000", ServiceVerifyEmail), ErrOtpInvalid)
+
+	// Wrong guesses are counted without extending the code's lifetime.
+	var stored storedOtp
+	raw, err := server.Get(otpKey(ServiceVerifyEmail, "user@example.com"))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal([]byte(raw), &stored))
+	require.Equal(t, int64(2), stored.Attempts)
+	require.Equal(t, otpTTL, server.TTL(otpKey(ServiceVerifyEmail, "user@example.com")))
+
+	require.ErrorIs(t, repo.VerifyOtp(ctx, user, "000000", ServiceVerifyEmail), ErrOtpAttemptsExceeded)
+
+	// The right code doesn't help anymore.
+	require.ErrorIs(t, repo.VerifyOtp(ctx, user, "123456", ServiceVerifyEmail), ErrOtpExpired)
+}
+
+func TestVerifyOtpBindsTextedCodeToPhone(t *testing.T) {
+	repo, server := newTestOtpRepository(t)
+	ctx := context.Background()
+	user := Recipient{Email: "user@example.com", Phone: "+15550100"}
+	storeCode(t, server, ServiceVerifyPhone, user, "123456")
+
+	// The number changed after the code was texted.
+	changed := Recipient{Email: "user@example.com", Phone: "+15550199"}
+	require.ErrorIs(t, repo.VerifyOtp(ctx, changed, "123456", ServiceVerifyPhone), ErrOtpInvalid)
+
+	require.NoError(t, repo.VerifyOtp(ctx, user, "123456", ServiceVerifyPhone))
+}
+
+func TestVerifyOtpBindsChangeEmailCodeToUser(t *testing.T) {
+	repo, server := newTestOtpRepository(t)
+	ctx := context.Background()
+	requester := Recipient{UserUUID: uuid.New(), Email: "new@example.com"}
+	storeCode(t, server, ServiceChangeEmail, requester, "123456")
+
+	// Another user asking for the same address doesn't get to use the code.
+	other := Recipient{UserUUID: uuid.New(), Email: "new@example.com"}
+	require.ErrorIs(t, repo.VerifyOtp(ctx, other, "123456", ServiceChangeEmail), ErrOtpExpired)
+
+	storeCode(t, server, ServiceChangeEmail, other, "654321")
+	require.ErrorIs(t, repo.VerifyOtp(ctx, requester, "654321", ServiceChangeEmail), ErrOtpInvalid)
+	require.NoError(t, repo.VerifyOtp(ctx, requester, "123456", ServiceChangeEmail))
+}
@@ -1,158 +1,382 @@
 package otp
 
 import (
-	"bytes"
 	"context"
-	"crypto/tls"
+	cryptorand "crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"math/rand"
-	"net/smtp"
+	"net/url"
 	"onboarding/pkg/config"
-	"text/template"
+	"onboarding/pkg/notify"
+	"onboarding/pkg/templates"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
 const (
-	emailTemplate = `
-					<!DOCTYPE html>
-					<html>
-					<body style="font-family: Helvetica, Arial; padding: 24px; color: #333;">
-					  <h2>{{.Service}}</h2>
-					  <p>To continue, use the verification code below:</p>
-					  <div style="
-					    margin: 24px 0;
-					    padding: 16px 24px;
-					    font-size: 32px;
-					    font-weight: 700;
-					    letter-spacing: 6px;
-					    border: 1px solid #ccc;
-					    border-radius: 8px;
-					    background: #fafafa;
-					  ">
-					    {{.OTP}}
-					  </div>
-					  <p>This code expires in 5 minutes. Please do not share it.</p>
-					</body>
-					</html>`
+	// otpTTL is how long a code can be used after it was sent.
+	otpTTL = 5 * time.Minute
+	// verifyRetries is how often a verification is retried when the code
+	// changed while it was checked.
+	verifyRetries = 3
 )
 
+var (
+	ErrOtpExpired          = errors.New("invalid or expired otp")
+	ErrOtpInvalid          = errors.New("invalid otp")
+	ErrOtpAttemptsExceeded = errors.New("too many invalid attempts, request a new otp")
+	ErrOtpCooldown         = errors.New("otp was sent recently, wait before requesting another one")
+	ErrOtpQuotaExceeded    = errors.New("too many otps were requested, try again later")
+	ErrMagicLinkInvalid    = errors.New("invalid or expired magic link")
+)
+
+// OtpRepository sends one-time codes and links. Sends are limited by a
+// cooldown per service and address and by hourly quotas per address and per
+// IP address. The IP address may be empty when the send isn't made on behalf
+// of a client, only the per address limits apply then.
 type OtpRepository interface {
-	SendOtp(ctx context.Context, email string, service ServiceType) error
-	VerifyOtp(ctx context.Context, email string, otp string, service ServiceType) error
+	// SendOtp delivers the code over the service's channel, or else the
+	// recipient's preferred one, and falls back to e-mail when the preferred
+	// channel can't reach them.
+	SendOtp(ctx context.Context, to Recipient, ip string, service ServiceType) error
+	// VerifyOtp checks a code sent to the recipient by SendOtp, and burns it
+	// after too many wrong guesses.
+	VerifyOtp(ctx context.Context, to Recipient, otp string, service ServiceType) error
+	SendMagicLink(ctx context.Context, to Recipient, ip string) error
+	// ConsumeMagicLink returns the e-mail address the link was sent to. The
+	// link can't be used again afterwards.
+	ConsumeMagicLink(ctx context.Context, token string) (string, error)
+	// SendNotice delivers an informational message that needs no action, the
+	// same way as SendOtp. data fills in the service's notice template.
+	SendNotice(ctx context.Context, to Recipient, service ServiceType, data map[string]any) error
 }
 
 type IOtpRepository struct {
-	redis   *redis.Client
-	smtpCfg config.SMTP
+	redis        *redis.Client
+	notifier     notify.Sender
+	catalog      *templates.Catalog
+	magicLinkCfg config.MagicLink
+	otpCfg       config.OTP
 }
 
-func NewOtpRepository(redis *redis.Client, smtpCfg config.SMTP) OtpRepository {
-	return &IOtpRepository{redis: redis, smtpCfg: smtpCfg}
+func NewOtpRepository(
+	redis *redis.Client,
+	notifier notify.Sender,
+	catalog *templates.Catalog,
+	magicLinkCfg config.MagicLink,
+	otpCfg config.OTP,
+) OtpRepository {
+	return &IOtpRepository{
+		redis:        redis,
+		notifier:     notifier,
+		catalog:      catalog,
+		magicLinkCfg: magicLinkCfg,
+		otpCfg:       otpCfg,
+	}
 }
 
-func (i *IOtpRepository) SendOtp(ctx context.Context, email string, service ServiceType) error {
-	otp := fmt.Sprintf("%06d", rand.Intn(1000000))
+func (i *IOtpRepository) SendOtp(ctx context.Context, to Recipient, ip string, service ServiceType) error {
+	msg, err := i.route(to, service)
+	if err != nil {
+		return err
+	}
+
+	if err := i.checkSendLimits(ctx, to, ip, service); err != nil {
+		return err
+	}
 
-	var body bytes.Buffer
-	tmpl, err := template.New("otp").Parse(emailTemplate)
+	otp, err := generateCode(i.otpCfg.Length, i.otpCfg.Alphabet)
 	if err != nil {
-		return fmt.Errorf("template parse: %w", err)
+		return fmt.Errorf("otp: %w", err)
 	}
 
-	if err := tmpl.Execute(&body, map[string]string{
-		"OTP":     otp,
-		"Service": service.Name,
-	}); err != nil {
-		return fmt.Errorf("template execute: %w", err)
+	rendered, err := i.render(ctx, to, service.Code+".otp", map[string]any{
+		"Code":    otp,
+		"Minutes": int(otpTTL.Minutes()),
+	})
+	if err != nil {
+		return err
 	}
 
-	if err := sendEmailSMTP(i.smtpCfg, email, service.Name, body.String()); err != nil {
-		return fmt.Errorf("send email: %w", err)
+	stored, err := json.Marshal(storedOtp{
+		Digest:    digest(i.otpCfg.Secret, service, subject(to, service), otp),
+		CreatedAt: time.Now(),
+		IP:        ip,
+	})
+	if err != nil {
+		return err
 	}
 
-	key := fmt.Sprintf("otp:%s:%s", service.Code, email)
-	err = i.redis.Set(ctx, key, otp, 5*time.Minute).Err()
+	// The code is stored before it's handed over for delivery, so it can be
+	// used as soon as it arrives.
+	err = i.redis.Set(ctx, codeKey(to, service), stored, otpTTL).Err()
 	if err != nil {
 		return fmt.Errorf("redis error: %w", err)
 	}
 
+	msg = withContent(msg, rendered)
+	msg.ExpiresAt = time.Now().Add(otpTTL)
+	if err := i.notifier.Notify(ctx, msg); err != nil {
+		return fmt.Errorf("send otp: %w", err)
+	}
+
 	return nil
 }
 
-func (i *IOtpRepository) VerifyOtp(ctx context.Context, email string, otp string, service ServiceType) error {
-	key := fmt.Sprintf("otp:%s:%s", service.Code, email)
+func (i *IOtpRepository) VerifyOtp(ctx context.Context, to Recipient, otp string, service ServiceType) error {
+	key := codeKey(to, service)
 
-	stored, err := i.redis.Get(ctx, key).Result()
-	if err == redis.Nil {
-		return errors.New("invalid or expired otp")
+	// The record is watched so concurrent guesses can't lose attempts or use
+	// the same code twice. A guess that loses the race is retried.
+	for range verifyRetries {
+		var result error
+		err := i.redis.Watch(ctx, func(tx *redis.Tx) error {
+			result = nil
+
+			raw, err := tx.Get(ctx, key).Bytes()
+			if err == redis.Nil {
+				result = ErrOtpExpired
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			var stored storedOtp
+			if err := json.Unmarshal(raw, &stored); err != nil {
+				return err
+			}
+
+			if matchDigest(i.otpCfg.Secret, service, subject(to, service), otp, stored.Digest) {
+				_, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+					pipe.Del(ctx, key)
+					return nil
+				})
+				return err
+			}
+
+			// The code is burned after too many wrong guesses.
+			stored.Attempts++
+			if stored.Attempts >= i.otpCfg.MaxVerifyAttempts {
+				result = ErrOtpAttemptsExceeded
+				_, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+					pipe.Del(ctx, key)
+					return nil
+				})
+				return err
+			}
+
+			updated, err := json.Marshal(stored)
+			if err != nil {
+				return err
+			}
+
+			result = ErrOtpInvalid
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.SetArgs(ctx, key, updated, redis.SetArgs{KeepTTL: true})
+				return nil
+			})
+			return err
+		}, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("redis error: %w", err)
+		}
+
+		return result
 	}
+
+	return ErrOtpInvalid
+}
+
+// checkSendLimits counts a send against the cooldown and the quotas, and
+// fails when one of them is used up.
+func (i *IOtpRepository) checkSendLimits(ctx context.Context, to Recipient, ip string, service ServiceType) error {
+	cooldownKey := codeKey(to, service) + ":cooldown"
+	fresh, err := i.redis.SetNX(ctx, cooldownKey, 1, i.otpCfg.ResendCooldown).Result()
 	if err != nil {
 		return fmt.Errorf("redis error: %w", err)
 	}
 
-	if stored != otp {
-		return errors.New("invalid otp")
+	if !fresh {
+		return ErrOtpCooldown
+	}
+
+	quotas := map[string]int64{
+		fmt.Sprintf("otp:quota:email:%s", to.Email): i.otpCfg.EmailHourlyQuota,
+	}
+	if ip != "" {
+		quotas[fmt.Sprintf("otp:quota:ip:%s", ip)] = i.otpCfg.IPHourlyQuota
+	}
+
+	pipe := i.redis.TxPipeline()
+	counts := make(map[string]*redis.IntCmd, len(quotas))
+	for key := range quotas {
+		counts[key] = pipe.Incr(ctx, key)
+		pipe.ExpireNX(ctx, key, time.Hour)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("redis error: %w", err)
 	}
 
-	i.redis.Del(ctx, key)
+	for key, quota := range quotas {
+		if counts[key].Val() > quota {
+			return ErrOtpQuotaExceeded
+		}
+	}
 
 	return nil
 }
 
-func sendEmailSMTP(cfg config.SMTP, to string, service string, htmlBody string) error {
-	tlsConfig := &tls.Config{
-		InsecureSkipVerify: false,
-		ServerName:         cfg.Host,
+// SendMagicLink emails a link carrying a random token. Only a hash of the
+// token is kept, so the stored keys can't be used to sign in.
+func (i *IOtpRepository) SendMagicLink(ctx context.Context, to Recipient, ip string) error {
+	msg, err := i.route(to, ServiceMagicLink)
+	if err != nil {
+		return err
 	}
 
-	conn, err := tls.Dial("tcp", fmt.Sprintf("%s:%s", cfg.Host, cfg.Port), tlsConfig)
-	if err != nil {
-		return fmt.Errorf("tls dial error: %w", err)
+	email := to.Email
+	if err := i.checkSendLimits(ctx, to, ip, ServiceMagicLink); err != nil {
+		return err
 	}
 
-	client, err := smtp.NewClient(conn, cfg.Host)
-	if err != nil {
-		return fmt.Errorf("smtp client error: %w", err)
+	raw := make([]byte, 32)
+	if _, err := cryptorand.Read(raw); err != nil {
+		return fmt.Errorf("magic link token: %w", err)
 	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
 
-	auth := smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
-	if err := client.Auth(auth); err != nil {
-		return fmt.Errorf("smtp auth error: %w", err)
+	link, err := url.Parse(i.magicLinkCfg.URL)
+	if err != nil {
+		return fmt.Errorf("magic link url: %w", err)
 	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
 
-	if err := client.Mail(cfg.Username); err != nil {
+	rendered, err := i.render(ctx, to, ServiceMagicLink.Code+".link", map[string]any{
+		"Link":    link.String(),
+		"Minutes": int(i.magicLinkCfg.TTL.Minutes()),
+	})
+	if err != nil {
 		return err
 	}
 
-	if err := client.Rcpt(to); err != nil {
-		return err
+	err = i.redis.Set(ctx, magicLinkKey(token), email, i.magicLinkCfg.TTL).Err()
+	if err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+
+	msg = withContent(msg, rendered)
+	msg.ExpiresAt = time.Now().Add(i.magicLinkCfg.TTL)
+	if err := i.notifier.Notify(ctx, msg); err != nil {
+		return fmt.Errorf("send magic link: %w", err)
 	}
 
-	w, err := client.Data()
+	return nil
+}
+
+func (i *IOtpRepository) ConsumeMagicLink(ctx context.Context, token string) (string, error) {
+	email, err := i.redis.GetDel(ctx, magicLinkKey(token)).Result()
+	if err == redis.Nil {
+		return "", ErrMagicLinkInvalid
+	}
 	if err != nil {
-		return err
+		return "", fmt.Errorf("redis error: %w", err)
 	}
 
-	// Build MIME email
-	var msg bytes.Buffer
-	msg.WriteString("From: " + cfg.FromName + "\r\n")
-	msg.WriteString("To: " + to + "\r\n")
-	msg.WriteString("Subject: " + service + " Verification Code\r\n")
-	msg.WriteString("MIME-Version: 1.0\r\n")
-	msg.WriteString("Content-Type: text/html; charset=\"UTF-8\"\r\n")
-	msg.WriteString("\r\n")
-	msg.WriteString(htmlBody)
+	return email, nil
+}
 
-	if _, err := w.Write(msg.Bytes()); err != nil {
+func (i *IOtpRepository) SendNotice(
+	ctx context.Context,
+	to Recipient,
+	service ServiceType,
+	data map[string]any,
+) error {
+	msg, err := i.route(to, service)
+	if err != nil {
 		return err
 	}
-	if err := w.Close(); err != nil {
+
+	rendered, err := i.render(ctx, to, service.Code+".notice", data)
+	if err != nil {
 		return err
 	}
 
-	client.Quit()
+	if err := i.notifier.Notify(ctx, withContent(msg, rendered)); err != nil {
+		return fmt.Errorf("send notice: %w", err)
+	}
+
 	return nil
 }
+
+// render renders the template in the recipient's locale, or else in one the
+// client asked for.
+func (i *IOtpRepository) render(
+	ctx context.Context,
+	to Recipient,
+	name string,
+	data map[string]any,
+) (templates.Rendered, error) {
+	preferences := templates.Locales(ctx)
+	if to.Locale != "" {
+		preferences = append([]string{to.Locale}, preferences...)
+	}
+
+	return i.catalog.Render([]string{name}, preferences, data)
+}
+
+func withContent(msg notify.Message, rendered templates.Rendered) notify.Message {
+	msg.Subject = rendered.Subject
+	msg.HTML = rendered.HTML
+	msg.Text = rendered.Text
+
+	return msg
+}
+
+// route picks the channel a message to the recipient goes over. A channel
+// fixed by the service has to work, a preferred one falls back to e-mail.
+func (i *IOtpRepository) route(to Recipient, service ServiceType) (notify.Message, error) {
+	if service.Channel != "" {
+		address := to.address(service.Channel)
+		if address == "" || !i.notifier.Supports(service.Channel) {
+			return notify.Message{}, fmt.Errorf("%w: %s", notify.ErrChannelUnavailable, service.Channel)
+		}
+
+		return notify.Message{Channel: service.Channel, To: address}, nil
+	}
+
+	if address := to.address(to.Channel); address != "" && i.notifier.Supports(to.Channel) {
+		return notify.Message{Channel: to.Channel, To: address}, nil
+	}
+
+	return notify.Message{Channel: notify.ChannelEmail, To: to.Email}, nil
+}
+
+func otpKey(service ServiceType, email string) string {
+	return fmt.Sprintf("otp:%s:%s", service.Code, email)
+}
+
+// codeKey is where the code sent to the recipient is kept. Codes of
+// ForUser services are kept under the user, so users asking for the same
+// address don't replace each other's.
+func codeKey(to Recipient, service ServiceType) string {
+	if service.ForUser {
+		return otpKey(service, to.UserUUID.String())
+	}
+
+	return otpKey(service, to.Email)
+}
+
+func magicLinkKey(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return fmt.Sprintf("otp:%s:%x", ServiceMagicLink.Code, hash)
+}
@@ -0,0 +1,51 @@
+package otp
+
+import (
+	"onboarding/internal/entity"
+	"onboarding/pkg/notify"
+
+	"github.com/google/uuid"
+)
+
+// Recipient is who a code or notice is sent to. Codes are always keyed by
+// the e-mail address, whichever channel delivers them.
+type Recipient struct {
+	UserUUID uuid.UUID
+	Email    string
+	Phone    string
+	// Channel is the preferred channel. Messages go by e-mail when it's
+	// empty or can't reach the recipient.
+	Channel notify.Channel
+	// Locale is the preferred locale. The client's are used when it's empty.
+	Locale string
+}
+
+// NewRecipient addresses the user, with their phone number only when it has
+// been verified.
+func NewRecipient(user entity.User) Recipient {
+	return Recipient{
+		UserUUID: user.UUID,
+		Email:    user.Email,
+		Phone:    user.VerifiedPhone(),
+		Channel:  user.NotificationChannel,
+		Locale:   user.Locale,
+	}
+}
+
+// address returns the recipient's address on the channel, or "" when they
+// can't be reached there.
+func (r Recipient) address(channel notify.Channel) string {
+	switch channel {
+	case notify.ChannelEmail:
+		return r.Email
+	case notify.ChannelSMS:
+		return r.Phone
+	case notify.ChannelWebhook:
+		if r.UserUUID == uuid.Nil {
+			return ""
+		}
+		return r.UserUUID.String()
+	}
+
+	return ""
+}
@@ -1,8 +1,17 @@
 package otp
 
+import "onboarding/pkg/notify"
+
 type ServiceType struct {
 	Code string
 	Name string
+	// Channel is where the service's messages always go. When it's empty
+	// they follow the recipient's preference.
+	Channel notify.Channel
+	// ForUser services send codes to an address a signed in user asked to
+	// switch to. The code is kept under and bound to the user, who has to
+	// be set on the recipient.
+	ForUser bool
 }
 
 var (
@@ -10,4 +19,39 @@ var (
 		Code: "forgot",
 		Name: "Forgot Password",
 	}
+	ServiceVerifyEmail = ServiceType{
+		Code:    "verify",
+		Name:    "E-mail Verification",
+		Channel: notify.ChannelEmail,
+	}
+	ServiceChangeEmail = ServiceType{
+		Code:    "change_email",
+		Name:    "E-mail Change",
+		Channel: notify.ChannelEmail,
+		ForUser: true,
+	}
+	ServiceMagicLink = ServiceType{
+		Code:    "magic",
+		Name:    "Sign In",
+		Channel: notify.ChannelEmail,
+	}
+	ServiceAccountLocked = ServiceType{
+		Code: "locked",
+		Name: "Account Locked",
+	}
+	ServiceVerifyPhone = ServiceType{
+		Code:    "verify_phone",
+		Name:    "Phone Verification",
+		Channel: notify.ChannelSMS,
+	}
+	ServiceChangePhone = ServiceType{
+		Code:    "change_phone",
+		Name:    "Phone Change",
+		Channel: notify.ChannelEmail,
+	}
+	ServiceChangeChannel = ServiceType{
+		Code:    "change_channel",
+		Name:    "Notification Channel Change",
+		Channel: notify.ChannelEmail,
+	}
 )
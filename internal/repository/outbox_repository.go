@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"onboarding/internal/entity"
+	"onboarding/pkg/notify"
+	"onboarding/pkg/outbox"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OutboxRepository is the outbox.Store kept in the database, with what admins
+// need to look after it. Enqueue joins the transaction a Transactor put in
+// the context, so messages are only queued when the write they belong to
+// commits.
+type OutboxRepository interface {
+	outbox.Store
+	// ListOutboxMessages returns a page of the messages matching the filter,
+	// newest first, and how many match in total.
+	ListOutboxMessages(ctx context.Context, filter entity.OutboxFilter) ([]entity.OutboxMessage, int64, error)
+	GetOutboxMessage(ctx context.Context, uuid uuid.UUID) (entity.OutboxMessage, error)
+	// ReplayOutboxMessage queues a dead message again with fresh retries, and
+	// fails with gorm.ErrRecordNotFound when it isn't dead (anymore) or
+	// expires.
+	ReplayOutboxMessage(ctx context.Context, uuid uuid.UUID) error
+}
+
+type IOutboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &IOutboxRepository{db: db}
+}
+
+func (r *IOutboxRepository) Enqueue(ctx context.Context, msg notify.Message) error {
+	now := time.Now()
+
+	var expiresAt *time.Time
+	if !msg.ExpiresAt.IsZero() {
+		expiresAt = &msg.ExpiresAt
+	}
+
+	return conn(ctx, r.db).Create(&entity.OutboxMessage{
+		UUID:          uuid.New(),
+		Channel:       msg.Channel,
+		Recipient:     msg.To,
+		Subject:       msg.Subject,
+		HTML:          msg.HTML,
+		Text:          msg.Text,
+		Status:        entity.OutboxStatusPending,
+		NextAttemptAt: now,
+		ExpiresAt:     expiresAt,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}).Error
+}
+
+// Claim skips the rows other workers have locked, so concurrent workers
+// never claim the same message.
+func (r *IOutboxRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]outbox.Message, error) {
+	now := time.Now()
+
+	var claimed []entity.OutboxMessage
+	err := conn(ctx, r.db).Raw(`
+		UPDATE outbox_messages
+		SET status = ?, attempts = attempts + 1, next_attempt_at = ?, updated_at = ?
+		WHERE id IN (
+			SELECT id FROM outbox_messages
+			WHERE status IN ?
+			AND next_attempt_at <= ?
+			ORDER BY next_attempt_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		entity.OutboxStatusSending,
+		now.Add(lease),
+		now,
+		[]entity.OutboxStatus{entity.OutboxStatusPending, entity.OutboxStatusSending},
+		now,
+		limit,
+	).Scan(&claimed).Error
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]outbox.Message, 0, len(claimed))
+	for _, m := range claimed {
+		notification := notify.Message{
+			Channel: m.Channel,
+			To:      m.Recipient,
+			Subject: m.Subject,
+			HTML:    m.HTML,
+			Text:    m.Text,
+		}
+		if m.ExpiresAt != nil {
+			notification.ExpiresAt = *m.ExpiresAt
+		}
+
+		messages = append(messages, outbox.Message{
+			ID:           m.ID,
+			Attempts:     m.Attempts,
+			Notification: notification,
+		})
+	}
+
+	return messages, nil
+}
+
+func (r *IOutboxRepository) MarkSent(ctx context.Context, id int64) error {
+	now := time.Now()
+
+	return conn(ctx, r.db).
+		Model(&entity.OutboxMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status":     entity.OutboxStatusSent,
+			"html":       "",
+			"text":       "",
+			"last_error": "",
+			"sent_at":    now,
+			"updated_at": now,
+		}).Error
+}
+
+func (r *IOutboxRepository) MarkFailed(ctx context.Context, id int64, cause string, retryAt *time.Time) error {
+	now := time.Now()
+	updates := map[string]any{
+		"status":     entity.OutboxStatusPending,
+		"last_error": cause,
+		"updated_at": now,
+	}
+
+	if retryAt != nil {
+		updates["next_attempt_at"] = *retryAt
+	} else {
+		updates["status"] = entity.OutboxStatusDead
+		// Dead messages that expire are never sent, so the codes in them
+		// aren't kept around.
+		updates["html"] = gorm.Expr("CASE WHEN expires_at IS NULL THEN html ELSE '' END")
+		updates["text"] = gorm.Expr("CASE WHEN expires_at IS NULL THEN text ELSE '' END")
+	}
+
+	return conn(ctx, r.db).
+		Model(&entity.OutboxMessage{}).
+		Where("id = ?", id).
+		Updates(updates).Error
+}
+
+func (r *IOutboxRepository) ListOutboxMessages(
+	ctx context.Context,
+	filter entity.OutboxFilter,
+) ([]entity.OutboxMessage, int64, error) {
+	query := conn(ctx, r.db).Model(&entity.OutboxMessage{})
+
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var messages []entity.OutboxMessage
+	err := query.
+		Order("created_at DESC, id DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&messages).Error
+
+	return messages, total, err
+}
+
+func (r *IOutboxRepository) GetOutboxMessage(ctx context.Context, uuid uuid.UUID) (entity.OutboxMessage, error) {
+	var message entity.OutboxMessage
+	err := conn(ctx, r.db).Take(&message, "uuid = ?", uuid).Error
+
+	return message, err
+}
+
+func (r *IOutboxRepository) ReplayOutboxMessage(ctx context.Context, uuid uuid.UUID) error {
+	now := time.Now()
+
+	result := conn(ctx, r.db).
+		Model(&entity.OutboxMessage{}).
+		Where("uuid = ? AND status = ? AND expires_at IS NULL", uuid, entity.OutboxStatusDead).
+		Updates(map[string]any{
+			"status":          entity.OutboxStatusPending,
+			"attempts":        0,
+			"next_attempt_at": now,
+			"updated_at":      now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
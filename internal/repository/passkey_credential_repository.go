@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"onboarding/internal/entity"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PasskeyCredentialRepository interface {
+	CreatePasskeyCredential(ctx context.Context, credential entity.PasskeyCredential) error
+	ListPasskeyCredentials(ctx context.Context, userUUID uuid.UUID) ([]entity.PasskeyCredential, error)
+	UpdatePasskeyCredential(ctx context.Context, credentialID []byte, credential []byte, lastUsedAt time.Time) error
+	DeletePasskeyCredential(ctx context.Context, userUUID uuid.UUID, uuid uuid.UUID) error
+}
+
+type IPasskeyCredentialRepository struct {
+	db *gorm.DB
+}
+
+func NewPasskeyCredentialRepository(db *gorm.DB) PasskeyCredentialRepository {
+	return &IPasskeyCredentialRepository{db: db}
+}
+
+func (r *IPasskeyCredentialRepository) CreatePasskeyCredential(
+	ctx context.Context,
+	credential entity.PasskeyCredential,
+) error {
+	return r.db.WithContext(ctx).Create(&credential).Error
+}
+
+func (r *IPasskeyCredentialRepository) ListPasskeyCredentials(
+	ctx context.Context,
+	userUUID uuid.UUID,
+) ([]entity.PasskeyCredential, error) {
+	var credentials []entity.PasskeyCredential
+	err := r.db.WithContext(ctx).
+		Where("user_uuid = ?", userUUID).
+		Order("created_at").
+		Find(&credentials).Error
+
+	return credentials, err
+}
+
+// UpdatePasskeyCredential stores the credential again after a login, so the
+// signature counter used for clone detection stays current.
+func (r *IPasskeyCredentialRepository) UpdatePasskeyCredential(
+	ctx context.Context,
+	credentialID []byte,
+	credential []byte,
+	lastUsedAt time.Time,
+) error {
+	return r.db.WithContext(ctx).
+		Model(&entity.PasskeyCredential{}).
+		Where("credential_id = ?", credentialID).
+		Updates(map[string]any{
+			"credential":   credential,
+			"last_used_at": lastUsedAt,
+		}).Error
+}
+
+func (r *IPasskeyCredentialRepository) DeletePasskeyCredential(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	uuid uuid.UUID,
+) error {
+	result := r.db.WithContext(ctx).
+		Where("uuid = ? AND user_uuid = ?", uuid, userUUID).
+		Delete(&entity.PasskeyCredential{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
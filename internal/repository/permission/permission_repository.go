@@ -0,0 +1,112 @@
+package permission
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// PermissionRepository caches what permission checks read from Postgres: the
+// role of every user and the permissions of every role. Entries are
+// invalidated when they change and expire in case an invalidation is missed.
+type PermissionRepository interface {
+	GetUserRole(ctx context.Context, userUUID uuid.UUID) (string, bool, error)
+	SetUserRole(ctx context.Context, userUUID uuid.UUID, role string) error
+	InvalidateUserRole(ctx context.Context, userUUID uuid.UUID) error
+	GetRolePermissions(ctx context.Context, role string) ([]string, bool, error)
+	SetRolePermissions(ctx context.Context, role string, permissions []string) error
+	InvalidateRolePermissions(ctx context.Context, role string) error
+}
+
+const cacheTTL = 10 * time.Minute
+
+type IPermissionRepository struct {
+	redis *redis.Client
+}
+
+func NewPermissionRepository(redis *redis.Client) PermissionRepository {
+	return &IPermissionRepository{redis: redis}
+}
+
+func (i *IPermissionRepository) GetUserRole(ctx context.Context, userUUID uuid.UUID) (string, bool, error) {
+	role, err := i.redis.Get(ctx, userRoleKey(userUUID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, fmt.Errorf("redis error: %w", err)
+	}
+
+	return role, true, nil
+}
+
+func (i *IPermissionRepository) SetUserRole(ctx context.Context, userUUID uuid.UUID, role string) error {
+	if err := i.redis.Set(ctx, userRoleKey(userUUID), role, cacheTTL).Err(); err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+
+	return nil
+}
+
+func (i *IPermissionRepository) InvalidateUserRole(ctx context.Context, userUUID uuid.UUID) error {
+	if err := i.redis.Del(ctx, userRoleKey(userUUID)).Err(); err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+
+	return nil
+}
+
+func (i *IPermissionRepository) GetRolePermissions(ctx context.Context, role string) ([]string, bool, error) {
+	data, err := i.redis.Get(ctx, rolePermissionsKey(role)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, fmt.Errorf("redis error: %w", err)
+	}
+
+	var permissions []string
+	if err := json.Unmarshal(data, &permissions); err != nil {
+		return nil, false, err
+	}
+
+	return permissions, true, nil
+}
+
+// SetRolePermissions stores the list as JSON, so a role without any
+// permission is cached too.
+func (i *IPermissionRepository) SetRolePermissions(ctx context.Context, role string, permissions []string) error {
+	if permissions == nil {
+		permissions = []string{}
+	}
+
+	data, err := json.Marshal(permissions)
+	if err != nil {
+		return err
+	}
+
+	if err := i.redis.Set(ctx, rolePermissionsKey(role), data, cacheTTL).Err(); err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+
+	return nil
+}
+
+func (i *IPermissionRepository) InvalidateRolePermissions(ctx context.Context, role string) error {
+	if err := i.redis.Del(ctx, rolePermissionsKey(role)).Err(); err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+
+	return nil
+}
+
+func userRoleKey(userUUID uuid.UUID) string {
+	return fmt.Sprintf("authz:user_role:%s", userUUID)
+}
+
+func rolePermissionsKey(role string) string {
+	return fmt.Sprintf("authz:role_permissions:%s", role)
+}
@@ -0,0 +1,147 @@
+package refresh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"onboarding/pkg/token"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrTokenRevoked = errors.New("refresh token is revoked or expired")
+	ErrTokenReused  = errors.New("refresh token reuse detected, please login again")
+)
+
+// rotateScript marks the presented token as used and registers its successor
+// in the same family. Presenting a token that was already used revokes every
+// token of the family.
+//
+// KEYS[1] presented token, KEYS[2] family, KEYS[3] successor token
+// ARGV[1] family id, ARGV[2] successor jti, ARGV[3] successor ttl (ms), ARGV[4] token key prefix
+var rotateScript = redis.NewScript(`
+local family = redis.call('HGET', KEYS[1], 'family')
+if not family or family ~= ARGV[1] then
+	return -1
+end
+
+if redis.call('HGET', KEYS[1], 'used') == '1' then
+	for _, jti in ipairs(redis.call('SMEMBERS', KEYS[2])) do
+		redis.call('DEL', ARGV[4] .. jti)
+	end
+	redis.call('DEL', KEYS[2])
+	return 0
+end
+
+redis.call('HSET', KEYS[1], 'used', '1')
+redis.call('HSET', KEYS[3], 'family', ARGV[1], 'used', '0')
+redis.call('PEXPIRE', KEYS[3], ARGV[3])
+redis.call('SADD', KEYS[2], ARGV[2])
+redis.call('PEXPIRE', KEYS[2], ARGV[3])
+return 1
+`)
+
+const (
+	tokenKeyPrefix  = "refresh:"
+	familyKeyPrefix = "refresh_family:"
+)
+
+type RefreshRepository interface {
+	StoreToken(ctx context.Context, claim token.CustomClaims) error
+	RotateToken(ctx context.Context, presented token.CustomClaims, successor token.CustomClaims) error
+	RevokeFamily(ctx context.Context, familyID uuid.UUID) error
+}
+
+type IRefreshRepository struct {
+	redis *redis.Client
+}
+
+func NewRefreshRepository(redis *redis.Client) RefreshRepository {
+	return &IRefreshRepository{redis: redis}
+}
+
+func (i *IRefreshRepository) StoreToken(ctx context.Context, claim token.CustomClaims) error {
+	ttl := time.Until(claim.Expiry.Time())
+	tKey := tokenKey(claim.TokenID)
+	fKey := familyKey(claim.FamilyID)
+
+	_, err := i.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, tKey, "family", claim.FamilyID.String(), "used", "0")
+		pipe.PExpire(ctx, tKey, ttl)
+		pipe.SAdd(ctx, fKey, claim.TokenID.String())
+		pipe.PExpire(ctx, fKey, ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+
+	return nil
+}
+
+func (i *IRefreshRepository) RotateToken(
+	ctx context.Context,
+	presented token.CustomClaims,
+	successor token.CustomClaims,
+) error {
+	if presented.FamilyID == uuid.Nil || presented.FamilyID != successor.FamilyID {
+		return ErrTokenRevoked
+	}
+
+	result, err := rotateScript.Run(
+		ctx,
+		i.redis,
+		[]string{
+			tokenKey(presented.TokenID),
+			familyKey(presented.FamilyID),
+			tokenKey(successor.TokenID),
+		},
+		presented.FamilyID.String(),
+		successor.TokenID.String(),
+		time.Until(successor.Expiry.Time()).Milliseconds(),
+		tokenKeyPrefix,
+	).Int()
+	if err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+
+	switch result {
+	case 1:
+		return nil
+	case 0:
+		return ErrTokenReused
+	default:
+		return ErrTokenRevoked
+	}
+}
+
+func (i *IRefreshRepository) RevokeFamily(ctx context.Context, familyID uuid.UUID) error {
+	fKey := familyKey(familyID)
+
+	jtis, err := i.redis.SMembers(ctx, fKey).Result()
+	if err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+
+	keys := []string{fKey}
+	for _, jti := range jtis {
+		keys = append(keys, tokenKeyPrefix+jti)
+	}
+
+	if err := i.redis.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+
+	return nil
+}
+
+func tokenKey(jti uuid.UUID) string {
+	return tokenKeyPrefix + jti.String()
+}
+
+func familyKey(familyID uuid.UUID) string {
+	return familyKeyPrefix + familyID.String()
+}
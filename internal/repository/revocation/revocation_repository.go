@@ -0,0 +1,52 @@
+package revocation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+type RevocationRepository interface {
+	RevokeToken(ctx context.Context, jti uuid.UUID, expireAt time.Time) error
+	IsRevoked(ctx context.Context, jti uuid.UUID) (bool, error)
+}
+
+type IRevocationRepository struct {
+	redis *redis.Client
+}
+
+func NewRevocationRepository(redis *redis.Client) RevocationRepository {
+	return &IRevocationRepository{redis: redis}
+}
+
+// RevokeToken keeps the jti only for as long as the token itself would have
+// been valid; an expired token is rejected by VerifyToken anyway.
+func (i *IRevocationRepository) RevokeToken(ctx context.Context, jti uuid.UUID, expireAt time.Time) error {
+	ttl := time.Until(expireAt)
+	if ttl <= 0 {
+		return nil
+	}
+
+	err := i.redis.Set(ctx, revokedKey(jti), 1, ttl).Err()
+	if err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+
+	return nil
+}
+
+func (i *IRevocationRepository) IsRevoked(ctx context.Context, jti uuid.UUID) (bool, error) {
+	count, err := i.redis.Exists(ctx, revokedKey(jti)).Result()
+	if err != nil {
+		return false, fmt.Errorf("redis error: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+func revokedKey(jti uuid.UUID) string {
+	return fmt.Sprintf("revoked:%s", jti)
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"onboarding/internal/entity"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RoleRepository interface {
+	CreateRole(ctx context.Context, role entity.Role) error
+	GetRole(ctx context.Context, name entity.UserRole) (entity.Role, error)
+	ListRoles(ctx context.Context) ([]entity.Role, error)
+	DeleteRole(ctx context.Context, name entity.UserRole) error
+	CountRoleUsers(ctx context.Context, name entity.UserRole) (int64, error)
+	ListRolePermissions(ctx context.Context, name entity.UserRole) ([]entity.RolePermission, error)
+	AddRolePermission(ctx context.Context, permission entity.RolePermission) error
+	RemoveRolePermission(ctx context.Context, permission entity.RolePermission) error
+}
+
+type IRoleRepository struct {
+	db *gorm.DB
+}
+
+func NewRoleRepository(db *gorm.DB) RoleRepository {
+	return &IRoleRepository{db: db}
+}
+
+func (r *IRoleRepository) CreateRole(ctx context.Context, role entity.Role) error {
+	return r.db.WithContext(ctx).Create(&role).Error
+}
+
+func (r *IRoleRepository) GetRole(ctx context.Context, name entity.UserRole) (entity.Role, error) {
+	var role entity.Role
+	err := r.db.WithContext(ctx).Take(&role, "name = ?", name).Error
+
+	return role, err
+}
+
+func (r *IRoleRepository) ListRoles(ctx context.Context) ([]entity.Role, error) {
+	var roles []entity.Role
+	err := r.db.WithContext(ctx).Order("created_at, name").Find(&roles).Error
+
+	return roles, err
+}
+
+// DeleteRole also deletes the permissions of the role. Built-in roles are
+// left alone and reported as not found.
+func (r *IRoleRepository) DeleteRole(ctx context.Context, name entity.UserRole) error {
+	result := r.db.WithContext(ctx).
+		Where("name = ? AND built_in = false", name).
+		Delete(&entity.Role{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *IRoleRepository) CountRoleUsers(ctx context.Context, name entity.UserRole) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&entity.User{}).
+		Where("role = ?", name).
+		Count(&count).Error
+
+	return count, err
+}
+
+func (r *IRoleRepository) ListRolePermissions(
+	ctx context.Context,
+	name entity.UserRole,
+) ([]entity.RolePermission, error) {
+	var permissions []entity.RolePermission
+	err := r.db.WithContext(ctx).
+		Where("role_name = ?", name).
+		Order("resource, action").
+		Find(&permissions).Error
+
+	return permissions, err
+}
+
+// AddRolePermission does nothing when the role already has the permission.
+func (r *IRoleRepository) AddRolePermission(ctx context.Context, permission entity.RolePermission) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&permission).Error
+}
+
+func (r *IRoleRepository) RemoveRolePermission(ctx context.Context, permission entity.RolePermission) error {
+	result := r.db.WithContext(ctx).
+		Where(
+			"role_name = ? AND resource = ? AND action = ?",
+			permission.RoleName,
+			permission.Resource,
+			permission.Action,
+		).
+		Delete(&entity.RolePermission{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
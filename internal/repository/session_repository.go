@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"onboarding/internal/entity"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session entity.Session) error
+	GetSession(ctx context.Context, uuid uuid.UUID) (entity.Session, error)
+	ListActiveSessions(ctx context.Context, userUUID uuid.UUID) ([]entity.Session, error)
+	TouchSession(ctx context.Context, uuid uuid.UUID, lastSeenAt time.Time) error
+	RevokeSession(ctx context.Context, userUUID uuid.UUID, uuid uuid.UUID) error
+	RevokeSessionsExcept(ctx context.Context, userUUID uuid.UUID, keep uuid.UUID) ([]uuid.UUID, error)
+}
+
+type ISessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &ISessionRepository{db: db}
+}
+
+func (r *ISessionRepository) CreateSession(ctx context.Context, session entity.Session) error {
+	return r.db.WithContext(ctx).Create(&session).Error
+}
+
+func (r *ISessionRepository) GetSession(ctx context.Context, uuid uuid.UUID) (entity.Session, error) {
+	var session entity.Session
+	err := r.db.WithContext(ctx).Take(&session, "uuid = ?", uuid).Error
+
+	return session, err
+}
+
+func (r *ISessionRepository) ListActiveSessions(ctx context.Context, userUUID uuid.UUID) ([]entity.Session, error) {
+	var sessions []entity.Session
+	err := r.db.WithContext(ctx).
+		Where("user_uuid = ? AND revoked_at IS NULL", userUUID).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+
+	return sessions, err
+}
+
+func (r *ISessionRepository) TouchSession(ctx context.Context, uuid uuid.UUID, lastSeenAt time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&entity.Session{}).
+		Where("uuid = ?", uuid).
+		Update("last_seen_at", lastSeenAt).Error
+}
+
+func (r *ISessionRepository) RevokeSession(ctx context.Context, userUUID uuid.UUID, uuid uuid.UUID) error {
+	result := r.db.WithContext(ctx).
+		Model(&entity.Session{}).
+		Where("uuid = ? AND user_uuid = ? AND revoked_at IS NULL", uuid, userUUID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// RevokeSessionsExcept revokes every active session of the user but keep and
+// returns the UUIDs of the sessions it revoked.
+func (r *ISessionRepository) RevokeSessionsExcept(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	keep uuid.UUID,
+) ([]uuid.UUID, error) {
+	var revoked []entity.Session
+	err := r.db.WithContext(ctx).
+		Model(&revoked).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "uuid"}}}).
+		Where("user_uuid = ? AND uuid <> ? AND revoked_at IS NULL", userUUID, keep).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return nil, err
+	}
+
+	uuids := make([]uuid.UUID, 0, len(revoked))
+	for _, session := range revoked {
+		uuids = append(uuids, session.UUID)
+	}
+
+	return uuids, nil
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// Transactor runs work in a database transaction. Repositories pick the
+// transaction up from the context, so a write and the messages it queues in
+// the outbox are committed together or not at all.
+type Transactor interface {
+	// Transaction commits when fn returns nil and rolls back otherwise.
+	// Transactions started inside fn are nested as savepoints.
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type ITransactor struct {
+	db *gorm.DB
+}
+
+func NewTransactor(db *gorm.DB) Transactor {
+	return &ITransactor{db: db}
+}
+
+func (t *ITransactor) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return conn(ctx, t.db).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// conn returns the transaction ctx carries, or else db.
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+
+	return db.WithContext(ctx)
+}
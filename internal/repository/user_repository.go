@@ -3,6 +3,9 @@ package repository
 import (
 	"context"
 	"onboarding/internal/entity"
+	"onboarding/pkg/notify"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -14,6 +17,24 @@ type UserRepository interface {
 	GetUserByUUID(ctx context.Context, uuid uuid.UUID) (entity.User, error)
 	GetUserByEmail(ctx context.Context, email string) (entity.User, error)
 	UpdateUserPassword(ctx context.Context, email, newPassword string) error
+	UpdateUserTOTP(ctx context.Context, uuid uuid.UUID, secret string, enabled bool) error
+	UpdateUserStatus(ctx context.Context, uuid uuid.UUID, from entity.UserStatus, to entity.UserStatus) error
+	UpdateUserEmail(ctx context.Context, uuid uuid.UUID, email string) error
+	UpdateUserRole(ctx context.Context, uuid uuid.UUID, role entity.UserRole) error
+	// GetUserByVerifiedPhone finds the user who verified the phone number.
+	GetUserByVerifiedPhone(ctx context.Context, phone string) (entity.User, error)
+	// UpdateUserPhone sets a new, unverified phone number. A user notified by
+	// SMS falls back to e-mail until it's verified.
+	UpdateUserPhone(ctx context.Context, uuid uuid.UUID, phone string) error
+	// VerifyUserPhone marks the phone number verified, and fails with
+	// gorm.ErrRecordNotFound when the user's number isn't phone (anymore).
+	VerifyUserPhone(ctx context.Context, uuid uuid.UUID, phone string) error
+	UpdateUserNotificationChannel(ctx context.Context, uuid uuid.UUID, channel notify.Channel) error
+	UpdateUserLocale(ctx context.Context, uuid uuid.UUID, locale string) error
+	// ListUsers returns a page of the users matching the filter, newest
+	// first, and how many match in total.
+	ListUsers(ctx context.Context, filter entity.UserFilter) ([]entity.User, int64, error)
+	DeleteUser(ctx context.Context, uuid uuid.UUID) error
 }
 
 type IUserRepository struct {
@@ -25,25 +46,25 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 }
 
 func (r *IUserRepository) CreateUser(ctx context.Context, user entity.User) error {
-	return r.db.WithContext(ctx).Omit("UUID").Create(user).Error
+	return conn(ctx, r.db).Omit("UUID").Create(user).Error
 }
 
 func (r *IUserRepository) GetUserByUUID(ctx context.Context, uuid uuid.UUID) (entity.User, error) {
 	var user entity.User
-	err := r.db.WithContext(ctx).Take(&user, "uuid = ?", uuid).Error
+	err := conn(ctx, r.db).Take(&user, "uuid = ?", uuid).Error
 
 	return user, err
 }
 
 func (r *IUserRepository) GetUserByEmail(ctx context.Context, email string) (entity.User, error) {
 	var user entity.User
-	err := r.db.WithContext(ctx).Order(nil).Take(&user, "email = ?", email).Error
+	err := conn(ctx, r.db).Order(nil).Take(&user, "email = ?", email).Error
 
 	return user, err
 }
 
 func (r *IUserRepository) UpdateUserPassword(ctx context.Context, email, newPassword string) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
 		if err := tx.
 			Clauses(clause.Locking{Strength: "UPDATE"}).
 			Take(&entity.User{}, "email = ?", email).Error; err != nil {
@@ -53,6 +74,173 @@ func (r *IUserRepository) UpdateUserPassword(ctx context.Context, email, newPass
 		return tx.
 			Model(&entity.User{}).
 			Where("email = ?", email).
-			Update("password", newPassword).Error
+			Updates(map[string]any{
+				"password":           newPassword,
+				"tokens_valid_after": time.Now(),
+			}).Error
 	})
 }
+
+func (r *IUserRepository) UpdateUserTOTP(ctx context.Context, uuid uuid.UUID, secret string, enabled bool) error {
+	return conn(ctx, r.db).
+		Model(&entity.User{}).
+		Where("uuid = ?", uuid).
+		Updates(map[string]any{
+			"totp_secret":  secret,
+			"totp_enabled": enabled,
+		}).Error
+}
+
+// UpdateUserStatus only moves the user out of the from status, and fails with
+// gorm.ErrRecordNotFound when the user isn't in it (anymore).
+func (r *IUserRepository) UpdateUserStatus(
+	ctx context.Context,
+	uuid uuid.UUID,
+	from entity.UserStatus,
+	to entity.UserStatus,
+) error {
+	result := conn(ctx, r.db).
+		Model(&entity.User{}).
+		Where("uuid = ? AND status = ?", uuid, from).
+		Update("status", to)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *IUserRepository) UpdateUserEmail(ctx context.Context, uuid uuid.UUID, email string) error {
+	return conn(ctx, r.db).
+		Model(&entity.User{}).
+		Where("uuid = ?", uuid).
+		Update("email", email).Error
+}
+
+func (r *IUserRepository) UpdateUserRole(ctx context.Context, uuid uuid.UUID, role entity.UserRole) error {
+	result := conn(ctx, r.db).
+		Model(&entity.User{}).
+		Where("uuid = ?", uuid).
+		Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *IUserRepository) GetUserByVerifiedPhone(ctx context.Context, phone string) (entity.User, error) {
+	var user entity.User
+	err := conn(ctx, r.db).
+		Order(nil).
+		Take(&user, "phone = ? AND phone_verified_at IS NOT NULL", phone).Error
+
+	return user, err
+}
+
+func (r *IUserRepository) UpdateUserPhone(ctx context.Context, uuid uuid.UUID, phone string) error {
+	return conn(ctx, r.db).
+		Model(&entity.User{}).
+		Where("uuid = ?", uuid).
+		Updates(map[string]any{
+			"phone":             phone,
+			"phone_verified_at": nil,
+			"notification_channel": gorm.Expr(
+				"CASE WHEN notification_channel = ? THEN ? ELSE notification_channel END",
+				notify.ChannelSMS,
+				notify.ChannelEmail,
+			),
+		}).Error
+}
+
+func (r *IUserRepository) VerifyUserPhone(ctx context.Context, uuid uuid.UUID, phone string) error {
+	result := conn(ctx, r.db).
+		Model(&entity.User{}).
+		Where("uuid = ? AND phone = ?", uuid, phone).
+		Update("phone_verified_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *IUserRepository) UpdateUserNotificationChannel(
+	ctx context.Context,
+	uuid uuid.UUID,
+	channel notify.Channel,
+) error {
+	return conn(ctx, r.db).
+		Model(&entity.User{}).
+		Where("uuid = ?", uuid).
+		Update("notification_channel", channel).Error
+}
+
+func (r *IUserRepository) UpdateUserLocale(ctx context.Context, uuid uuid.UUID, locale string) error {
+	return conn(ctx, r.db).
+		Model(&entity.User{}).
+		Where("uuid = ?", uuid).
+		Update("locale", locale).Error
+}
+
+func (r *IUserRepository) ListUsers(ctx context.Context, filter entity.UserFilter) ([]entity.User, int64, error) {
+	query := conn(ctx, r.db).Model(&entity.User{})
+
+	if filter.Email != "" {
+		query = query.Where("email ILIKE ?", "%"+escapeLike(filter.Email)+"%")
+	}
+
+	if filter.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedAfter)
+	}
+
+	if filter.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *filter.CreatedBefore)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []entity.User
+	err := query.
+		Order("created_at DESC, id DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&users).Error
+
+	return users, total, err
+}
+
+// DeleteUser removes the user for good. Sessions, passkeys and recovery codes
+// go with it through the foreign keys.
+func (r *IUserRepository) DeleteUser(ctx context.Context, uuid uuid.UUID) error {
+	result := conn(ctx, r.db).Delete(&entity.User{}, "uuid = ?", uuid)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// escapeLike makes the wildcards of a LIKE pattern match literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
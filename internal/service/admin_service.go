@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"onboarding/common"
+	"onboarding/internal/entity"
+	"onboarding/internal/repository"
+	otp "onboarding/internal/repository/otp"
+	"onboarding/internal/repository/permission"
+
+	"github.com/google/uuid"
+)
+
+// AdminService is what support staff and admins use to look after other
+// users' accounts.
+type AdminService interface {
+	ListUsers(ctx context.Context, filter entity.UserFilter) ([]entity.UserViewModel, int64, error)
+	GetUser(ctx context.Context, userUUID uuid.UUID) (entity.UserViewModel, error)
+	// SuspendUser blocks an active user from logging in and logs out every
+	// session.
+	SuspendUser(ctx context.Context, userUUID uuid.UUID) error
+	UnsuspendUser(ctx context.Context, userUUID uuid.UUID) error
+	// SendPasswordReset e-mails the user a forgot password code, as if they
+	// had asked for it themselves.
+	SendPasswordReset(ctx context.Context, userUUID uuid.UUID) error
+	RevokeSessions(ctx context.Context, userUUID uuid.UUID) error
+	// ClearLockout lets a user locked out by failed logins try again right
+	// away.
+	ClearLockout(ctx context.Context, userUUID uuid.UUID) error
+	DeleteUser(ctx context.Context, userUUID uuid.UUID) error
+}
+
+var (
+	ErrUserNotActive    = errors.New("Only active users can be suspended")
+	ErrUserNotSuspended = errors.New("User is not suspended")
+)
+
+type IAdminService struct {
+	userRepo       repository.UserRepository
+	otpRepo        otp.OtpRepository
+	permissionRepo permission.PermissionRepository
+	sessionService SessionService
+	lockoutService LockoutService
+}
+
+func NewAdminService(
+	userRepo repository.UserRepository,
+	otpRepo otp.OtpRepository,
+	permissionRepo permission.PermissionRepository,
+	sessionService SessionService,
+	lockoutService LockoutService,
+) AdminService {
+	return &IAdminService{
+		userRepo:       userRepo,
+		otpRepo:        otpRepo,
+		permissionRepo: permissionRepo,
+		sessionService: sessionService,
+		lockoutService: lockoutService,
+	}
+}
+
+func (s *IAdminService) ListUsers(
+	ctx context.Context,
+	filter entity.UserFilter,
+) ([]entity.UserViewModel, int64, error) {
+	users, total, err := s.userRepo.ListUsers(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	result := make([]entity.UserViewModel, 0, len(users))
+	for _, user := range users {
+		result = append(result, user.ToViewModel())
+	}
+
+	return result, total, nil
+}
+
+func (s *IAdminService) GetUser(ctx context.Context, userUUID uuid.UUID) (entity.UserViewModel, error) {
+	user, err := s.userRepo.GetUserByUUID(ctx, userUUID)
+	if err != nil {
+		return entity.UserViewModel{}, err
+	}
+
+	return user.ToViewModel(), nil
+}
+
+// SuspendUser logs the user out before suspending them, so a failure can't
+// leave a suspended user signed in. A session started in between can't be
+// refreshed once the user is suspended.
+func (s *IAdminService) SuspendUser(ctx context.Context, userUUID uuid.UUID) error {
+	user, err := s.userRepo.GetUserByUUID(ctx, userUUID)
+	if err != nil {
+		return err
+	}
+
+	if user.Status != entity.UserStatusActive {
+		return ErrUserNotActive
+	}
+
+	if err := s.sessionService.RevokeAllSessions(ctx, userUUID); err != nil {
+		return err
+	}
+
+	return s.updateStatus(ctx, userUUID, entity.UserStatusActive, entity.UserStatusSuspended, ErrUserNotActive)
+}
+
+func (s *IAdminService) UnsuspendUser(ctx context.Context, userUUID uuid.UUID) error {
+	return s.updateStatus(ctx, userUUID, entity.UserStatusSuspended, entity.UserStatusActive, ErrUserNotSuspended)
+}
+
+func (s *IAdminService) SendPasswordReset(ctx context.Context, userUUID uuid.UUID) error {
+	user, err := s.userRepo.GetUserByUUID(ctx, userUUID)
+	if err != nil {
+		return err
+	}
+
+	return s.otpRepo.SendOtp(ctx, otp.NewRecipient(user), "", otp.ServiceForgotPassword)
+}
+
+func (s *IAdminService) RevokeSessions(ctx context.Context, userUUID uuid.UUID) error {
+	if _, err := s.userRepo.GetUserByUUID(ctx, userUUID); err != nil {
+		return err
+	}
+
+	return s.sessionService.RevokeAllSessions(ctx, userUUID)
+}
+
+func (s *IAdminService) ClearLockout(ctx context.Context, userUUID uuid.UUID) error {
+	user, err := s.userRepo.GetUserByUUID(ctx, userUUID)
+	if err != nil {
+		return err
+	}
+
+	return s.lockoutService.Reset(ctx, user.Email)
+}
+
+// DeleteUser logs the user out before deleting, the refresh tokens live in
+// Redis and wouldn't go with the sessions.
+func (s *IAdminService) DeleteUser(ctx context.Context, userUUID uuid.UUID) error {
+	if err := s.RevokeSessions(ctx, userUUID); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.DeleteUser(ctx, userUUID); err != nil {
+		return err
+	}
+
+	return s.permissionRepo.InvalidateUserRole(ctx, userUUID)
+}
+
+// updateStatus moves the user from one status to another, and tells a user
+// that isn't found apart from one in the wrong status.
+func (s *IAdminService) updateStatus(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	from entity.UserStatus,
+	to entity.UserStatus,
+	wrongStatus error,
+) error {
+	err := s.userRepo.UpdateUserStatus(ctx, userUUID, from, to)
+	if !errors.Is(err, common.ErrRecordNotFound) {
+		return err
+	}
+
+	if _, err := s.userRepo.GetUserByUUID(ctx, userUUID); err != nil {
+		return err
+	}
+
+	return wrongStatus
+}
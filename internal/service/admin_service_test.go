@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"onboarding/common"
+	"onboarding/internal/entity"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuspendUser(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	user := env.addUser(t, "user@example.com")
+	pair := env.login(t, "user@example.com")
+
+	require.NoError(t, env.admin.SuspendUser(ctx, user.UUID))
+	require.ErrorIs(t, env.admin.SuspendUser(ctx, user.UUID), ErrUserNotActive)
+
+	_, err := env.auth.VerifyAccessToken(ctx, pair.AccessToken.SignedToken)
+	require.ErrorIs(t, err, ErrSessionRevoked)
+
+	_, err = env.auth.Refresh(ctx, pair.RefreshToken.SignedToken)
+	require.ErrorIs(t, err, ErrSessionRevoked)
+
+	_, err = env.auth.Login(ctx, "user@example.com", testPassword, entity.SessionClient{IP: testIP})
+	require.Equal(t, fmt.Sprint(common.ErrAccountDisabled), common.ErrorCode(err))
+
+	require.NoError(t, env.admin.UnsuspendUser(ctx, user.UUID))
+	require.ErrorIs(t, env.admin.UnsuspendUser(ctx, user.UUID), ErrUserNotSuspended)
+	env.login(t, "user@example.com")
+}
+
+func TestDeleteUser(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	user := env.addUser(t, "user@example.com")
+	pair := env.login(t, "user@example.com")
+
+	require.NoError(t, env.admin.DeleteUser(ctx, user.UUID))
+	require.ErrorIs(t, env.admin.DeleteUser(ctx, user.UUID), common.ErrRecordNotFound)
+
+	_, err := env.auth.Refresh(ctx, pair.RefreshToken.SignedToken)
+	require.ErrorIs(t, err, ErrSessionRevoked)
+}
+
+func TestClearLockout(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	user := env.addUser(t, "user@example.com")
+
+	for range testLockout.MaxAttempts {
+		_, err := env.auth.Login(ctx, "user@example.com", "wrong", entity.SessionClient{IP: testIP})
+		require.Error(t, err)
+	}
+
+	var lockoutErr *LockoutError
+	require.ErrorAs(t, env.lockout.Check(ctx, "user@example.com", testIP), &lockoutErr)
+
+	require.NoError(t, env.admin.ClearLockout(ctx, user.UUID))
+	env.login(t, "user@example.com")
+}
+
+func TestSendPasswordReset(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	user := env.addUser(t, "user@example.com")
+
+	require.NoError(t, env.admin.SendPasswordReset(ctx, user.UUID))
+
+	code := env.lastCode(t, "user@example.com")
+	require.NoError(t, env.otp.VerifyOtpForgotPassword(ctx, "user@example.com", code))
+}
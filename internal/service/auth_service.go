@@ -2,32 +2,111 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"onboarding/common"
 	"onboarding/internal/entity"
 	"onboarding/internal/repository"
+	"onboarding/internal/repository/mfa"
+	"onboarding/internal/repository/refresh"
+	"onboarding/internal/repository/revocation"
 	pw "onboarding/pkg/password"
 	"onboarding/pkg/token"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 type AuthService interface {
-	Register(ctx context.Context, email string, password string) (entity.UserViewModel, error)
-	Login(ctx context.Context, email string, password string) (*token.JWTToken, error)
+	// Register creates a pending user and sends the verification code, both
+	// or neither.
+	Register(ctx context.Context, email string, password string, ip string) (entity.UserViewModel, error)
+	Login(ctx context.Context, email string, password string, client entity.SessionClient) (*LoginResult, error)
+	LoginMFA(
+		ctx context.Context,
+		mfaToken string,
+		code string,
+		recoveryCode string,
+		client entity.SessionClient,
+	) (*token.TokenPair, error)
+	LoginPasskey(ctx context.Context, response []byte, client entity.SessionClient) (*token.TokenPair, error)
+	LoginMagicLink(ctx context.Context, magicLinkToken string, client entity.SessionClient) (*LoginResult, error)
+	Refresh(ctx context.Context, refreshToken string) (*token.TokenPair, error)
+	Logout(ctx context.Context, claim *token.CustomClaims) error
+	VerifyAccessToken(ctx context.Context, accessToken string) (*token.CustomClaims, error)
+	RevokeToken(ctx context.Context, claim token.CustomClaims) error
+}
+
+var (
+	ErrTokenInvalid       = errors.New("Token is invalid")
+	ErrTokenRevoked       = errors.New("Token has been revoked")
+	ErrSessionRevoked     = errors.New("Session has been revoked")
+	ErrCredentialsChanged = errors.New("Credentials have changed, please login again")
+	ErrTooManyMFAAttempts = errors.New("Too many invalid authentication codes, please login again")
+)
+
+// maxMFAAttempts is the number of wrong codes accepted per MFA token before
+// it's revoked.
+const maxMFAAttempts = 5
+
+// LoginResult holds either the token pair of the new session or, when the
+// user has a second factor enabled, the MFA token to finish the login with.
+type LoginResult struct {
+	TokenPair *token.TokenPair
+	MFAToken  *token.JWTToken
 }
 
+// sessionTouchInterval limits how often the last-seen time of a session is
+// written back while it is being used.
+const sessionTouchInterval = time.Minute
+
 type IAuthService struct {
-	userRepo repository.UserRepository
-	jwtImpl  token.JWT
+	userRepo       repository.UserRepository
+	sessionRepo    repository.SessionRepository
+	refreshRepo    refresh.RefreshRepository
+	revocationRepo revocation.RevocationRepository
+	mfaRepo        mfa.MFARepository
+	mfaService     MFAService
+	passkeyService PasskeyService
+	otpService     OtpService
+	lockoutService LockoutService
+	jwtImpl        token.JWT
+	transactor     repository.Transactor
 }
 
-func NewAuthService(userRepo repository.UserRepository, jwtImpl token.JWT) AuthService {
-	return &IAuthService{userRepo: userRepo, jwtImpl: jwtImpl}
+func NewAuthService(
+	userRepo repository.UserRepository,
+	sessionRepo repository.SessionRepository,
+	refreshRepo refresh.RefreshRepository,
+	revocationRepo revocation.RevocationRepository,
+	mfaRepo mfa.MFARepository,
+	mfaService MFAService,
+	passkeyService PasskeyService,
+	otpService OtpService,
+	lockoutService LockoutService,
+	jwtImpl token.JWT,
+	transactor repository.Transactor,
+) AuthService {
+	return &IAuthService{
+		userRepo:       userRepo,
+		sessionRepo:    sessionRepo,
+		refreshRepo:    refreshRepo,
+		revocationRepo: revocationRepo,
+		mfaRepo:        mfaRepo,
+		mfaService:     mfaService,
+		passkeyService: passkeyService,
+		otpService:     otpService,
+		lockoutService: lockoutService,
+		jwtImpl:        jwtImpl,
+		transactor:     transactor,
+	}
 }
 
 func (s *IAuthService) Register(
 	ctx context.Context,
 	email string,
 	password string,
+	ip string,
 ) (entity.UserViewModel, error) {
 	hashedPassword, err := pw.HashPassword(password)
 	if err != nil {
@@ -37,9 +116,17 @@ func (s *IAuthService) Register(
 	arg := entity.User{
 		Email:    email,
 		Password: hashedPassword,
+		Status:   entity.UserStatusPendingVerification,
+		Role:     entity.UserRoleUser,
 	}
 
-	err = s.userRepo.CreateUser(ctx, arg)
+	err = s.transactor.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.CreateUser(ctx, arg); err != nil {
+			return err
+		}
+
+		return s.otpService.SendOtpVerifyEmail(ctx, email, ip)
+	})
 	if err != nil {
 		return entity.UserViewModel{}, err
 	}
@@ -51,20 +138,366 @@ func (s *IAuthService) Login(
 	ctx context.Context,
 	email string,
 	password string,
-) (*token.JWTToken, error) {
+	client entity.SessionClient,
+) (*LoginResult, error) {
+	// Locked logins are turned away before the password is hashed.
+	if err := s.lockoutService.Check(ctx, email, client.IP); err != nil {
+		return nil, err
+	}
+
 	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if errors.Is(err, common.ErrRecordNotFound) {
+		return nil, s.loginFailed(ctx, email, client, err)
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	if err := pw.CheckPassword(password, user.Password); err != nil {
-		return nil, fmt.Errorf("%d", common.ErrCredentiials)
+		return nil, s.loginFailed(ctx, email, client, fmt.Errorf("%d", common.ErrCredentiials))
+	}
+
+	if err := s.lockoutService.Reset(ctx, email); err != nil {
+		return nil, err
+	}
+
+	return s.firstFactorPassed(ctx, user, client)
+}
+
+// loginFailed counts the failure and returns err, or the lockout it caused.
+func (s *IAuthService) loginFailed(
+	ctx context.Context,
+	email string,
+	client entity.SessionClient,
+	err error,
+) error {
+	if lockoutErr := s.lockoutService.RecordFailure(ctx, email, client.IP); lockoutErr != nil {
+		return lockoutErr
+	}
+
+	return err
+}
+
+// LoginMagicLink logs in with a link sent by SendMagicLink. Owning the
+// mailbox stands in for the password, a second factor is still asked for.
+func (s *IAuthService) LoginMagicLink(
+	ctx context.Context,
+	magicLinkToken string,
+	client entity.SessionClient,
+) (*LoginResult, error) {
+	email, err := s.otpService.ConsumeMagicLink(ctx, magicLinkToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.firstFactorPassed(ctx, user, client)
+}
+
+// firstFactorPassed starts the session of a user who proved the first
+// factor, or hands out an MFA token when a second one is enabled.
+func (s *IAuthService) firstFactorPassed(
+	ctx context.Context,
+	user entity.User,
+	client entity.SessionClient,
+) (*LoginResult, error) {
+	if err := checkUserStatus(user); err != nil {
+		return nil, err
+	}
+
+	if user.TOTPEnabled {
+		mfaToken, err := s.jwtImpl.CreateMFAToken(user.UUID)
+		if err != nil {
+			return nil, err
+		}
+
+		return &LoginResult{MFAToken: mfaToken}, nil
+	}
+
+	tokenPair, err := s.startSession(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResult{TokenPair: tokenPair}, nil
+}
+
+// LoginMFA finishes a login started by Login with a TOTP code or, when the
+// authenticator is lost, a recovery code. The MFA token can only be exchanged
+// once and is revoked after too many wrong codes.
+func (s *IAuthService) LoginMFA(
+	ctx context.Context,
+	mfaToken string,
+	code string,
+	recoveryCode string,
+	client entity.SessionClient,
+) (*token.TokenPair, error) {
+	claim, err := s.jwtImpl.VerifyToken(mfaToken, token.MFATokenExpectation())
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.revocationRepo.IsRevoked(ctx, claim.TokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	user, err := s.userRepo.GetUserByUUID(ctx, claim.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkUserStatus(user); err != nil {
+		return nil, err
+	}
+
+	if err := s.mfaService.VerifySecondFactor(ctx, user, code, recoveryCode); err != nil {
+		if !errors.Is(err, ErrInvalidMFACode) {
+			return nil, err
+		}
+
+		attempts, countErr := s.mfaRepo.CountFailedAttempt(ctx, claim.TokenID, time.Until(claim.Expiry.Time()))
+		if countErr != nil {
+			return nil, countErr
+		}
+
+		if attempts >= maxMFAAttempts {
+			if err := s.RevokeToken(ctx, *claim); err != nil {
+				return nil, err
+			}
+			return nil, ErrTooManyMFAAttempts
+		}
+
+		return nil, err
+	}
+
+	if err := s.RevokeToken(ctx, *claim); err != nil {
+		return nil, err
+	}
+
+	return s.startSession(ctx, user, client)
+}
+
+// LoginPasskey starts a session from a passkey assertion. The passkey has
+// been verified by the user on the authenticator, so no second factor is
+// asked for.
+func (s *IAuthService) LoginPasskey(
+	ctx context.Context,
+	response []byte,
+	client entity.SessionClient,
+) (*token.TokenPair, error) {
+	user, err := s.passkeyService.VerifyLogin(ctx, response)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkUserStatus(user); err != nil {
+		return nil, err
+	}
+
+	return s.startSession(ctx, user, client)
+}
+
+func (s *IAuthService) Refresh(ctx context.Context, refreshToken string) (*token.TokenPair, error) {
+	claim, err := s.jwtImpl.VerifyToken(refreshToken, token.RefreshTokenExpectation())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTokenInvalid, err)
+	}
+
+	session, err := s.sessionRepo.GetSession(ctx, claim.FamilyID)
+	if err != nil {
+		if errors.Is(err, common.ErrRecordNotFound) {
+			return nil, ErrSessionRevoked
+		}
+		return nil, err
+	}
+
+	if session.RevokedAt != nil || session.UserUUID != claim.UserID {
+		return nil, ErrSessionRevoked
+	}
+
+	user, err := s.userRepo.GetUserByUUID(ctx, claim.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkUserStatus(user); err != nil {
+		return nil, err
+	}
+
+	if err := checkTokensValidAfter(user, claim); err != nil {
+		return nil, err
+	}
+
+	// The role is read again on every refresh, so a changed role is in
+	// effect once the current access token has expired.
+	tokenPair, err := s.createTokenPair(user, session.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.refreshRepo.RotateToken(ctx, *claim, tokenPair.RefreshToken.Claims)
+	if errors.Is(err, refresh.ErrTokenReused) {
+		// Whoever used the token first may hold the session's access tokens,
+		// so the session ends with its refresh tokens.
+		err := s.sessionRepo.RevokeSession(ctx, session.UserUUID, session.UUID)
+		if err != nil && !errors.Is(err, common.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, refresh.ErrTokenReused
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return tokenPair, nil
+}
+
+func (s *IAuthService) Logout(ctx context.Context, claim *token.CustomClaims) error {
+	if err := s.RevokeToken(ctx, *claim); err != nil {
+		return err
+	}
+
+	err := s.sessionRepo.RevokeSession(ctx, claim.UserID, claim.SessionID)
+	if err != nil && !errors.Is(err, common.ErrRecordNotFound) {
+		return err
+	}
+
+	return s.refreshRepo.RevokeFamily(ctx, claim.SessionID)
+}
+
+func (s *IAuthService) VerifyAccessToken(ctx context.Context, accessToken string) (*token.CustomClaims, error) {
+	claim, err := s.jwtImpl.VerifyToken(accessToken, token.AccessTokenExpectation())
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.revocationRepo.IsRevoked(ctx, claim.TokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	session, err := s.sessionRepo.GetSession(ctx, claim.SessionID)
+	if err != nil {
+		if errors.Is(err, common.ErrRecordNotFound) {
+			return nil, ErrSessionRevoked
+		}
+		return nil, err
+	}
+
+	if session.RevokedAt != nil || session.UserUUID != claim.UserID {
+		return nil, ErrSessionRevoked
+	}
+
+	user, err := s.userRepo.GetUserByUUID(ctx, claim.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkTokensValidAfter(user, claim); err != nil {
+		return nil, err
+	}
+
+	if now := time.Now(); now.Sub(session.LastSeenAt) > sessionTouchInterval {
+		if err := s.sessionRepo.TouchSession(ctx, session.UUID, now); err != nil {
+			return nil, err
+		}
+	}
+
+	return claim, nil
+}
+
+func (s *IAuthService) RevokeToken(ctx context.Context, claim token.CustomClaims) error {
+	return s.revocationRepo.RevokeToken(ctx, claim.TokenID, claim.Expiry.Time())
+}
+
+// checkUserStatus only lets active accounts log in. The error codes tell an
+// unverified e-mail address apart from a disabled account.
+func checkUserStatus(user entity.User) error {
+	switch user.Status {
+	case entity.UserStatusActive:
+		return nil
+	case entity.UserStatusPendingVerification:
+		return fmt.Errorf("%d", common.ErrAccountNotVerified)
+	default:
+		return fmt.Errorf("%d", common.ErrAccountDisabled)
+	}
+}
+
+// checkTokensValidAfter rejects tokens issued before the user's credentials
+// last changed. IssuedAt only has second precision, so the comparison is made
+// at that precision too.
+func checkTokensValidAfter(user entity.User, claim *token.CustomClaims) error {
+	if user.TokensValidAfter == nil || claim.IssuedAt == nil {
+		return nil
+	}
+
+	if claim.IssuedAt.Time().Before(user.TokensValidAfter.Truncate(time.Second)) {
+		return ErrCredentialsChanged
+	}
+
+	return nil
+}
+
+// startSession records a new session for the user and issues its first token
+// pair. The session UUID doubles as the refresh token family, so revoking a
+// session also revokes every refresh token issued for it.
+func (s *IAuthService) startSession(
+	ctx context.Context,
+	user entity.User,
+	client entity.SessionClient,
+) (*token.TokenPair, error) {
+	now := time.Now()
+	session := entity.Session{
+		UUID:       uuid.New(),
+		UserUUID:   user.UUID,
+		UserAgent:  client.UserAgent,
+		IP:         client.IP,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}
+
+	if err := s.sessionRepo.CreateSession(ctx, session); err != nil {
+		return nil, err
+	}
+
+	tokenPair, err := s.createTokenPair(user, session.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.refreshRepo.StoreToken(ctx, tokenPair.RefreshToken.Claims); err != nil {
+		return nil, err
+	}
+
+	return tokenPair, nil
+}
+
+func (s *IAuthService) createTokenPair(user entity.User, sessionID uuid.UUID) (*token.TokenPair, error) {
+	accessToken, err := s.jwtImpl.CreateAccessToken(user.UUID, sessionID, token.WithRole(string(user.Role)))
+	if err != nil {
+		return nil, err
 	}
 
-	jwtToken, err := s.jwtImpl.CreateAccessToken(user.UUID)
+	refreshToken, err := s.jwtImpl.CreateRefreshToken(user.UUID, sessionID)
 	if err != nil {
 		return nil, err
 	}
 
-	return jwtToken, nil
+	return &token.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
 }
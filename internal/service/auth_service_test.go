@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"onboarding/common"
+	"onboarding/internal/entity"
+	"onboarding/internal/repository/otp"
+	"onboarding/internal/repository/refresh"
+	"onboarding/pkg/token"
+	"onboarding/pkg/totp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshRotatesTokens(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	env.addUser(t, "user@example.com")
+	first := env.login(t, "user@example.com")
+
+	second, err := env.auth.Refresh(ctx, first.RefreshToken.SignedToken)
+	require.NoError(t, err)
+	require.Equal(t, first.RefreshToken.Claims.FamilyID, second.RefreshToken.Claims.FamilyID)
+
+	claim, err := env.auth.VerifyAccessToken(ctx, second.AccessToken.SignedToken)
+	require.NoError(t, err)
+	require.Equal(t, first.RefreshToken.Claims.FamilyID, claim.SessionID)
+
+	_, err = env.auth.Refresh(ctx, second.AccessToken.SignedToken)
+	require.ErrorIs(t, err, ErrTokenInvalid)
+}
+
+func TestRefreshReuseRevokesSession(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	env.addUser(t, "user@example.com")
+	first := env.login(t, "user@example.com")
+
+	second, err := env.auth.Refresh(ctx, first.RefreshToken.SignedToken)
+	require.NoError(t, err)
+
+	// The first token shows up again, so one of the two was stolen.
+	_, err = env.auth.Refresh(ctx, first.RefreshToken.SignedToken)
+	require.ErrorIs(t, err, refresh.ErrTokenReused)
+
+	session, err := env.sessions.GetSession(ctx, second.RefreshToken.Claims.FamilyID)
+	require.NoError(t, err)
+	require.NotNil(t, session.RevokedAt)
+
+	_, err = env.auth.VerifyAccessToken(ctx, second.AccessToken.SignedToken)
+	require.ErrorIs(t, err, ErrSessionRevoked)
+
+	_, err = env.auth.Refresh(ctx, second.RefreshToken.SignedToken)
+	require.ErrorIs(t, err, ErrSessionRevoked)
+}
+
+func TestRefreshRejectsSuspendedUser(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	user := env.addUser(t, "user@example.com")
+	pair := env.login(t, "user@example.com")
+
+	require.NoError(t, env.users.UpdateUserStatus(ctx, user.UUID, entity.UserStatusActive, entity.UserStatusSuspended))
+
+	_, err := env.auth.Refresh(ctx, pair.RefreshToken.SignedToken)
+	require.Equal(t, fmt.Sprint(common.ErrAccountDisabled), common.ErrorCode(err))
+}
+
+func TestLogoutRevokesTokens(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	env.addUser(t, "user@example.com")
+	pair := env.login(t, "user@example.com")
+
+	claim, err := env.auth.VerifyAccessToken(ctx, pair.AccessToken.SignedToken)
+	require.NoError(t, err)
+	require.NoError(t, env.auth.Logout(ctx, claim))
+
+	_, err = env.auth.VerifyAccessToken(ctx, pair.AccessToken.SignedToken)
+	require.ErrorIs(t, err, ErrTokenRevoked)
+
+	_, err = env.auth.Refresh(ctx, pair.RefreshToken.SignedToken)
+	require.ErrorIs(t, err, ErrSessionRevoked)
+}
+
+func TestLoginLocksOutAfterMaxAttempts(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	env.addUser(t, "user@example.com")
+	client := entity.SessionClient{IP: testIP}
+
+	for range testLockout.MaxAttempts - 1 {
+		_, err := env.auth.Login(ctx, "user@example.com", "wrong", client)
+		require.Equal(t, fmt.Sprint(common.ErrCredentiials), common.ErrorCode(err))
+	}
+
+	_, err := env.auth.Login(ctx, "user@example.com", "wrong", client)
+	var lockoutErr *LockoutError
+	require.ErrorAs(t, err, &lockoutErr)
+	require.Equal(t, testLockout.Duration, lockoutErr.RetryAfter)
+	require.Len(t, env.sender.sentTo("user@example.com"), 1)
+
+	// Not even the right password gets in while the account is locked.
+	_, err = env.auth.Login(ctx, "user@example.com", testPassword, client)
+	require.ErrorAs(t, err, &lockoutErr)
+
+	env.server.FastForward(testLockout.Duration)
+	env.login(t, "user@example.com")
+}
+
+// addTOTPUser stores an active user with TOTP enabled and returns the secret.
+func (env *testEnv) addTOTPUser(t *testing.T, email string) (entity.User, string) {
+	user := env.addUser(t, email)
+
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	require.NoError(t, env.users.UpdateUserTOTP(context.Background(), user.UUID, secret, true))
+
+	user, err = env.users.GetUserByUUID(context.Background(), user.UUID)
+	require.NoError(t, err)
+
+	return user, secret
+}
+
+func TestLoginMFA(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	_, secret := env.addTOTPUser(t, "user@example.com")
+	client := entity.SessionClient{IP: testIP}
+
+	result, err := env.auth.Login(ctx, "user@example.com", testPassword, client)
+	require.NoError(t, err)
+	require.Nil(t, result.TokenPair)
+	require.NotNil(t, result.MFAToken)
+
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+
+	pair, err := env.auth.LoginMFA(ctx, result.MFAToken.SignedToken, code, "", client)
+	require.NoError(t, err)
+
+	_, err = env.auth.VerifyAccessToken(ctx, pair.AccessToken.SignedToken)
+	require.NoError(t, err)
+
+	// The MFA token is spent.
+	_, err = env.auth.LoginMFA(ctx, result.MFAToken.SignedToken, code, "", client)
+	require.ErrorIs(t, err, ErrTokenRevoked)
+}
+
+func TestLoginMFARevokesTokenAfterMaxAttempts(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	env.addTOTPUser(t, "user@example.com")
+	client := entity.SessionClient{IP: testIP}
+
+	result, err := env.auth.Login(ctx, "user@example.com", testPassword, client)
+	require.NoError(t, err)
+
+	for range maxMFAAttempts - 1 {
+		_, err := env.auth.LoginMFA(ctx, result.MFAToken.SignedToken, "", "wrong-code", client)
+		require.ErrorIs(t, err, ErrInvalidMFACode)
+	}
+
+	_, err = env.auth.LoginMFA(ctx, result.MFAToken.SignedToken, "", "wrong-code", client)
+	require.ErrorIs(t, err, ErrTooManyMFAAttempts)
+
+	_, err = env.auth.LoginMFA(ctx, result.MFAToken.SignedToken, "", "wrong-code", client)
+	require.ErrorIs(t, err, ErrTokenRevoked)
+}
+
+func TestLoginMagicLink(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	env.addUser(t, "user@example.com")
+	client := entity.SessionClient{IP: testIP}
+
+	require.NoError(t, env.otp.SendMagicLink(ctx, "user@example.com", testIP))
+	magicLinkToken := env.lastMagicLinkToken(t, "user@example.com")
+
+	result, err := env.auth.LoginMagicLink(ctx, magicLinkToken, client)
+	require.NoError(t, err)
+	require.NotNil(t, result.TokenPair)
+
+	_, err = env.auth.LoginMagicLink(ctx, magicLinkToken, client)
+	require.ErrorIs(t, err, otp.ErrMagicLinkInvalid)
+}
+
+func TestLoginMagicLinkAsksForSecondFactor(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	env.addTOTPUser(t, "user@example.com")
+
+	require.NoError(t, env.otp.SendMagicLink(ctx, "user@example.com", testIP))
+
+	result, err := env.auth.LoginMagicLink(ctx, env.lastMagicLinkToken(t, "user@example.com"), entity.SessionClient{})
+	require.NoError(t, err)
+	require.Nil(t, result.TokenPair)
+	require.NotNil(t, result.MFAToken)
+
+	claim, err := env.jwt.VerifyToken(result.MFAToken.SignedToken, token.MFATokenExpectation())
+	require.NoError(t, err)
+	require.Equal(t, token.ScopeMFAPending, claim.Scope)
+}
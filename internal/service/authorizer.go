@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"onboarding/internal/entity"
+	"onboarding/internal/repository"
+	"onboarding/internal/repository/permission"
+	"onboarding/pkg/authz"
+
+	"github.com/google/uuid"
+)
+
+// Authorizer decides whether a user is granted a permission. The role is
+// looked up rather than taken from the token, so a new role assignment is in
+// effect right away.
+type Authorizer interface {
+	Authorize(ctx context.Context, userUUID uuid.UUID, permission authz.Permission) (bool, error)
+}
+
+type IAuthorizer struct {
+	userRepo       repository.UserRepository
+	roleRepo       repository.RoleRepository
+	permissionRepo permission.PermissionRepository
+}
+
+func NewAuthorizer(
+	userRepo repository.UserRepository,
+	roleRepo repository.RoleRepository,
+	permissionRepo permission.PermissionRepository,
+) Authorizer {
+	return &IAuthorizer{
+		userRepo:       userRepo,
+		roleRepo:       roleRepo,
+		permissionRepo: permissionRepo,
+	}
+}
+
+func (a *IAuthorizer) Authorize(ctx context.Context, userUUID uuid.UUID, requested authz.Permission) (bool, error) {
+	role, err := a.userRole(ctx, userUUID)
+	if err != nil {
+		return false, err
+	}
+
+	granted, err := a.rolePermissions(ctx, role)
+	if err != nil {
+		return false, err
+	}
+
+	return authz.Allowed(granted, requested), nil
+}
+
+func (a *IAuthorizer) userRole(ctx context.Context, userUUID uuid.UUID) (string, error) {
+	role, ok, err := a.permissionRepo.GetUserRole(ctx, userUUID)
+	if err != nil || ok {
+		return role, err
+	}
+
+	user, err := a.userRepo.GetUserByUUID(ctx, userUUID)
+	if err != nil {
+		return "", err
+	}
+
+	role = string(user.Role)
+	if err := a.permissionRepo.SetUserRole(ctx, userUUID, role); err != nil {
+		return "", err
+	}
+
+	return role, nil
+}
+
+func (a *IAuthorizer) rolePermissions(ctx context.Context, role string) ([]authz.Permission, error) {
+	cached, ok, err := a.permissionRepo.GetRolePermissions(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		stored, err := a.roleRepo.ListRolePermissions(ctx, entity.UserRole(role))
+		if err != nil {
+			return nil, err
+		}
+
+		cached = make([]string, 0, len(stored))
+		for _, p := range stored {
+			cached = append(cached, p.Permission().String())
+		}
+
+		if err := a.permissionRepo.SetRolePermissions(ctx, role, cached); err != nil {
+			return nil, err
+		}
+	}
+
+	granted := make([]authz.Permission, 0, len(cached))
+	for _, s := range cached {
+		p, err := authz.ParsePermission(s)
+		if err != nil {
+			return nil, err
+		}
+		granted = append(granted, p)
+	}
+
+	return granted, nil
+}
@@ -0,0 +1,338 @@
+package service
+
+import (
+	"context"
+	"onboarding/common"
+	"onboarding/internal/entity"
+	"onboarding/internal/repository"
+	"onboarding/pkg/notify"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// fakeUserRepository keeps users in memory. Methods the tests don't reach
+// panic through the nil interface.
+type fakeUserRepository struct {
+	repository.UserRepository
+	users map[uuid.UUID]entity.User
+}
+
+func newFakeUserRepository() *fakeUserRepository {
+	return &fakeUserRepository{users: map[uuid.UUID]entity.User{}}
+}
+
+// add stores the user as it is and returns it.
+func (r *fakeUserRepository) add(user entity.User) entity.User {
+	if user.UUID == uuid.Nil {
+		user.UUID = uuid.New()
+	}
+	r.users[user.UUID] = user
+
+	return user
+}
+
+func (r *fakeUserRepository) CreateUser(ctx context.Context, user entity.User) error {
+	user.UUID = uuid.Nil
+	user.CreatedAt = time.Now()
+	r.add(user)
+
+	return nil
+}
+
+func (r *fakeUserRepository) GetUserByUUID(ctx context.Context, id uuid.UUID) (entity.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return entity.User{}, common.ErrRecordNotFound
+	}
+
+	return user, nil
+}
+
+func (r *fakeUserRepository) GetUserByEmail(ctx context.Context, email string) (entity.User, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+
+	return entity.User{}, common.ErrRecordNotFound
+}
+
+func (r *fakeUserRepository) GetUserByVerifiedPhone(ctx context.Context, phone string) (entity.User, error) {
+	for _, user := range r.users {
+		if user.VerifiedPhone() == phone {
+			return user, nil
+		}
+	}
+
+	return entity.User{}, common.ErrRecordNotFound
+}
+
+// update applies change to the user, like an UPDATE matching no row when the
+// user doesn't exist.
+func (r *fakeUserRepository) update(id uuid.UUID, change func(user *entity.User)) error {
+	user, ok := r.users[id]
+	if !ok {
+		return common.ErrRecordNotFound
+	}
+
+	change(&user)
+	r.users[id] = user
+
+	return nil
+}
+
+func (r *fakeUserRepository) UpdateUserPassword(ctx context.Context, email, newPassword string) error {
+	user, err := r.GetUserByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	return r.update(user.UUID, func(user *entity.User) {
+		now := time.Now()
+		user.Password = newPassword
+		user.TokensValidAfter = &now
+	})
+}
+
+func (r *fakeUserRepository) UpdateUserTOTP(ctx context.Context, id uuid.UUID, secret string, enabled bool) error {
+	return r.update(id, func(user *entity.User) {
+		user.TOTPSecret = secret
+		user.TOTPEnabled = enabled
+	})
+}
+
+func (r *fakeUserRepository) UpdateUserStatus(
+	ctx context.Context,
+	id uuid.UUID,
+	from entity.UserStatus,
+	to entity.UserStatus,
+) error {
+	if user, ok := r.users[id]; !ok || user.Status != from {
+		return common.ErrRecordNotFound
+	}
+
+	return r.update(id, func(user *entity.User) { user.Status = to })
+}
+
+func (r *fakeUserRepository) UpdateUserEmail(ctx context.Context, id uuid.UUID, email string) error {
+	return r.update(id, func(user *entity.User) { user.Email = email })
+}
+
+func (r *fakeUserRepository) UpdateUserPhone(ctx context.Context, id uuid.UUID, phone string) error {
+	return r.update(id, func(user *entity.User) {
+		user.Phone = phone
+		user.PhoneVerifiedAt = nil
+	})
+}
+
+func (r *fakeUserRepository) VerifyUserPhone(ctx context.Context, id uuid.UUID, phone string) error {
+	if user, ok := r.users[id]; !ok || user.Phone != phone {
+		return common.ErrRecordNotFound
+	}
+
+	return r.update(id, func(user *entity.User) {
+		now := time.Now()
+		user.PhoneVerifiedAt = &now
+	})
+}
+
+func (r *fakeUserRepository) UpdateUserNotificationChannel(
+	ctx context.Context,
+	id uuid.UUID,
+	channel notify.Channel,
+) error {
+	return r.update(id, func(user *entity.User) { user.NotificationChannel = channel })
+}
+
+func (r *fakeUserRepository) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	if _, ok := r.users[id]; !ok {
+		return common.ErrRecordNotFound
+	}
+
+	delete(r.users, id)
+	return nil
+}
+
+type fakeSessionRepository struct {
+	sessions map[uuid.UUID]entity.Session
+}
+
+func newFakeSessionRepository() *fakeSessionRepository {
+	return &fakeSessionRepository{sessions: map[uuid.UUID]entity.Session{}}
+}
+
+func (r *fakeSessionRepository) CreateSession(ctx context.Context, session entity.Session) error {
+	r.sessions[session.UUID] = session
+	return nil
+}
+
+func (r *fakeSessionRepository) GetSession(ctx context.Context, id uuid.UUID) (entity.Session, error) {
+	session, ok := r.sessions[id]
+	if !ok {
+		return entity.Session{}, common.ErrRecordNotFound
+	}
+
+	return session, nil
+}
+
+func (r *fakeSessionRepository) ListActiveSessions(ctx context.Context, userUUID uuid.UUID) ([]entity.Session, error) {
+	var sessions []entity.Session
+	for _, session := range r.sessions {
+		if session.UserUUID == userUUID && session.RevokedAt == nil {
+			sessions = append(sessions, session)
+		}
+	}
+
+	return sessions, nil
+}
+
+func (r *fakeSessionRepository) TouchSession(ctx context.Context, id uuid.UUID, lastSeenAt time.Time) error {
+	session, ok := r.sessions[id]
+	if !ok {
+		return common.ErrRecordNotFound
+	}
+
+	session.LastSeenAt = lastSeenAt
+	r.sessions[id] = session
+	return nil
+}
+
+func (r *fakeSessionRepository) RevokeSession(ctx context.Context, userUUID uuid.UUID, id uuid.UUID) error {
+	session, ok := r.sessions[id]
+	if !ok || session.UserUUID != userUUID || session.RevokedAt != nil {
+		return common.ErrRecordNotFound
+	}
+
+	now := time.Now()
+	session.RevokedAt = &now
+	r.sessions[id] = session
+	return nil
+}
+
+func (r *fakeSessionRepository) RevokeSessionsExcept(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	keep uuid.UUID,
+) ([]uuid.UUID, error) {
+	var revoked []uuid.UUID
+	for id, session := range r.sessions {
+		if session.UserUUID != userUUID || id == keep || session.RevokedAt != nil {
+			continue
+		}
+
+		now := time.Now()
+		session.RevokedAt = &now
+		r.sessions[id] = session
+		revoked = append(revoked, id)
+	}
+
+	return revoked, nil
+}
+
+type fakeRecoveryCodeRepository struct {
+	codes  []entity.MFARecoveryCode
+	nextID int64
+}
+
+func (r *fakeRecoveryCodeRepository) ReplaceRecoveryCodes(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	codes []entity.MFARecoveryCode,
+) error {
+	r.codes = slices.DeleteFunc(r.codes, func(code entity.MFARecoveryCode) bool {
+		return code.UserUUID == userUUID
+	})
+
+	for _, code := range codes {
+		r.nextID++
+		code.ID = r.nextID
+		code.UserUUID = userUUID
+		r.codes = append(r.codes, code)
+	}
+
+	return nil
+}
+
+func (r *fakeRecoveryCodeRepository) FindUnusedRecoveryCodes(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	lookup string,
+) ([]entity.MFARecoveryCode, error) {
+	var codes []entity.MFARecoveryCode
+	for _, code := range r.codes {
+		if code.UserUUID == userUUID && code.UsedAt == nil && (code.Lookup == lookup || code.Lookup == "") {
+			codes = append(codes, code)
+		}
+	}
+
+	return codes, nil
+}
+
+func (r *fakeRecoveryCodeRepository) CountUnusedRecoveryCodes(ctx context.Context, userUUID uuid.UUID) (int64, error) {
+	var count int64
+	for _, code := range r.codes {
+		if code.UserUUID == userUUID && code.UsedAt == nil {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (r *fakeRecoveryCodeRepository) MarkRecoveryCodeUsed(ctx context.Context, id int64) error {
+	for i, code := range r.codes {
+		if code.ID == id && code.UsedAt == nil {
+			now := time.Now()
+			r.codes[i].UsedAt = &now
+			return nil
+		}
+	}
+
+	return common.ErrRecordNotFound
+}
+
+func (r *fakeRecoveryCodeRepository) DeleteRecoveryCodes(ctx context.Context, userUUID uuid.UUID) error {
+	r.codes = slices.DeleteFunc(r.codes, func(code entity.MFARecoveryCode) bool {
+		return code.UserUUID == userUUID
+	})
+
+	return nil
+}
+
+// fakeTransactor runs the work without a transaction, so it doesn't roll
+// anything back.
+type fakeTransactor struct{}
+
+func (fakeTransactor) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// fakeSender keeps the messages instead of sending them. It supports every
+// channel.
+type fakeSender struct {
+	messages []notify.Message
+}
+
+func (s *fakeSender) Notify(ctx context.Context, msg notify.Message) error {
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+func (s *fakeSender) Supports(channel notify.Channel) bool {
+	return true
+}
+
+// sentTo returns the messages sent to the address, oldest first.
+func (s *fakeSender) sentTo(to string) []notify.Message {
+	var messages []notify.Message
+	for _, msg := range s.messages {
+		if msg.To == to {
+			messages = append(messages, msg)
+		}
+	}
+
+	return messages
+}
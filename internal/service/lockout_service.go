@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"onboarding/common"
+	"onboarding/internal/repository"
+	"onboarding/internal/repository/lockout"
+	otp "onboarding/internal/repository/otp"
+	"onboarding/pkg/config"
+	"time"
+)
+
+// LockoutService slows down password guessing. Failed logins are counted per
+// account and per IP address; past a few of them the account has to wait a
+// growing delay between attempts, and past the limit it is locked.
+type LockoutService interface {
+	// Check returns a *LockoutError when the account or the IP address may
+	// not try to log in right now.
+	Check(ctx context.Context, email string, ip string) error
+	// RecordFailure counts a failed login and returns a *LockoutError when
+	// it locks the account or the IP address.
+	RecordFailure(ctx context.Context, email string, ip string) error
+	// Reset forgets the failures and the lock of an account, after a
+	// successful login, a password reset or when an admin clears it.
+	Reset(ctx context.Context, email string) error
+}
+
+// LockoutError is returned while logins are locked. Its message is the
+// common.ErrAccountLocked code, like the other login errors.
+type LockoutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("%d", common.ErrAccountLocked)
+}
+
+type ILockoutService struct {
+	userRepo    repository.UserRepository
+	otpRepo     otp.OtpRepository
+	lockoutRepo lockout.LockoutRepository
+	cfg         config.Lockout
+}
+
+func NewLockoutService(
+	userRepo repository.UserRepository,
+	otpRepo otp.OtpRepository,
+	lockoutRepo lockout.LockoutRepository,
+	cfg config.Lockout,
+) LockoutService {
+	return &ILockoutService{
+		userRepo:    userRepo,
+		otpRepo:     otpRepo,
+		lockoutRepo: lockoutRepo,
+		cfg:         cfg,
+	}
+}
+
+func (s *ILockoutService) Check(ctx context.Context, email string, ip string) error {
+	for _, subject := range []lockout.Subject{lockout.AccountSubject(email), lockout.IPSubject(ip)} {
+		ttl, err := s.lockoutRepo.LockedFor(ctx, subject)
+		if err != nil {
+			return err
+		}
+
+		if ttl > 0 {
+			return &LockoutError{RetryAfter: ttl}
+		}
+	}
+
+	return nil
+}
+
+func (s *ILockoutService) RecordFailure(ctx context.Context, email string, ip string) error {
+	ipSubject := lockout.IPSubject(ip)
+	ipFailures, err := s.lockoutRepo.CountFailure(ctx, ipSubject, s.cfg.Window)
+	if err != nil {
+		return err
+	}
+
+	if ipFailures >= s.cfg.IPMaxAttempts {
+		if err := s.lockoutRepo.Lock(ctx, ipSubject, s.cfg.Duration); err != nil {
+			return err
+		}
+	}
+
+	accountSubject := lockout.AccountSubject(email)
+	failures, err := s.lockoutRepo.CountFailure(ctx, accountSubject, s.cfg.Window)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case failures == s.cfg.MaxAttempts:
+		if err := s.lockoutRepo.Lock(ctx, accountSubject, s.cfg.Duration); err != nil {
+			return err
+		}
+
+		s.notifyLocked(ctx, email)
+		return &LockoutError{RetryAfter: s.cfg.Duration}
+	case failures > s.cfg.MaxAttempts:
+		// Attempts that raced the lock don't extend it or send another
+		// e-mail. The lock may have run out while the failures are still
+		// counted though, then the account is locked again, quietly.
+		ttl, err := s.lockoutRepo.LockedFor(ctx, accountSubject)
+		if err != nil {
+			return err
+		}
+
+		if ttl > 0 {
+			return &LockoutError{RetryAfter: ttl}
+		}
+
+		if err := s.lockoutRepo.Lock(ctx, accountSubject, s.cfg.Duration); err != nil {
+			return err
+		}
+
+		return &LockoutError{RetryAfter: s.cfg.Duration}
+	case failures > s.cfg.DelayAfter:
+		delay := s.delay(failures - s.cfg.DelayAfter)
+		if err := s.lockoutRepo.Lock(ctx, accountSubject, delay); err != nil {
+			return err
+		}
+	}
+
+	if ipFailures >= s.cfg.IPMaxAttempts {
+		return &LockoutError{RetryAfter: s.cfg.Duration}
+	}
+
+	return nil
+}
+
+func (s *ILockoutService) Reset(ctx context.Context, email string) error {
+	return s.lockoutRepo.Clear(ctx, lockout.AccountSubject(email))
+}
+
+// delay doubles from BaseDelay with every failure past DelayAfter.
+func (s *ILockoutService) delay(n int64) time.Duration {
+	delay := s.cfg.BaseDelay
+	for range n - 1 {
+		if delay >= s.cfg.MaxDelay {
+			break
+		}
+		delay *= 2
+	}
+
+	return min(delay, s.cfg.MaxDelay)
+}
+
+// notifyLocked lets the owner know, so they can reset the password if it
+// wasn't them. Unknown addresses are counted too but never notified.
+func (s *ILockoutService) notifyLocked(ctx context.Context, email string) {
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if !errors.Is(err, common.ErrRecordNotFound) {
+			log.Printf("Lockout notice lookup failed for %s: %v", email, err)
+		}
+		return
+	}
+
+	data := map[string]any{"Minutes": int(s.cfg.Duration.Minutes())}
+	if err := s.otpRepo.SendNotice(ctx, otp.NewRecipient(user), otp.ServiceAccountLocked, data); err != nil {
+		log.Printf("Lockout notice send failed for %s: %v", user.Email, err)
+	}
+}
@@ -0,0 +1,307 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"onboarding/common"
+	"onboarding/internal/entity"
+	"onboarding/internal/repository"
+	"onboarding/internal/repository/mfa"
+	pw "onboarding/pkg/password"
+	"onboarding/pkg/totp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type MFAService interface {
+	EnrollTOTP(ctx context.Context, userUUID uuid.UUID) (entity.TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, userUUID uuid.UUID, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, userUUID uuid.UUID, code string) error
+	VerifyTOTP(ctx context.Context, user entity.User, code string) error
+	// VerifySecondFactor accepts either a TOTP code or a recovery code.
+	VerifySecondFactor(ctx context.Context, user entity.User, code string, recoveryCode string) error
+	RegenerateRecoveryCodes(ctx context.Context, userUUID uuid.UUID, code string) ([]string, error)
+	CountRecoveryCodes(ctx context.Context, userUUID uuid.UUID) (int64, error)
+	VerifyRecoveryCode(ctx context.Context, user entity.User, recoveryCode string) error
+}
+
+var (
+	ErrTOTPAlreadyEnabled = errors.New("Two-factor authentication is already enabled")
+	ErrTOTPNotEnrolled    = errors.New("Two-factor authentication is not enrolled")
+	ErrInvalidMFACode     = errors.New("Authentication code is invalid")
+)
+
+const (
+	// totpSkew accepts codes of one time step before and after the current one.
+	totpSkew = 1
+
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 12
+	// recoveryCodeLookupLength is how much of a code is kept in clear to
+	// find it. The rest is only kept hashed.
+	recoveryCodeLookupLength = 4
+	// recoveryCodeAlphabet leaves out characters that are easily confused
+	// when read from paper, like 0/o and 1/l/i.
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+)
+
+type IMFAService struct {
+	userRepo         repository.UserRepository
+	recoveryCodeRepo repository.MFARecoveryCodeRepository
+	mfaRepo          mfa.MFARepository
+	issuer           string
+}
+
+func NewMFAService(
+	userRepo repository.UserRepository,
+	recoveryCodeRepo repository.MFARecoveryCodeRepository,
+	mfaRepo mfa.MFARepository,
+	issuer string,
+) MFAService {
+	return &IMFAService{
+		userRepo:         userRepo,
+		recoveryCodeRepo: recoveryCodeRepo,
+		mfaRepo:          mfaRepo,
+		issuer:           issuer,
+	}
+}
+
+func (s *IMFAService) EnrollTOTP(ctx context.Context, userUUID uuid.UUID) (entity.TOTPEnrollment, error) {
+	user, err := s.userRepo.GetUserByUUID(ctx, userUUID)
+	if err != nil {
+		return entity.TOTPEnrollment{}, err
+	}
+
+	if user.TOTPEnabled {
+		return entity.TOTPEnrollment{}, ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return entity.TOTPEnrollment{}, err
+	}
+
+	if err := s.userRepo.UpdateUserTOTP(ctx, user.UUID, secret, false); err != nil {
+		return entity.TOTPEnrollment{}, err
+	}
+
+	return entity.TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(s.issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables TOTP once the first code checks out and returns the
+// initial set of recovery codes. They are only ever shown this once.
+func (s *IMFAService) ConfirmTOTP(ctx context.Context, userUUID uuid.UUID, code string) ([]string, error) {
+	user, err := s.userRepo.GetUserByUUID(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	if err := s.VerifyTOTP(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, err := s.replaceRecoveryCodes(ctx, user.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.UpdateUserTOTP(ctx, user.UUID, user.TOTPSecret, true); err != nil {
+		return nil, err
+	}
+
+	return recoveryCodes, nil
+}
+
+func (s *IMFAService) DisableTOTP(ctx context.Context, userUUID uuid.UUID, code string) error {
+	user, err := s.userRepo.GetUserByUUID(ctx, userUUID)
+	if err != nil {
+		return err
+	}
+
+	if !user.TOTPEnabled {
+		return ErrTOTPNotEnrolled
+	}
+
+	if err := s.VerifyTOTP(ctx, user, code); err != nil {
+		return err
+	}
+
+	if err := s.recoveryCodeRepo.DeleteRecoveryCodes(ctx, user.UUID); err != nil {
+		return err
+	}
+
+	return s.userRepo.UpdateUserTOTP(ctx, user.UUID, "", false)
+}
+
+// VerifyTOTP checks the code against the user's secret. A code is accepted
+// only once, even though it stays valid for the whole time step.
+func (s *IMFAService) VerifyTOTP(ctx context.Context, user entity.User, code string) error {
+	if user.TOTPSecret == "" {
+		return ErrTOTPNotEnrolled
+	}
+
+	step, ok, err := totp.Validate(user.TOTPSecret, code, time.Now(), totpSkew)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	ttl := (2*totpSkew + 1) * totp.Period
+	fresh, err := s.mfaRepo.MarkTOTPStepUsed(ctx, user.UUID, step, ttl)
+	if err != nil {
+		return err
+	}
+
+	if !fresh {
+		return ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+func (s *IMFAService) VerifySecondFactor(
+	ctx context.Context,
+	user entity.User,
+	code string,
+	recoveryCode string,
+) error {
+	if code != "" {
+		return s.VerifyTOTP(ctx, user, code)
+	}
+
+	return s.VerifyRecoveryCode(ctx, user, recoveryCode)
+}
+
+func (s *IMFAService) RegenerateRecoveryCodes(ctx context.Context, userUUID uuid.UUID, code string) ([]string, error) {
+	user, err := s.userRepo.GetUserByUUID(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.TOTPEnabled {
+		return nil, ErrTOTPNotEnrolled
+	}
+
+	if err := s.VerifyTOTP(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	return s.replaceRecoveryCodes(ctx, user.UUID)
+}
+
+func (s *IMFAService) CountRecoveryCodes(ctx context.Context, userUUID uuid.UUID) (int64, error) {
+	return s.recoveryCodeRepo.CountUnusedRecoveryCodes(ctx, userUUID)
+}
+
+// VerifyRecoveryCode redeems one of the user's unused recovery codes.
+func (s *IMFAService) VerifyRecoveryCode(ctx context.Context, user entity.User, recoveryCode string) error {
+	if !user.TOTPEnabled {
+		return ErrTOTPNotEnrolled
+	}
+
+	normalized := normalizeRecoveryCode(recoveryCode)
+	if len(normalized) < recoveryCodeLookupLength {
+		return ErrInvalidMFACode
+	}
+
+	codes, err := s.recoveryCodeRepo.FindUnusedRecoveryCodes(ctx, user.UUID, normalized[:recoveryCodeLookupLength])
+	if err != nil {
+		return err
+	}
+
+	for _, code := range codes {
+		if err := pw.CheckPassword(normalized, code.CodeHash); err != nil {
+			continue
+		}
+
+		err := s.recoveryCodeRepo.MarkRecoveryCodeUsed(ctx, code.ID)
+		if errors.Is(err, common.ErrRecordNotFound) {
+			return ErrInvalidMFACode
+		}
+
+		return err
+	}
+
+	return ErrInvalidMFACode
+}
+
+func (s *IMFAService) replaceRecoveryCodes(ctx context.Context, userUUID uuid.UUID) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	stored := make([]entity.MFARecoveryCode, 0, recoveryCodeCount)
+	lookups := make(map[string]bool, recoveryCodeCount)
+
+	for len(codes) < recoveryCodeCount {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		// Every code of the user needs its own lookup.
+		normalized := normalizeRecoveryCode(code)
+		lookup := normalized[:recoveryCodeLookupLength]
+		if lookups[lookup] {
+			continue
+		}
+		lookups[lookup] = true
+
+		hash, err := pw.HashPassword(normalized)
+		if err != nil {
+			return nil, err
+		}
+
+		codes = append(codes, code)
+		stored = append(stored, entity.MFARecoveryCode{Lookup: lookup, CodeHash: hash})
+	}
+
+	if err := s.recoveryCodeRepo.ReplaceRecoveryCodes(ctx, userUUID, stored); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// generateRecoveryCode returns a code formatted as two dash separated halves,
+// e.g. "k7m2p4-x9qrab".
+func generateRecoveryCode() (string, error) {
+	// Bytes past the last multiple of the alphabet size are skipped so every
+	// character is equally likely.
+	limit := byte(256 - 256%len(recoveryCodeAlphabet))
+
+	var code strings.Builder
+	b := make([]byte, 1)
+	for n := 0; n < recoveryCodeLength; {
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+
+		if b[0] >= limit {
+			continue
+		}
+
+		if n == recoveryCodeLength/2 {
+			code.WriteByte('-')
+		}
+		code.WriteByte(recoveryCodeAlphabet[int(b[0])%len(recoveryCodeAlphabet)])
+		n++
+	}
+
+	return code.String(), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
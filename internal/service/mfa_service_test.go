@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"onboarding/internal/entity"
+	pw "onboarding/pkg/password"
+	"onboarding/pkg/totp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// enableTOTP enrolls and confirms TOTP for the user and returns the recovery
+// codes.
+func (env *testEnv) enableTOTP(t *testing.T, user entity.User) []string {
+	ctx := context.Background()
+
+	enrollment, err := env.mfa.EnrollTOTP(ctx, user.UUID)
+	require.NoError(t, err)
+
+	code, err := totp.Code(enrollment.Secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+
+	recoveryCodes, err := env.mfa.ConfirmTOTP(ctx, user.UUID, code)
+	require.NoError(t, err)
+
+	return recoveryCodes
+}
+
+func TestVerifyRecoveryCode(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	user := env.addUser(t, "user@example.com")
+	recoveryCodes := env.enableTOTP(t, user)
+	require.Len(t, recoveryCodes, recoveryCodeCount)
+
+	user, err := env.users.GetUserByUUID(ctx, user.UUID)
+	require.NoError(t, err)
+	require.True(t, user.TOTPEnabled)
+
+	// Codes are taken however they're typed.
+	typed := strings.ToUpper(strings.ReplaceAll(recoveryCodes[0], "-", " "))
+	require.NoError(t, env.mfa.VerifyRecoveryCode(ctx, user, typed))
+	require.ErrorIs(t, env.mfa.VerifyRecoveryCode(ctx, user, recoveryCodes[0]), ErrInvalidMFACode)
+	require.ErrorIs(t, env.mfa.VerifyRecoveryCode(ctx, user, "abc"), ErrInvalidMFACode)
+
+	count, err := env.mfa.CountRecoveryCodes(ctx, user.UUID)
+	require.NoError(t, err)
+	require.Equal(t, int64(recoveryCodeCount-1), count)
+}
+
+func TestVerifyRecoveryCodeWithoutLookup(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	user, _ := env.addTOTPUser(t, "user@example.com")
+
+	// Codes made before lookups were kept are still taken.
+	hash, err := pw.HashPassword("k7m2px9qra")
+	require.NoError(t, err)
+
+	repo := env.mfa.(*IMFAService).recoveryCodeRepo
+	require.NoError(t, repo.ReplaceRecoveryCodes(ctx, user.UUID, []entity.MFARecoveryCode{{CodeHash: hash}}))
+
+	require.NoError(t, env.mfa.VerifyRecoveryCode(ctx, user, "k7m2p-x9qra"))
+	require.ErrorIs(t, env.mfa.VerifyRecoveryCode(ctx, user, "k7m2p-x9qra"), ErrInvalidMFACode)
+}
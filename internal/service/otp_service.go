@@ -2,15 +2,26 @@ package service
 
 import (
 	"context"
+	"errors"
+	"onboarding/internal/entity"
 	"onboarding/internal/repository"
 	otp "onboarding/internal/repository/otp"
 )
 
 type OtpService interface {
-	SendOtpForgotPassword(ctx context.Context, email string) error
+	SendOtpForgotPassword(ctx context.Context, email string, ip string) error
 	VerifyOtpForgotPassword(ctx context.Context, email string, otpCode string) error
+	SendOtpVerifyEmail(ctx context.Context, email string, ip string) error
+	// VerifyOtpVerifyEmail checks the code sent by SendOtpVerifyEmail and
+	// activates the account.
+	VerifyOtpVerifyEmail(ctx context.Context, email string, otpCode string) error
+	// SendMagicLink only sends a link to accounts that are able to log in.
+	SendMagicLink(ctx context.Context, email string, ip string) error
+	ConsumeMagicLink(ctx context.Context, token string) (string, error)
 }
 
+var ErrEmailAlreadyVerified = errors.New("E-mail address is already verified")
+
 type IOtpService struct {
 	userRepo repository.UserRepository
 	otpRepo  otp.OtpRepository
@@ -26,15 +37,67 @@ func NewOtpService(
 	}
 }
 
-func (s *IOtpService) SendOtpForgotPassword(ctx context.Context, email string) error {
-	_, err := s.userRepo.GetUserByEmail(ctx, email)
+func (s *IOtpService) SendOtpForgotPassword(ctx context.Context, email string, ip string) error {
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
 	if err != nil {
 		return err
 	}
 
-	return s.otpRepo.SendOtp(ctx, email, otp.ServiceForgotPassword)
+	return s.otpRepo.SendOtp(ctx, otp.NewRecipient(user), ip, otp.ServiceForgotPassword)
 }
 
 func (s *IOtpService) VerifyOtpForgotPassword(ctx context.Context, email string, otpCode string) error {
-	return s.otpRepo.VerifyOtp(ctx, email, otpCode, otp.ServiceForgotPassword)
+	return s.otpRepo.VerifyOtp(ctx, otp.Recipient{Email: email}, otpCode, otp.ServiceForgotPassword)
+}
+
+func (s *IOtpService) SendOtpVerifyEmail(ctx context.Context, email string, ip string) error {
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	if user.Status != entity.UserStatusPendingVerification {
+		return ErrEmailAlreadyVerified
+	}
+
+	return s.otpRepo.SendOtp(ctx, otp.NewRecipient(user), ip, otp.ServiceVerifyEmail)
+}
+
+func (s *IOtpService) VerifyOtpVerifyEmail(ctx context.Context, email string, otpCode string) error {
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	if user.Status != entity.UserStatusPendingVerification {
+		return ErrEmailAlreadyVerified
+	}
+
+	if err := s.otpRepo.VerifyOtp(ctx, otp.Recipient{Email: email}, otpCode, otp.ServiceVerifyEmail); err != nil {
+		return err
+	}
+
+	return s.userRepo.UpdateUserStatus(
+		ctx,
+		user.UUID,
+		entity.UserStatusPendingVerification,
+		entity.UserStatusActive,
+	)
+}
+
+func (s *IOtpService) SendMagicLink(ctx context.Context, email string, ip string) error {
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	if err := checkUserStatus(user); err != nil {
+		return err
+	}
+
+	return s.otpRepo.SendMagicLink(ctx, otp.NewRecipient(user), ip)
+}
+
+func (s *IOtpService) ConsumeMagicLink(ctx context.Context, token string) (string, error) {
+	return s.otpRepo.ConsumeMagicLink(ctx, token)
 }
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"onboarding/common"
+	"onboarding/internal/entity"
+	"onboarding/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// OutboxService lets admins follow the delivery of the queued messages and
+// replay the ones that were dead-lettered.
+type OutboxService interface {
+	ListMessages(ctx context.Context, filter entity.OutboxFilter) ([]entity.OutboxMessageViewModel, int64, error)
+	GetMessage(ctx context.Context, messageUUID uuid.UUID) (entity.OutboxMessageViewModel, error)
+	ReplayMessage(ctx context.Context, messageUUID uuid.UUID) error
+}
+
+var (
+	ErrOutboxMessageNotDead  = errors.New("Only dead messages can be replayed")
+	ErrOutboxMessageExpiring = errors.New("Messages with one-time codes or links can't be replayed")
+)
+
+type IOutboxService struct {
+	outboxRepo repository.OutboxRepository
+}
+
+func NewOutboxService(outboxRepo repository.OutboxRepository) OutboxService {
+	return &IOutboxService{outboxRepo: outboxRepo}
+}
+
+func (s *IOutboxService) ListMessages(
+	ctx context.Context,
+	filter entity.OutboxFilter,
+) ([]entity.OutboxMessageViewModel, int64, error) {
+	messages, total, err := s.outboxRepo.ListOutboxMessages(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	result := make([]entity.OutboxMessageViewModel, 0, len(messages))
+	for _, message := range messages {
+		result = append(result, message.ToViewModel())
+	}
+
+	return result, total, nil
+}
+
+func (s *IOutboxService) GetMessage(
+	ctx context.Context,
+	messageUUID uuid.UUID,
+) (entity.OutboxMessageViewModel, error) {
+	message, err := s.outboxRepo.GetOutboxMessage(ctx, messageUUID)
+	if err != nil {
+		return entity.OutboxMessageViewModel{}, err
+	}
+
+	return message.ToViewModel(), nil
+}
+
+func (s *IOutboxService) ReplayMessage(ctx context.Context, messageUUID uuid.UUID) error {
+	message, err := s.outboxRepo.GetOutboxMessage(ctx, messageUUID)
+	if err != nil {
+		return err
+	}
+
+	// The code or link would have expired long since, and the body is gone.
+	if message.ExpiresAt != nil {
+		return ErrOutboxMessageExpiring
+	}
+
+	err = s.outboxRepo.ReplayOutboxMessage(ctx, messageUUID)
+	if errors.Is(err, common.ErrRecordNotFound) {
+		return ErrOutboxMessageNotDead
+	}
+
+	return err
+}
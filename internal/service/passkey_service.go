@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"onboarding/internal/entity"
+	"onboarding/internal/repository"
+	"onboarding/internal/repository/ceremony"
+	"onboarding/pkg/passkey"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+)
+
+type PasskeyService interface {
+	BeginRegistration(ctx context.Context, userUUID uuid.UUID) (*protocol.CredentialCreation, error)
+	FinishRegistration(ctx context.Context, userUUID uuid.UUID, response []byte) (entity.PasskeyCredentialViewModel, error)
+	ListPasskeys(ctx context.Context, userUUID uuid.UUID) ([]entity.PasskeyCredentialViewModel, error)
+	DeletePasskey(ctx context.Context, userUUID uuid.UUID, uuid uuid.UUID) error
+	BeginLogin(ctx context.Context) (*protocol.CredentialAssertion, error)
+	// VerifyLogin checks a login assertion and returns the user the passkey
+	// belongs to.
+	VerifyLogin(ctx context.Context, response []byte) (entity.User, error)
+}
+
+var (
+	ErrPasskeyCeremonyExpired = errors.New("Passkey challenge has expired or was already used")
+	ErrInvalidPasskey         = errors.New("Passkey is invalid")
+)
+
+type IPasskeyService struct {
+	userRepo       repository.UserRepository
+	credentialRepo repository.PasskeyCredentialRepository
+	ceremonyRepo   ceremony.CeremonyRepository
+	passkey        passkey.Passkey
+}
+
+func NewPasskeyService(
+	userRepo repository.UserRepository,
+	credentialRepo repository.PasskeyCredentialRepository,
+	ceremonyRepo ceremony.CeremonyRepository,
+	passkey passkey.Passkey,
+) PasskeyService {
+	return &IPasskeyService{
+		userRepo:       userRepo,
+		credentialRepo: credentialRepo,
+		ceremonyRepo:   ceremonyRepo,
+		passkey:        passkey,
+	}
+}
+
+func (s *IPasskeyService) BeginRegistration(
+	ctx context.Context,
+	userUUID uuid.UUID,
+) (*protocol.CredentialCreation, error) {
+	user, err := s.passkeyUser(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	options, session, err := s.passkey.BeginRegistration(user)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.ceremonyRepo.SaveCeremony(ctx, ceremony.KindRegistration, *session); err != nil {
+		return nil, err
+	}
+
+	return options, nil
+}
+
+func (s *IPasskeyService) FinishRegistration(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	response []byte,
+) (entity.PasskeyCredentialViewModel, error) {
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(response)
+	if err != nil {
+		return entity.PasskeyCredentialViewModel{}, fmt.Errorf("%w: %w", ErrInvalidPasskey, err)
+	}
+
+	session, err := s.takeCeremony(ctx, ceremony.KindRegistration, parsed.Response.CollectedClientData.Challenge)
+	if err != nil {
+		return entity.PasskeyCredentialViewModel{}, err
+	}
+
+	user, err := s.passkeyUser(ctx, userUUID)
+	if err != nil {
+		return entity.PasskeyCredentialViewModel{}, err
+	}
+
+	credential, err := s.passkey.FinishRegistration(user, session, parsed)
+	if err != nil {
+		return entity.PasskeyCredentialViewModel{}, fmt.Errorf("%w: %w", ErrInvalidPasskey, err)
+	}
+
+	encoded, err := json.Marshal(credential)
+	if err != nil {
+		return entity.PasskeyCredentialViewModel{}, err
+	}
+
+	arg := entity.PasskeyCredential{
+		UUID:         uuid.New(),
+		UserUUID:     userUUID,
+		CredentialID: credential.ID,
+		Credential:   encoded,
+		CreatedAt:    time.Now(),
+	}
+
+	if err := s.credentialRepo.CreatePasskeyCredential(ctx, arg); err != nil {
+		return entity.PasskeyCredentialViewModel{}, err
+	}
+
+	return arg.ToViewModel(), nil
+}
+
+func (s *IPasskeyService) ListPasskeys(
+	ctx context.Context,
+	userUUID uuid.UUID,
+) ([]entity.PasskeyCredentialViewModel, error) {
+	credentials, err := s.credentialRepo.ListPasskeyCredentials(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]entity.PasskeyCredentialViewModel, 0, len(credentials))
+	for _, credential := range credentials {
+		result = append(result, credential.ToViewModel())
+	}
+
+	return result, nil
+}
+
+func (s *IPasskeyService) DeletePasskey(ctx context.Context, userUUID uuid.UUID, uuid uuid.UUID) error {
+	return s.credentialRepo.DeletePasskeyCredential(ctx, userUUID, uuid)
+}
+
+func (s *IPasskeyService) BeginLogin(ctx context.Context) (*protocol.CredentialAssertion, error) {
+	options, session, err := s.passkey.BeginLogin()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.ceremonyRepo.SaveCeremony(ctx, ceremony.KindLogin, *session); err != nil {
+		return nil, err
+	}
+
+	return options, nil
+}
+
+func (s *IPasskeyService) VerifyLogin(ctx context.Context, response []byte) (entity.User, error) {
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(response)
+	if err != nil {
+		return entity.User{}, fmt.Errorf("%w: %w", ErrInvalidPasskey, err)
+	}
+
+	session, err := s.takeCeremony(ctx, ceremony.KindLogin, parsed.Response.CollectedClientData.Challenge)
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	var user entity.User
+	_, credential, err := s.passkey.FinishLogin(session, parsed, func(userUUID uuid.UUID) (*passkey.User, error) {
+		found, err := s.userRepo.GetUserByUUID(ctx, userUUID)
+		if err != nil {
+			return nil, err
+		}
+
+		user = found
+		return s.passkeyUserOf(ctx, user)
+	})
+	if err != nil {
+		return entity.User{}, fmt.Errorf("%w: %w", ErrInvalidPasskey, err)
+	}
+
+	encoded, err := json.Marshal(credential)
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	if err := s.credentialRepo.UpdatePasskeyCredential(ctx, credential.ID, encoded, time.Now()); err != nil {
+		return entity.User{}, err
+	}
+
+	return user, nil
+}
+
+func (s *IPasskeyService) takeCeremony(
+	ctx context.Context,
+	kind ceremony.Kind,
+	challenge string,
+) (webauthn.SessionData, error) {
+	session, err := s.ceremonyRepo.TakeCeremony(ctx, kind, challenge)
+	if errors.Is(err, ceremony.ErrCeremonyNotFound) {
+		return session, ErrPasskeyCeremonyExpired
+	}
+
+	return session, err
+}
+
+func (s *IPasskeyService) passkeyUser(ctx context.Context, userUUID uuid.UUID) (*passkey.User, error) {
+	user, err := s.userRepo.GetUserByUUID(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.passkeyUserOf(ctx, user)
+}
+
+func (s *IPasskeyService) passkeyUserOf(ctx context.Context, user entity.User) (*passkey.User, error) {
+	stored, err := s.credentialRepo.ListPasskeyCredentials(ctx, user.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := make([]webauthn.Credential, 0, len(stored))
+	for _, c := range stored {
+		var credential webauthn.Credential
+		if err := json.Unmarshal(c.Credential, &credential); err != nil {
+			return nil, fmt.Errorf("Couldn't decode passkey %s: %w", c.UUID, err)
+		}
+		credentials = append(credentials, credential)
+	}
+
+	return &passkey.User{
+		UUID:        user.UUID,
+		Name:        user.Email,
+		Credentials: credentials,
+	}, nil
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"onboarding/common"
+	"onboarding/internal/entity"
+	"onboarding/internal/repository"
+	"onboarding/internal/repository/permission"
+	"onboarding/pkg/authz"
+
+	"github.com/google/uuid"
+)
+
+type RoleService interface {
+	ListRoles(ctx context.Context) ([]entity.RoleViewModel, error)
+	CreateRole(ctx context.Context, name entity.UserRole, description string) (entity.RoleViewModel, error)
+	DeleteRole(ctx context.Context, name entity.UserRole) error
+	GrantPermission(ctx context.Context, name entity.UserRole, permission authz.Permission) error
+	RevokePermission(ctx context.Context, name entity.UserRole, permission authz.Permission) error
+	// AssignRole changes the role of a user. Permission checks see it right
+	// away, the role claim of the access token only after a refresh.
+	AssignRole(ctx context.Context, userUUID uuid.UUID, name entity.UserRole) error
+}
+
+var (
+	ErrRoleNotFound = errors.New("Role is not found")
+	ErrRoleExists   = errors.New("Role already exists")
+	ErrRoleBuiltIn  = errors.New("Built-in roles can't be deleted")
+	ErrRoleInUse    = errors.New("Role is still assigned to users")
+)
+
+type IRoleService struct {
+	userRepo       repository.UserRepository
+	roleRepo       repository.RoleRepository
+	permissionRepo permission.PermissionRepository
+}
+
+func NewRoleService(
+	userRepo repository.UserRepository,
+	roleRepo repository.RoleRepository,
+	permissionRepo permission.PermissionRepository,
+) RoleService {
+	return &IRoleService{
+		userRepo:       userRepo,
+		roleRepo:       roleRepo,
+		permissionRepo: permissionRepo,
+	}
+}
+
+func (s *IRoleService) ListRoles(ctx context.Context) ([]entity.RoleViewModel, error) {
+	roles, err := s.roleRepo.ListRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]entity.RoleViewModel, 0, len(roles))
+	for _, role := range roles {
+		permissions, err := s.roleRepo.ListRolePermissions(ctx, role.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, role.ToViewModel(permissions))
+	}
+
+	return result, nil
+}
+
+func (s *IRoleService) CreateRole(
+	ctx context.Context,
+	name entity.UserRole,
+	description string,
+) (entity.RoleViewModel, error) {
+	if _, err := s.getRole(ctx, name); err == nil {
+		return entity.RoleViewModel{}, ErrRoleExists
+	} else if !errors.Is(err, ErrRoleNotFound) {
+		return entity.RoleViewModel{}, err
+	}
+
+	role := entity.Role{
+		Name:        name,
+		Description: description,
+	}
+
+	if err := s.roleRepo.CreateRole(ctx, role); err != nil {
+		return entity.RoleViewModel{}, err
+	}
+
+	return role.ToViewModel(nil), nil
+}
+
+func (s *IRoleService) DeleteRole(ctx context.Context, name entity.UserRole) error {
+	role, err := s.getRole(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if role.BuiltIn {
+		return ErrRoleBuiltIn
+	}
+
+	count, err := s.roleRepo.CountRoleUsers(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return ErrRoleInUse
+	}
+
+	if err := s.roleRepo.DeleteRole(ctx, name); err != nil {
+		return err
+	}
+
+	return s.permissionRepo.InvalidateRolePermissions(ctx, string(name))
+}
+
+func (s *IRoleService) GrantPermission(ctx context.Context, name entity.UserRole, permission authz.Permission) error {
+	if _, err := s.getRole(ctx, name); err != nil {
+		return err
+	}
+
+	err := s.roleRepo.AddRolePermission(ctx, entity.RolePermission{
+		RoleName: name,
+		Resource: permission.Resource,
+		Action:   permission.Action,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.permissionRepo.InvalidateRolePermissions(ctx, string(name))
+}
+
+func (s *IRoleService) RevokePermission(ctx context.Context, name entity.UserRole, permission authz.Permission) error {
+	if _, err := s.getRole(ctx, name); err != nil {
+		return err
+	}
+
+	err := s.roleRepo.RemoveRolePermission(ctx, entity.RolePermission{
+		RoleName: name,
+		Resource: permission.Resource,
+		Action:   permission.Action,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.permissionRepo.InvalidateRolePermissions(ctx, string(name))
+}
+
+func (s *IRoleService) AssignRole(ctx context.Context, userUUID uuid.UUID, name entity.UserRole) error {
+	if _, err := s.getRole(ctx, name); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.UpdateUserRole(ctx, userUUID, name); err != nil {
+		return err
+	}
+
+	return s.permissionRepo.InvalidateUserRole(ctx, userUUID)
+}
+
+func (s *IRoleService) getRole(ctx context.Context, name entity.UserRole) (entity.Role, error) {
+	role, err := s.roleRepo.GetRole(ctx, name)
+	if errors.Is(err, common.ErrRecordNotFound) {
+		return role, ErrRoleNotFound
+	}
+
+	return role, err
+}
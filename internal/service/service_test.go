@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"onboarding/common"
+	"onboarding/internal/entity"
+	"onboarding/internal/repository/lockout"
+	"onboarding/internal/repository/mfa"
+	"onboarding/internal/repository/otp"
+	"onboarding/internal/repository/permission"
+	"onboarding/internal/repository/refresh"
+	"onboarding/internal/repository/revocation"
+	"onboarding/pkg/config"
+	pw "onboarding/pkg/password"
+	"onboarding/pkg/templates"
+	"onboarding/pkg/token"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testPassword       = "Passw0rd!"
+	testIP             = "192.0.2.1"
+	testResendCooldown = time.Minute
+)
+
+var (
+	testLockout = config.Lockout{
+		MaxAttempts:   3,
+		IPMaxAttempts: 100,
+		Window:        time.Hour,
+		Duration:      15 * time.Minute,
+		DelayAfter:    10,
+		BaseDelay:     time.Second,
+		MaxDelay:      time.Minute,
+	}
+
+	codePattern      = regexp.MustCompile(`\b[0-9]{6}\b`)
+	magicLinkPattern = regexp.MustCompile(`https://\S+`)
+)
+
+// testEnv wires the services to Redis on miniredis and the database
+// repositories to fakes.
+type testEnv struct {
+	server   *miniredis.Miniredis
+	users    *fakeUserRepository
+	sessions *fakeSessionRepository
+	sender   *fakeSender
+	jwt      token.JWT
+
+	auth    AuthService
+	user    UserService
+	admin   AdminService
+	otp     OtpService
+	mfa     MFAService
+	lockout LockoutService
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	private, public := common.GenerateRSAKey(t)
+	jwtImpl, err := token.NewJWT(config.Token{
+		AccessTokenDuration:  time.Minute,
+		RefreshTokenDuration: time.Hour,
+		MFATokenDuration:     5 * time.Minute,
+		PrivateKey:           private,
+		PublicKey:            public,
+		Issuer:               "onboarding",
+	})
+	require.NoError(t, err)
+
+	catalog, err := templates.New(config.Templates{DefaultLocale: "en"})
+	require.NoError(t, err)
+
+	env := &testEnv{
+		server:   server,
+		users:    newFakeUserRepository(),
+		sessions: newFakeSessionRepository(),
+		sender:   &fakeSender{},
+		jwt:      jwtImpl,
+	}
+
+	otpRepo := otp.NewOtpRepository(
+		client,
+		env.sender,
+		catalog,
+		config.MagicLink{URL: "https://example.com/magic-link", TTL: 15 * time.Minute},
+		config.OTP{
+			ResendCooldown:    testResendCooldown,
+			EmailHourlyQuota:  100,
+			IPHourlyQuota:     100,
+			MaxVerifyAttempts: 3,
+			Length:            6,
+			Alphabet:          "0123456789",
+			Secret:            []byte("0123456789abcdef0123456789abcdef"),
+		},
+	)
+	refreshRepo := refresh.NewRefreshRepository(client)
+	mfaRepo := mfa.NewMFARepository(client)
+
+	env.otp = NewOtpService(env.users, otpRepo)
+	env.mfa = NewMFAService(env.users, &fakeRecoveryCodeRepository{}, mfaRepo, "Onboarding")
+	env.lockout = NewLockoutService(env.users, otpRepo, lockout.NewLockoutRepository(client), testLockout)
+	sessionService := NewSessionService(env.sessions, refreshRepo)
+	env.auth = NewAuthService(
+		env.users,
+		env.sessions,
+		refreshRepo,
+		revocation.NewRevocationRepository(client),
+		mfaRepo,
+		env.mfa,
+		nil,
+		env.otp,
+		env.lockout,
+		jwtImpl,
+		fakeTransactor{},
+	)
+	env.user = NewUserService(env.users, otpRepo, sessionService, env.lockout, fakeTransactor{})
+	env.admin = NewAdminService(
+		env.users,
+		otpRepo,
+		permission.NewPermissionRepository(client),
+		sessionService,
+		env.lockout,
+	)
+
+	return env
+}
+
+// addUser stores an active user with testPassword.
+func (env *testEnv) addUser(t *testing.T, email string) entity.User {
+	hash, err := pw.HashPassword(testPassword)
+	require.NoError(t, err)
+
+	return env.users.add(entity.User{
+		Email:    email,
+		Password: hash,
+		Status:   entity.UserStatusActive,
+		Role:     entity.UserRoleUser,
+	})
+}
+
+// login starts a session with the password.
+func (env *testEnv) login(t *testing.T, email string) *token.TokenPair {
+	result, err := env.auth.Login(context.Background(), email, testPassword, entity.SessionClient{IP: testIP})
+	require.NoError(t, err)
+	require.NotNil(t, result.TokenPair)
+
+	return result.TokenPair
+}
+
+// lastCode returns the code in the last message sent to the address.
+func (env *testEnv) lastCode(t *testing.T, to string) string {
+	messages := env.sender.sentTo(to)
+	require.NotEmpty(t, messages, "nothing was sent to %s", to)
+
+	code := codePattern.FindString(messages[len(messages)-1].Text)
+	require.NotEmpty(t, code, "no code was sent to %s", to)
+
+	return code
+}
+
+// lastMagicLinkToken returns the token of the last link sent to the address.
+func (env *testEnv) lastMagicLinkToken(t *testing.T, to string) string {
+	messages := env.sender.sentTo(to)
+	require.NotEmpty(t, messages, "nothing was sent to %s", to)
+
+	link, err := url.Parse(magicLinkPattern.FindString(messages[len(messages)-1].Text))
+	require.NoError(t, err)
+
+	magicLinkToken := link.Query().Get("token")
+	require.NotEmpty(t, magicLinkToken)
+
+	return magicLinkToken
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"onboarding/internal/entity"
+	"onboarding/internal/repository"
+	"onboarding/internal/repository/refresh"
+
+	"github.com/google/uuid"
+)
+
+type SessionService interface {
+	ListSessions(ctx context.Context, userUUID uuid.UUID) ([]entity.SessionViewModel, error)
+	RevokeSession(ctx context.Context, userUUID uuid.UUID, sessionUUID uuid.UUID) error
+	RevokeOtherSessions(ctx context.Context, userUUID uuid.UUID, currentUUID uuid.UUID) error
+	RevokeAllSessions(ctx context.Context, userUUID uuid.UUID) error
+}
+
+type ISessionService struct {
+	sessionRepo repository.SessionRepository
+	refreshRepo refresh.RefreshRepository
+}
+
+func NewSessionService(
+	sessionRepo repository.SessionRepository,
+	refreshRepo refresh.RefreshRepository,
+) SessionService {
+	return &ISessionService{
+		sessionRepo: sessionRepo,
+		refreshRepo: refreshRepo,
+	}
+}
+
+func (s *ISessionService) ListSessions(ctx context.Context, userUUID uuid.UUID) ([]entity.SessionViewModel, error) {
+	sessions, err := s.sessionRepo.ListActiveSessions(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]entity.SessionViewModel, 0, len(sessions))
+	for _, session := range sessions {
+		result = append(result, session.ToViewModel())
+	}
+
+	return result, nil
+}
+
+func (s *ISessionService) RevokeSession(ctx context.Context, userUUID uuid.UUID, sessionUUID uuid.UUID) error {
+	if err := s.sessionRepo.RevokeSession(ctx, userUUID, sessionUUID); err != nil {
+		return err
+	}
+
+	return s.refreshRepo.RevokeFamily(ctx, sessionUUID)
+}
+
+func (s *ISessionService) RevokeOtherSessions(ctx context.Context, userUUID uuid.UUID, currentUUID uuid.UUID) error {
+	revoked, err := s.sessionRepo.RevokeSessionsExcept(ctx, userUUID, currentUUID)
+	if err != nil {
+		return err
+	}
+
+	for _, sessionUUID := range revoked {
+		if err := s.refreshRepo.RevokeFamily(ctx, sessionUUID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *ISessionService) RevokeAllSessions(ctx context.Context, userUUID uuid.UUID) error {
+	return s.RevokeOtherSessions(ctx, userUUID, uuid.Nil)
+}
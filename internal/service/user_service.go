@@ -2,25 +2,74 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"onboarding/common"
 	"onboarding/internal/entity"
 	"onboarding/internal/repository"
+	otp "onboarding/internal/repository/otp"
+	"onboarding/pkg/notify"
 	pw "onboarding/pkg/password"
 
 	"github.com/google/uuid"
+	"golang.org/x/text/language"
 )
 
 type UserService interface {
 	GetUser(ctx context.Context, id uuid.UUID) (entity.UserViewModel, error)
 	ChangeUserPassword(ctx context.Context, email, newPassword string) error
+	// UpdatePassword changes the password of a logged in user, who has to
+	// know the current one. Every session is logged out afterwards.
+	UpdatePassword(ctx context.Context, userUUID uuid.UUID, currentPassword, newPassword string) error
+	// RequestEmailChange sends a code to the new address and lets the current
+	// one know a change was asked for.
+	RequestEmailChange(ctx context.Context, userUUID uuid.UUID, password, newEmail, ip string) error
+	// ConfirmEmailChange swaps the address once the code sent to it is
+	// confirmed.
+	ConfirmEmailChange(ctx context.Context, userUUID uuid.UUID, newEmail, otpCode string) error
+	// RequestPhoneChange sets a new, unverified phone number, texts a code to
+	// it and lets the e-mail address know.
+	RequestPhoneChange(ctx context.Context, userUUID uuid.UUID, password, phone, ip string) error
+	// ConfirmPhoneChange verifies the phone number once the code texted to it
+	// is confirmed.
+	ConfirmPhoneChange(ctx context.Context, userUUID uuid.UUID, otpCode string) error
+	// UpdateNotificationChannel sets where codes and notices go and lets the
+	// e-mail address know. SMS needs a verified phone number.
+	UpdateNotificationChannel(ctx context.Context, userUUID uuid.UUID, channel notify.Channel) error
+	// UpdateLocale sets the language messages are sent in. An empty locale
+	// goes back to the client's Accept-Language.
+	UpdateLocale(ctx context.Context, userUUID uuid.UUID, locale string) error
 }
 
+var (
+	ErrEmailTaken           = errors.New("E-mail is already registered.")
+	ErrPhoneTaken           = errors.New("Phone number is already registered.")
+	ErrPhoneNotVerified     = errors.New("Phone number is not verified.")
+	ErrPhoneAlreadyVerified = errors.New("Phone number is already verified.")
+	ErrInvalidLocale        = errors.New("Locale is not a valid language tag.")
+)
+
 type IUserService struct {
-	userRepo repository.UserRepository
+	userRepo       repository.UserRepository
+	otpRepo        otp.OtpRepository
+	sessionService SessionService
+	lockoutService LockoutService
+	transactor     repository.Transactor
 }
 
-func NewUserService(userRepo repository.UserRepository) UserService {
+func NewUserService(
+	userRepo repository.UserRepository,
+	otpRepo otp.OtpRepository,
+	sessionService SessionService,
+	lockoutService LockoutService,
+	transactor repository.Transactor,
+) UserService {
 	return &IUserService{
-		userRepo: userRepo,
+		userRepo:       userRepo,
+		otpRepo:        otpRepo,
+		sessionService: sessionService,
+		lockoutService: lockoutService,
+		transactor:     transactor,
 	}
 }
 
@@ -41,5 +90,232 @@ func (s *IUserService) ChangeUserPassword(ctx context.Context, email, newPasswor
 		return err
 	}
 
-	return s.userRepo.UpdateUserPassword(ctx, email, hashedPassword)
+	if err := s.userRepo.UpdateUserPassword(ctx, email, hashedPassword); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	// Whoever locked the account out doesn't know the new password.
+	if err := s.lockoutService.Reset(ctx, email); err != nil {
+		return err
+	}
+
+	return s.sessionService.RevokeAllSessions(ctx, user.UUID)
+}
+
+func (s *IUserService) UpdatePassword(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	currentPassword string,
+	newPassword string,
+) error {
+	user, err := s.checkPassword(ctx, userUUID, currentPassword)
+	if err != nil {
+		return err
+	}
+
+	return s.ChangeUserPassword(ctx, user.Email, newPassword)
+}
+
+func (s *IUserService) RequestEmailChange(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	password string,
+	newEmail string,
+	ip string,
+) error {
+	user, err := s.checkPassword(ctx, userUUID, password)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkEmailAvailable(ctx, newEmail); err != nil {
+		return err
+	}
+
+	// The code isn't sent without the notice to the current address.
+	return s.transactor.Transaction(ctx, func(ctx context.Context) error {
+		to := otp.Recipient{UserUUID: userUUID, Email: newEmail}
+		if err := s.otpRepo.SendOtp(ctx, to, ip, otp.ServiceChangeEmail); err != nil {
+			return err
+		}
+
+		return s.otpRepo.SendNotice(
+			ctx,
+			otp.NewRecipient(user),
+			otp.ServiceChangeEmail,
+			map[string]any{"NewEmail": newEmail},
+		)
+	})
+}
+
+func (s *IUserService) ConfirmEmailChange(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	newEmail string,
+	otpCode string,
+) error {
+	to := otp.Recipient{UserUUID: userUUID, Email: newEmail}
+	if err := s.otpRepo.VerifyOtp(ctx, to, otpCode, otp.ServiceChangeEmail); err != nil {
+		return err
+	}
+
+	if err := s.checkEmailAvailable(ctx, newEmail); err != nil {
+		return err
+	}
+
+	return s.userRepo.UpdateUserEmail(ctx, userUUID, newEmail)
+}
+
+func (s *IUserService) RequestPhoneChange(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	password string,
+	phone string,
+	ip string,
+) error {
+	// Codes, password resets among them, can be texted to the number, so
+	// setting it takes the password like an e-mail change.
+	user, err := s.checkPassword(ctx, userUUID, password)
+	if err != nil {
+		return err
+	}
+
+	if user.VerifiedPhone() == phone {
+		return ErrPhoneAlreadyVerified
+	}
+
+	if err := s.checkPhoneAvailable(ctx, userUUID, phone); err != nil {
+		return err
+	}
+
+	// The number is only kept when the code and the notice are queued.
+	return s.transactor.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.UpdateUserPhone(ctx, userUUID, phone); err != nil {
+			return err
+		}
+
+		to := otp.Recipient{UserUUID: user.UUID, Email: user.Email, Phone: phone}
+		if err := s.otpRepo.SendOtp(ctx, to, ip, otp.ServiceVerifyPhone); err != nil {
+			return err
+		}
+
+		return s.otpRepo.SendNotice(
+			ctx,
+			otp.NewRecipient(user),
+			otp.ServiceChangePhone,
+			map[string]any{"Phone": phone},
+		)
+	})
+}
+
+func (s *IUserService) ConfirmPhoneChange(ctx context.Context, userUUID uuid.UUID, otpCode string) error {
+	user, err := s.userRepo.GetUserByUUID(ctx, userUUID)
+	if err != nil {
+		return err
+	}
+
+	if user.PhoneVerifiedAt != nil {
+		return ErrPhoneAlreadyVerified
+	}
+
+	// The code only matches the number it was texted to, not one set since.
+	to := otp.Recipient{UserUUID: user.UUID, Email: user.Email, Phone: user.Phone}
+	if err := s.otpRepo.VerifyOtp(ctx, to, otpCode, otp.ServiceVerifyPhone); err != nil {
+		return err
+	}
+
+	if err := s.checkPhoneAvailable(ctx, userUUID, user.Phone); err != nil {
+		return err
+	}
+
+	return s.userRepo.VerifyUserPhone(ctx, userUUID, user.Phone)
+}
+
+func (s *IUserService) UpdateNotificationChannel(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	channel notify.Channel,
+) error {
+	user, err := s.userRepo.GetUserByUUID(ctx, userUUID)
+	if err != nil {
+		return err
+	}
+
+	if channel == notify.ChannelSMS && user.VerifiedPhone() == "" {
+		return ErrPhoneNotVerified
+	}
+
+	if channel == user.NotificationChannel {
+		return nil
+	}
+
+	return s.transactor.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.UpdateUserNotificationChannel(ctx, userUUID, channel); err != nil {
+			return err
+		}
+
+		return s.otpRepo.SendNotice(
+			ctx,
+			otp.NewRecipient(user),
+			otp.ServiceChangeChannel,
+			map[string]any{"Channel": string(channel)},
+		)
+	})
+}
+
+func (s *IUserService) UpdateLocale(ctx context.Context, userUUID uuid.UUID, locale string) error {
+	if locale != "" {
+		tag, err := language.Parse(locale)
+		if err != nil {
+			return ErrInvalidLocale
+		}
+		locale = tag.String()
+	}
+
+	return s.userRepo.UpdateUserLocale(ctx, userUUID, locale)
+}
+
+func (s *IUserService) checkPassword(ctx context.Context, userUUID uuid.UUID, password string) (entity.User, error) {
+	user, err := s.userRepo.GetUserByUUID(ctx, userUUID)
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	if err := pw.CheckPassword(password, user.Password); err != nil {
+		return entity.User{}, fmt.Errorf("%d", common.ErrCredentiials)
+	}
+
+	return user, nil
+}
+
+func (s *IUserService) checkEmailAvailable(ctx context.Context, email string) error {
+	_, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err == nil {
+		return ErrEmailTaken
+	}
+
+	if errors.Is(err, common.ErrRecordNotFound) {
+		return nil
+	}
+
+	return err
+}
+
+// checkPhoneAvailable fails when someone else verified the phone number.
+func (s *IUserService) checkPhoneAvailable(ctx context.Context, userUUID uuid.UUID, phone string) error {
+	owner, err := s.userRepo.GetUserByVerifiedPhone(ctx, phone)
+	if err == nil && owner.UUID != userUUID {
+		return ErrPhoneTaken
+	}
+
+	if err == nil || errors.Is(err, common.ErrRecordNotFound) {
+		return nil
+	}
+
+	return err
 }
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"onboarding/internal/repository/otp"
+	"onboarding/pkg/notify"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestForgotPassword(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	user := env.addUser(t, "user@example.com")
+	pair := env.login(t, "user@example.com")
+
+	require.NoError(t, env.otp.SendOtpForgotPassword(ctx, "user@example.com", testIP))
+	code := env.lastCode(t, "user@example.com")
+
+	require.ErrorIs(t, env.otp.VerifyOtpForgotPassword(ctx, "user@example.com", "000000"), otp.ErrOtpInvalid)
+	require.NoError(t, env.otp.VerifyOtpForgotPassword(ctx, "user@example.com", code))
+	require.NoError(t, env.user.ChangeUserPassword(ctx, "user@example.com", "N3w-Passw0rd!"))
+
+	// The code is spent, and the sessions from before the reset are over.
+	require.ErrorIs(t, env.otp.VerifyOtpForgotPassword(ctx, "user@example.com", code), otp.ErrOtpExpired)
+	_, err := env.auth.Refresh(ctx, pair.RefreshToken.SignedToken)
+	require.ErrorIs(t, err, ErrSessionRevoked)
+
+	changed, err := env.users.GetUserByUUID(ctx, user.UUID)
+	require.NoError(t, err)
+	require.NotEqual(t, user.Password, changed.Password)
+}
+
+func TestEmailChange(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	user := env.addUser(t, "user@example.com")
+
+	err := env.user.RequestEmailChange(ctx, user.UUID, "wrong", "new@example.com", testIP)
+	require.Error(t, err)
+	require.Empty(t, env.sender.messages)
+
+	require.NoError(t, env.user.RequestEmailChange(ctx, user.UUID, testPassword, "new@example.com", testIP))
+	code := env.lastCode(t, "new@example.com")
+	require.Len(t, env.sender.sentTo("user@example.com"), 1)
+
+	// Someone asking for the same address can't confirm it with this code.
+	other := env.addUser(t, "other@example.com")
+	err = env.user.ConfirmEmailChange(ctx, other.UUID, "new@example.com", code)
+	require.ErrorIs(t, err, otp.ErrOtpExpired)
+
+	require.NoError(t, env.user.ConfirmEmailChange(ctx, user.UUID, "new@example.com", code))
+
+	changed, err := env.users.GetUserByUUID(ctx, user.UUID)
+	require.NoError(t, err)
+	require.Equal(t, "new@example.com", changed.Email)
+
+	err = env.user.RequestEmailChange(ctx, other.UUID, testPassword, "new@example.com", testIP)
+	require.ErrorIs(t, err, ErrEmailTaken)
+}
+
+func TestPhoneChange(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	user := env.addUser(t, "user@example.com")
+
+	require.NoError(t, env.user.RequestPhoneChange(ctx, user.UUID, testPassword, "+15550100", testIP))
+	code := env.lastCode(t, "+15550100")
+	require.Equal(t, notify.ChannelSMS, env.sender.sentTo("+15550100")[0].Channel)
+	require.Len(t, env.sender.sentTo("user@example.com"), 1)
+
+	require.ErrorIs(t, env.user.UpdateNotificationChannel(ctx, user.UUID, notify.ChannelSMS), ErrPhoneNotVerified)
+
+	require.NoError(t, env.user.ConfirmPhoneChange(ctx, user.UUID, code))
+	require.NoError(t, env.user.UpdateNotificationChannel(ctx, user.UUID, notify.ChannelSMS))
+
+	// The verified number can't be taken by someone else.
+	other := env.addUser(t, "other@example.com")
+	err := env.user.RequestPhoneChange(ctx, other.UUID, testPassword, "+15550100", testIP)
+	require.ErrorIs(t, err, ErrPhoneTaken)
+}
+
+func TestPhoneChangeCodeDoesNotConfirmNewerNumber(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	user := env.addUser(t, "user@example.com")
+
+	require.NoError(t, env.user.RequestPhoneChange(ctx, user.UUID, testPassword, "+15550100", testIP))
+	code := env.lastCode(t, "+15550100")
+
+	env.server.FastForward(testResendCooldown)
+	require.NoError(t, env.user.RequestPhoneChange(ctx, user.UUID, testPassword, "+15550199", testIP))
+
+	require.ErrorIs(t, env.user.ConfirmPhoneChange(ctx, user.UUID, code), otp.ErrOtpInvalid)
+}
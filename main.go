@@ -1,15 +1,27 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"onboarding/api"
 	"onboarding/internal/handler"
 	"onboarding/internal/repository"
+	"onboarding/internal/repository/ceremony"
+	"onboarding/internal/repository/lockout"
+	"onboarding/internal/repository/mfa"
 	otp "onboarding/internal/repository/otp"
+	"onboarding/internal/repository/permission"
+	"onboarding/internal/repository/refresh"
+	"onboarding/internal/repository/revocation"
 	"onboarding/internal/service"
 	"onboarding/pkg/config"
+	"onboarding/pkg/notify"
+	"onboarding/pkg/outbox"
+	"onboarding/pkg/passkey"
+	"onboarding/pkg/ratelimit"
 	"onboarding/pkg/storage"
+	"onboarding/pkg/templates"
 	"onboarding/pkg/token"
 )
 
@@ -31,18 +43,110 @@ func main() {
 		log.Fatalf("Couldn't create token maker: %v", err)
 	}
 
+	sessionRepo := repository.NewSessionRepository(db)
+	refreshRepo := refresh.NewRefreshRepository(redis)
+	sessionService := service.NewSessionService(sessionRepo, refreshRepo)
+	sessionHandler := handler.NewSessionHandler(sessionService)
+
 	userRepo := repository.NewUserRepository(db)
-	userService := service.NewUserService(userRepo)
+	// Messages are queued in the outbox and delivered in the background, so
+	// requests don't wait for the mail server.
+	notifier := notify.New(cfg.SMTP, cfg.SMS, cfg.Webhook)
+	outboxRepo := repository.NewOutboxRepository(db)
+	go outbox.NewWorker(outboxRepo, notifier, cfg.Outbox).Run(context.Background())
+
+	catalog, err := templates.New(cfg.Templates)
+	if err != nil {
+		log.Fatalf("Couldn't load message templates: %v", err)
+	}
+
+	otpRepo := otp.NewOtpRepository(
+		redis,
+		outbox.NewQueue(outboxRepo, notifier),
+		catalog,
+		cfg.MagicLink,
+		cfg.OTP,
+	)
+	lockoutRepo := lockout.NewLockoutRepository(redis)
+	lockoutService := service.NewLockoutService(userRepo, otpRepo, lockoutRepo, cfg.Lockout)
+	transactor := repository.NewTransactor(db)
+	userService := service.NewUserService(userRepo, otpRepo, sessionService, lockoutService, transactor)
 	userHandler := handler.NewUserHandler(userService)
 
-	otpRepo := otp.NewOtpRepository(redis, cfg.SMTP)
 	otpService := service.NewOtpService(userRepo, otpRepo)
 	forgotPasswordHandler := handler.NewForgotPasswordHandler(otpService, userService)
 
-	authService := service.NewAuthService(userRepo, jwtImpl)
-	authHandler := handler.NewAuthHandler(authService)
+	mfaRepo := mfa.NewMFARepository(redis)
+	recoveryCodeRepo := repository.NewMFARecoveryCodeRepository(db)
+	mfaService := service.NewMFAService(userRepo, recoveryCodeRepo, mfaRepo, cfg.App.Name)
+	mfaHandler := handler.NewMFAHandler(mfaService)
+
+	passkeyImpl, err := passkey.NewPasskey(cfg.WebAuthn)
+	if err != nil {
+		log.Fatalf("Couldn't create passkey verifier: %v", err)
+	}
+
+	passkeyCredentialRepo := repository.NewPasskeyCredentialRepository(db)
+	ceremonyRepo := ceremony.NewCeremonyRepository(redis)
+	passkeyService := service.NewPasskeyService(userRepo, passkeyCredentialRepo, ceremonyRepo, passkeyImpl)
+	passkeyHandler := handler.NewPasskeyHandler(passkeyService)
+
+	revocationRepo := revocation.NewRevocationRepository(redis)
+	authService := service.NewAuthService(
+		userRepo,
+		sessionRepo,
+		refreshRepo,
+		revocationRepo,
+		mfaRepo,
+		mfaService,
+		passkeyService,
+		otpService,
+		lockoutService,
+		jwtImpl,
+		transactor,
+	)
+	authHandler := handler.NewAuthHandler(authService, otpService)
+
+	jwksHandler := handler.NewJWKSHandler(jwtImpl)
+
+	roleRepo := repository.NewRoleRepository(db)
+	permissionRepo := permission.NewPermissionRepository(redis)
+	authorizer := service.NewAuthorizer(userRepo, roleRepo, permissionRepo)
+	roleService := service.NewRoleService(userRepo, roleRepo, permissionRepo)
+	roleHandler := handler.NewRoleHandler(roleService)
+	authzHandler := handler.NewAuthzHandler(authService, authorizer)
+
+	adminService := service.NewAdminService(
+		userRepo,
+		otpRepo,
+		permissionRepo,
+		sessionService,
+		lockoutService,
+	)
+	adminHandler := handler.NewAdminHandler(adminService)
+
+	outboxService := service.NewOutboxService(outboxRepo)
+	outboxHandler := handler.NewOutboxHandler(outboxService)
 
-	server := api.NewServer(cfg.App, jwtImpl, authHandler, userHandler, forgotPasswordHandler)
+	server := api.NewServer(
+		cfg.App,
+		cfg.RateLimit,
+		jwtImpl,
+		authService,
+		authorizer,
+		ratelimit.New(redis),
+		authHandler,
+		userHandler,
+		sessionHandler,
+		mfaHandler,
+		passkeyHandler,
+		forgotPasswordHandler,
+		jwksHandler,
+		roleHandler,
+		authzHandler,
+		adminHandler,
+		outboxHandler,
+	)
 	if err != nil {
 		log.Fatal("Couldn't create server: ", err)
 	}
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS sessions (
+  id bigserial NOT NULL,
+  uuid uuid NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+  user_uuid uuid NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+  user_agent varchar NOT NULL DEFAULT '',
+  ip varchar(45) NOT NULL DEFAULT '',
+  created_at timestamptz NOT NULL DEFAULT (now()),
+  last_seen_at timestamptz NOT NULL DEFAULT (now()),
+  revoked_at timestamptz,
+
+  CONSTRAINT session__pkey PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS session__uuid__idx ON sessions USING BTREE (uuid);
+
+CREATE INDEX IF NOT EXISTS session__user_uuid__idx ON sessions USING BTREE (user_uuid);
@@ -0,0 +1,3 @@
+ALTER TABLE users
+  DROP COLUMN IF EXISTS totp_secret,
+  DROP COLUMN IF EXISTS totp_enabled;
@@ -0,0 +1,3 @@
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS totp_secret varchar NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS totp_enabled boolean NOT NULL DEFAULT false;
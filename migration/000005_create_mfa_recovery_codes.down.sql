@@ -0,0 +1 @@
+DROP TABLE IF EXISTS mfa_recovery_codes;
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+  id bigserial NOT NULL,
+  user_uuid uuid NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+  code_hash varchar NOT NULL,
+  used_at timestamptz,
+  created_at timestamptz NOT NULL DEFAULT (now()),
+
+  CONSTRAINT mfa_recovery_code__pkey PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS mfa_recovery_code__user_uuid__idx ON mfa_recovery_codes USING BTREE (user_uuid);
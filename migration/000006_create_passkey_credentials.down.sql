@@ -0,0 +1 @@
+DROP TABLE IF EXISTS passkey_credentials;
\ No newline at end of file
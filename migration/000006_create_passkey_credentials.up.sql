@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS passkey_credentials (
+  id bigserial NOT NULL,
+  uuid uuid NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+  user_uuid uuid NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+  credential_id bytea NOT NULL UNIQUE,
+  credential jsonb NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT (now()),
+  last_used_at timestamptz,
+
+  CONSTRAINT passkey_credential__pkey PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS passkey_credential__user_uuid__idx ON passkey_credentials USING BTREE (user_uuid);
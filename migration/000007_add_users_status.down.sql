@@ -0,0 +1,3 @@
+ALTER TABLE users
+  DROP CONSTRAINT IF EXISTS user__status__check,
+  DROP COLUMN IF EXISTS status;
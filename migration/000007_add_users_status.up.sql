@@ -0,0 +1,7 @@
+-- Accounts created before verification existed are treated as verified.
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS status varchar(32) NOT NULL DEFAULT 'active',
+  ADD CONSTRAINT user__status__check CHECK (status IN ('pending_verification', 'active', 'suspended', 'deleted'));
+
+ALTER TABLE users
+  ALTER COLUMN status SET DEFAULT 'pending_verification';
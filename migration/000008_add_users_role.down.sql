@@ -0,0 +1,3 @@
+ALTER TABLE users
+  DROP CONSTRAINT IF EXISTS user__role__check,
+  DROP COLUMN IF EXISTS role;
@@ -0,0 +1,3 @@
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS role varchar(16) NOT NULL DEFAULT 'user',
+  ADD CONSTRAINT user__role__check CHECK (role IN ('user', 'support', 'admin'));
@@ -0,0 +1,11 @@
+ALTER TABLE users
+  DROP CONSTRAINT IF EXISTS user__role__fkey;
+
+UPDATE users SET role = 'user' WHERE role NOT IN ('user', 'support', 'admin');
+
+ALTER TABLE users
+  ADD CONSTRAINT user__role__check CHECK (role IN ('user', 'support', 'admin'));
+
+DROP TABLE IF EXISTS role_permissions;
+
+DROP TABLE IF EXISTS roles;
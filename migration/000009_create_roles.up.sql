@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS roles (
+  id bigserial NOT NULL,
+  name varchar(16) NOT NULL UNIQUE,
+  description varchar NOT NULL DEFAULT '',
+  built_in boolean NOT NULL DEFAULT false,
+  created_at timestamptz NOT NULL DEFAULT (now()),
+
+  CONSTRAINT role__pkey PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+  id bigserial NOT NULL,
+  role_name varchar(16) NOT NULL REFERENCES roles (name) ON DELETE CASCADE ON UPDATE CASCADE,
+  resource varchar(64) NOT NULL,
+  action varchar(64) NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT (now()),
+
+  CONSTRAINT role_permission__pkey PRIMARY KEY (id),
+  CONSTRAINT role_permission__role_name__resource__action__key UNIQUE (role_name, resource, action)
+);
+
+INSERT INTO roles (name, description, built_in) VALUES
+  ('user', 'Every registered user', true),
+  ('support', 'Helps users with their accounts', true),
+  ('admin', 'Manages the service', true)
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_name, resource, action) VALUES
+  ('support', 'users', 'read'),
+  ('admin', '*', '*')
+ON CONFLICT (role_name, resource, action) DO NOTHING;
+
+-- Roles are rows now, so the fixed list is replaced by a reference.
+ALTER TABLE users
+  DROP CONSTRAINT IF EXISTS user__role__check,
+  ADD CONSTRAINT user__role__fkey FOREIGN KEY (role) REFERENCES roles (name) ON UPDATE CASCADE;
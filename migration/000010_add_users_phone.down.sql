@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS user__phone__uniq;
+
+ALTER TABLE users
+  DROP CONSTRAINT IF EXISTS user__notification_channel__check,
+  DROP COLUMN IF EXISTS notification_channel,
+  DROP COLUMN IF EXISTS phone_verified_at,
+  DROP COLUMN IF EXISTS phone;
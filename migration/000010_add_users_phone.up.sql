@@ -0,0 +1,9 @@
+-- A phone number only has to be unique once it's verified, so an unverified
+-- one can't keep its owner from adding it.
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS phone varchar(16) NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS phone_verified_at timestamptz,
+  ADD COLUMN IF NOT EXISTS notification_channel varchar(16) NOT NULL DEFAULT 'email',
+  ADD CONSTRAINT user__notification_channel__check CHECK (notification_channel IN ('email', 'sms', 'webhook'));
+
+CREATE UNIQUE INDEX IF NOT EXISTS user__phone__uniq ON users USING BTREE (phone) WHERE phone_verified_at IS NOT NULL;
@@ -0,0 +1,26 @@
+-- Bodies are cleared once a message is sent, they may hold one-time codes.
+CREATE TABLE IF NOT EXISTS outbox_messages (
+  id bigserial NOT NULL,
+  uuid uuid NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+  channel varchar(16) NOT NULL,
+  recipient varchar NOT NULL,
+  subject varchar NOT NULL DEFAULT '',
+  html text NOT NULL DEFAULT '',
+  text text NOT NULL DEFAULT '',
+  status varchar(16) NOT NULL DEFAULT 'pending',
+  attempts integer NOT NULL DEFAULT 0,
+  last_error text NOT NULL DEFAULT '',
+  next_attempt_at timestamptz NOT NULL DEFAULT (now()),
+  sent_at timestamptz,
+  created_at timestamptz NOT NULL DEFAULT (now()),
+  updated_at timestamptz NOT NULL DEFAULT (now()),
+
+  CONSTRAINT outbox_message__pkey PRIMARY KEY (id),
+  CONSTRAINT outbox_message__status__check CHECK (status IN ('pending', 'sending', 'sent', 'dead'))
+);
+
+-- Workers only look for the messages that aren't done yet.
+CREATE INDEX IF NOT EXISTS outbox_message__next_attempt_at__idx ON outbox_messages USING BTREE (next_attempt_at)
+  WHERE status IN ('pending', 'sending');
+
+CREATE INDEX IF NOT EXISTS outbox_message__status__idx ON outbox_messages USING BTREE (status, created_at);
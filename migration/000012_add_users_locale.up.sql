@@ -0,0 +1,3 @@
+-- An empty locale means the user has no preference.
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS locale varchar(35) NOT NULL DEFAULT '';
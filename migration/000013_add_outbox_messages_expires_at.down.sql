@@ -0,0 +1,2 @@
+ALTER TABLE outbox_messages
+  DROP COLUMN IF EXISTS expires_at;
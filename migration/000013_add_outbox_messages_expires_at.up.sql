@@ -0,0 +1,4 @@
+-- Messages with one-time codes or links expire with them. Their bodies are
+-- cleared when they are dead-lettered, and they can't be replayed.
+ALTER TABLE outbox_messages
+  ADD COLUMN IF NOT EXISTS expires_at timestamptz;
@@ -0,0 +1,2 @@
+ALTER TABLE mfa_recovery_codes
+  DROP COLUMN IF EXISTS lookup;
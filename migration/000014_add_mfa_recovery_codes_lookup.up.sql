@@ -0,0 +1,4 @@
+-- The lookup is the start of the code, kept in clear so a code is checked
+-- against one hash. Codes made before it have an empty lookup.
+ALTER TABLE mfa_recovery_codes
+  ADD COLUMN IF NOT EXISTS lookup varchar(8) NOT NULL DEFAULT '';
@@ -0,0 +1,52 @@
+package authz
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Wildcard grants every resource or every action.
+const Wildcard = "*"
+
+// Permission allows an action on a resource, written as resource:action.
+type Permission struct {
+	Resource string
+	Action   string
+}
+
+func NewPermission(resource, action string) Permission {
+	return Permission{Resource: resource, Action: action}
+}
+
+func ParsePermission(s string) (Permission, error) {
+	resource, action, ok := strings.Cut(s, ":")
+	if !ok || resource == "" || action == "" || strings.Contains(action, ":") {
+		return Permission{}, fmt.Errorf("Permission %q isn't in resource:action form", s)
+	}
+
+	return NewPermission(resource, action), nil
+}
+
+func (p Permission) String() string {
+	return p.Resource + ":" + p.Action
+}
+
+// Allows reports whether p, as a granted permission, covers requested.
+func (p Permission) Allows(requested Permission) bool {
+	return matches(p.Resource, requested.Resource) && matches(p.Action, requested.Action)
+}
+
+// Allowed reports whether any of the granted permissions covers requested.
+func Allowed(granted []Permission, requested Permission) bool {
+	for _, p := range granted {
+		if p.Allows(requested) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matches(granted, requested string) bool {
+	return granted == Wildcard || granted == requested
+}
@@ -0,0 +1,54 @@
+package authz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePermission(t *testing.T) {
+	p, err := ParsePermission("users:read")
+	require.NoError(t, err)
+	require.Equal(t, NewPermission("users", "read"), p)
+	require.Equal(t, "users:read", p.String())
+
+	for _, s := range []string{"", "users", "users:", ":read", "users:read:all"} {
+		_, err := ParsePermission(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestPermissionAllows(t *testing.T) {
+	testCases := []struct {
+		granted   string
+		requested string
+		allowed   bool
+	}{
+		{"users:read", "users:read", true},
+		{"users:read", "users:write", false},
+		{"users:read", "roles:read", false},
+		{"users:*", "users:write", true},
+		{"*:read", "roles:read", true},
+		{"*:read", "roles:write", false},
+		{"*:*", "roles:write", true},
+	}
+
+	for _, tc := range testCases {
+		granted, err := ParsePermission(tc.granted)
+		require.NoError(t, err)
+
+		requested, err := ParsePermission(tc.requested)
+		require.NoError(t, err)
+
+		require.Equal(t, tc.allowed, granted.Allows(requested), "%s allows %s", tc.granted, tc.requested)
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	granted := []Permission{NewPermission("users", "read"), NewPermission("roles", "*")}
+
+	require.True(t, Allowed(granted, NewPermission("users", "read")))
+	require.True(t, Allowed(granted, NewPermission("roles", "write")))
+	require.False(t, Allowed(granted, NewPermission("users", "write")))
+	require.False(t, Allowed(nil, NewPermission("users", "read")))
+}
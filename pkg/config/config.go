@@ -3,32 +3,54 @@ package config
 import (
 	"encoding/base64"
 	"log"
+	"net/mail"
 	"os"
 	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	App      App
-	Database Database
-	Redis    Redis
-	SMTP     SMTP
-	Token    Token
+	App       App
+	Database  Database
+	Redis     Redis
+	SMTP      SMTP
+	Token     Token
+	WebAuthn  WebAuthn
+	MagicLink MagicLink
+	Lockout   Lockout
+	OTP       OTP
+	RateLimit RateLimit
+	SMS       SMS
+	Webhook   Webhook
+	Outbox    Outbox
+	Templates Templates
 }
 
 func NewConfig() Config {
 	return Config{
-		App:      NewApp(),
-		Database: NewDatabase(),
-		Redis:    NewRedis(),
-		SMTP:     NewSMTP(),
-		Token:    NewToken(),
+		App:       NewApp(),
+		Database:  NewDatabase(),
+		Redis:     NewRedis(),
+		SMTP:      NewSMTP(),
+		Token:     NewToken(),
+		WebAuthn:  NewWebAuthn(),
+		MagicLink: NewMagicLink(),
+		Lockout:   NewLockout(),
+		OTP:       NewOTP(),
+		RateLimit: NewRateLimit(),
+		SMS:       NewSMS(),
+		Webhook:   NewWebhook(),
+		Outbox:    NewOutbox(),
+		Templates: NewTemplates(),
 	}
 }
 
 type App struct {
+	Name    string
 	Port    string
 	Timeout time.Duration
 	GinMode string
@@ -42,6 +64,7 @@ func NewApp() App {
 	}
 
 	return App{
+		Name:    os.Getenv("APP_NAME"),
 		Port:    os.Getenv("PORT"),
 		Timeout: timeout,
 		GinMode: os.Getenv("APP_GIN_MODE"),
@@ -90,29 +113,168 @@ func NewRedis() Redis {
 	}
 }
 
+// SMTP security modes.
+const (
+	// SMTPSecurityTLS connects over TLS right away, usually on port 465.
+	SMTPSecurityTLS = "tls"
+	// SMTPSecuritySTARTTLS upgrades a plain connection, usually on port 587.
+	// The server has to support it.
+	SMTPSecuritySTARTTLS = "starttls"
+	SMTPSecurityNone     = "none"
+)
+
+// SMTP auth mechanisms.
+const (
+	SMTPAuthPlain   = "plain"
+	SMTPAuthLogin   = "login"
+	SMTPAuthCRAMMD5 = "cram-md5"
+	SMTPAuthNone    = "none"
+)
+
 type SMTP struct {
 	Host     string
 	Port     string
-	FromName string
-	Username string
-	Password string
+	Security string
+	Auth     string
+	// HeloName is the name the client greets the server with. Some servers
+	// reject the default "localhost".
+	HeloName string
+	// FromName is the display name and FromAddress the address messages are
+	// sent from. FromAddress defaults to Username.
+	FromName    string
+	FromAddress string
+	Username    string
+	Password    string
+	// PoolSize caps the connections open at once. Idle ones are kept for
+	// IdleTimeout to be reused.
+	PoolSize    int64
+	IdleTimeout time.Duration
+	// Timeout bounds connecting and every exchange with the server.
+	Timeout time.Duration
 }
 
 func NewSMTP() SMTP {
-	return SMTP{
-		Host:     os.Getenv("SMTP_HOST"),
-		Port:     os.Getenv("SMTP_PORT"),
-		FromName: os.Getenv("SMTP_FROM_NAME"),
-		Username: os.Getenv("SMTP_USERNAME"),
-		Password: os.Getenv("SMTP_PASSWORD"),
+	smtp := SMTP{
+		Host:        os.Getenv("SMTP_HOST"),
+		Port:        os.Getenv("SMTP_PORT"),
+		Security:    strings.ToLower(os.Getenv("SMTP_SECURITY")),
+		Auth:        strings.ToLower(os.Getenv("SMTP_AUTH")),
+		HeloName:    os.Getenv("SMTP_HELO_NAME"),
+		FromName:    os.Getenv("SMTP_FROM_NAME"),
+		FromAddress: os.Getenv("SMTP_FROM_ADDRESS"),
+		Username:    os.Getenv("SMTP_USERNAME"),
+		Password:    os.Getenv("SMTP_PASSWORD"),
+		PoolSize:    envInt("SMTP_POOL_SIZE", 2),
+		IdleTimeout: envDuration("SMTP_IDLE_TIMEOUT", 30*time.Second),
+		Timeout:     envDuration("SMTP_TIMEOUT", 10*time.Second),
+	}
+
+	if smtp.Security == "" {
+		smtp.Security = SMTPSecurityTLS
+	}
+
+	if smtp.Auth == "" {
+		smtp.Auth = SMTPAuthPlain
+	}
+
+	if smtp.FromAddress == "" {
+		smtp.FromAddress = smtp.Username
+	}
+
+	if smtp.HeloName == "" {
+		hostname, err := os.Hostname()
+		if err == nil {
+			smtp.HeloName = hostname
+		}
+	}
+
+	switch smtp.Security {
+	case SMTPSecurityTLS, SMTPSecuritySTARTTLS, SMTPSecurityNone:
+	default:
+		log.Fatalf("Couldn't parse SMTP security %q, expected tls, starttls or none", smtp.Security)
 	}
+
+	switch smtp.Auth {
+	case SMTPAuthPlain, SMTPAuthLogin, SMTPAuthCRAMMD5, SMTPAuthNone:
+	default:
+		log.Fatalf("Couldn't parse SMTP auth %q, expected plain, login, cram-md5 or none", smtp.Auth)
+	}
+
+	if smtp.Host != "" {
+		if _, err := mail.ParseAddress(smtp.FromAddress); err != nil {
+			log.Fatalf("Couldn't parse SMTP from address %q: %v", smtp.FromAddress, err)
+		}
+	}
+
+	return smtp
+}
+
+// SMS configures the HTTP gateway text messages are sent through. SMS is
+// off when URL is empty.
+type SMS struct {
+	URL   string
+	Token string
+	// From is the sender number or name.
+	From string
+}
+
+func NewSMS() SMS {
+	return SMS{
+		URL:   os.Getenv("SMS_PROVIDER_URL"),
+		Token: os.Getenv("SMS_PROVIDER_TOKEN"),
+		From:  os.Getenv("SMS_FROM"),
+	}
+}
+
+// Webhook configures where push notifications are POSTed to. They are off
+// when URL is empty.
+type Webhook struct {
+	URL string
+	// Secret signs the requests.
+	Secret []byte
+}
+
+func NewWebhook() Webhook {
+	webhook := Webhook{
+		URL:    os.Getenv("PUSH_WEBHOOK_URL"),
+		Secret: []byte(os.Getenv("PUSH_WEBHOOK_SECRET")),
+	}
+
+	if webhook.URL != "" && len(webhook.Secret) == 0 {
+		log.Fatal("Push webhook needs a secret")
+	}
+
+	return webhook
 }
 
 type Token struct {
 	AccessTokenDuration  time.Duration
 	RefreshTokenDuration time.Duration
+	MFATokenDuration     time.Duration
 	PublicKey            string
 	PrivateKey           string
+	// KeyID identifies the signing key pair. It's derived from the public key
+	// when empty.
+	KeyID string
+	// VerificationKeys are retired public keys that are still accepted so
+	// tokens signed before a key rotation stay valid until they expire.
+	VerificationKeys []VerificationKey
+	// Algorithm is the signing algorithm (RS256, ES256 or EdDSA). It has to
+	// match the private key and is derived from it when empty.
+	Algorithm string
+	// Algorithms lists the algorithms accepted by VerifyToken. It defaults to
+	// the algorithms of the configured keys.
+	Algorithms []string
+	Issuer     string
+	// Audience is minted into tokens by default and required by VerifyToken.
+	Audience string
+	// Leeway is the clock skew tolerated when validating exp, nbf and iat.
+	Leeway time.Duration
+}
+
+type VerificationKey struct {
+	KeyID     string
+	PublicKey string
 }
 
 func NewToken() Token {
@@ -141,12 +303,324 @@ func NewToken() Token {
 		log.Fatal("Couldn't parse Access Token Duration")
 	}
 
+	// TOKEN_VERIFICATION_KEYS holds comma separated <kid>:<base64 public key> pairs.
+	var verificationKeys []VerificationKey
+	for _, entry := range strings.Split(os.Getenv("TOKEN_VERIFICATION_KEYS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		keyID, encodedKey, ok := strings.Cut(entry, ":")
+		if !ok || keyID == "" {
+			log.Fatal("Couldn't parse verification key, expected <kid>:<base64 public key>")
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encodedKey)
+		if err != nil {
+			log.Fatalf("Couldn't encode verification key %s", keyID)
+		}
+
+		verificationKeys = append(verificationKeys, VerificationKey{
+			KeyID:     keyID,
+			PublicKey: string(key),
+		})
+	}
+
+	mfaDuration := 5 * time.Minute
+	if mfaDurationStr := os.Getenv("TOKEN_MFA_TOKEN_DURATION"); mfaDurationStr != "" {
+		mfaDuration, err = time.ParseDuration(mfaDurationStr)
+		if err != nil {
+			log.Fatal("Couldn't parse MFA Token Duration")
+		}
+	}
+
+	// Defaults to the leeway go-jose used before it was configurable.
+	leeway := time.Minute
+	if leewayStr := os.Getenv("TOKEN_LEEWAY"); leewayStr != "" {
+		leeway, err = time.ParseDuration(leewayStr)
+		if err != nil {
+			log.Fatal("Couldn't parse Leeway")
+		}
+	}
+
+	var algorithms []string
+	for _, alg := range strings.Split(os.Getenv("TOKEN_ALGORITHMS"), ",") {
+		if alg = strings.TrimSpace(alg); alg != "" {
+			algorithms = append(algorithms, alg)
+		}
+	}
+
 	return Token{
 		AccessTokenDuration:  accessDuration,
 		RefreshTokenDuration: refreshDuration,
+		MFATokenDuration:     mfaDuration,
 		PublicKey:            string(publicKey),
 		PrivateKey:           string(privateKey),
+		KeyID:                os.Getenv("TOKEN_KEY_ID"),
+		VerificationKeys:     verificationKeys,
+		Algorithm:            os.Getenv("TOKEN_ALGORITHM"),
+		Algorithms:           algorithms,
+		Issuer:               os.Getenv("TOKEN_ISSUER"),
+		Audience:             os.Getenv("TOKEN_AUDIENCE"),
+		Leeway:               leeway,
+	}
+}
+
+type WebAuthn struct {
+	// RPID is the domain passkeys are scoped to, e.g. example.com.
+	RPID          string
+	RPDisplayName string
+	// RPOrigins are the fully qualified origins ceremonies may come from.
+	RPOrigins []string
+}
+
+func NewWebAuthn() WebAuthn {
+	var origins []string
+	for _, origin := range strings.Split(os.Getenv("WEBAUTHN_RP_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+
+	displayName := os.Getenv("WEBAUTHN_RP_DISPLAY_NAME")
+	if displayName == "" {
+		displayName = os.Getenv("APP_NAME")
+	}
+
+	return WebAuthn{
+		RPID:          os.Getenv("WEBAUTHN_RP_ID"),
+		RPDisplayName: displayName,
+		RPOrigins:     origins,
+	}
+}
+
+type MagicLink struct {
+	// URL is the page the link in the e-mail points to, the token is added
+	// as the token query parameter. The page should POST the token to the
+	// consume endpoint, so link scanners fetching it don't use it up.
+	URL string
+	TTL time.Duration
+}
+
+func NewMagicLink() MagicLink {
+	ttl := 15 * time.Minute
+	if ttlStr := os.Getenv("MAGIC_LINK_TTL"); ttlStr != "" {
+		var err error
+		ttl, err = time.ParseDuration(ttlStr)
+		if err != nil {
+			log.Fatal("Couldn't parse Magic Link TTL")
+		}
 	}
+
+	return MagicLink{
+		URL: os.Getenv("MAGIC_LINK_URL"),
+		TTL: ttl,
+	}
+}
+
+type Lockout struct {
+	// MaxAttempts is the number of failed logins within Window after which
+	// an account is locked for Duration.
+	MaxAttempts int64
+	// IPMaxAttempts is the same for the logins coming from one IP address,
+	// whichever accounts they are for.
+	IPMaxAttempts int64
+	Window        time.Duration
+	Duration      time.Duration
+	// DelayAfter is the number of failures after which every further one
+	// makes the account wait before the next attempt, doubling from
+	// BaseDelay up to MaxDelay.
+	DelayAfter int64
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+func NewLockout() Lockout {
+	return Lockout{
+		MaxAttempts:   envInt("LOCKOUT_MAX_ATTEMPTS", 10),
+		IPMaxAttempts: envInt("LOCKOUT_IP_MAX_ATTEMPTS", 100),
+		Window:        envDuration("LOCKOUT_WINDOW", 15*time.Minute),
+		Duration:      envDuration("LOCKOUT_DURATION", 15*time.Minute),
+		DelayAfter:    envInt("LOCKOUT_DELAY_AFTER", 3),
+		BaseDelay:     envDuration("LOCKOUT_BASE_DELAY", time.Second),
+		MaxDelay:      envDuration("LOCKOUT_MAX_DELAY", 30*time.Second),
+	}
+}
+
+type OTP struct {
+	// ResendCooldown is how long to wait before the same code can be sent
+	// to the same address again.
+	ResendCooldown time.Duration
+	// EmailHourlyQuota and IPHourlyQuota cap the e-mails sent to one
+	// address and on behalf of one IP address per hour, over all services.
+	EmailHourlyQuota int64
+	IPHourlyQuota    int64
+	// MaxVerifyAttempts is the number of wrong guesses after which a code is
+	// burned.
+	MaxVerifyAttempts int64
+	// Length and Alphabet shape the codes, six digits by default. The
+	// alphabet is taken byte by byte, so it has to be ASCII.
+	Length   int
+	Alphabet string
+	// Secret keys the digests codes are stored as.
+	Secret []byte
+}
+
+func NewOTP() OTP {
+	secret, err := base64.StdEncoding.DecodeString(os.Getenv("OTP_SECRET"))
+	if err != nil || len(secret) < 32 {
+		log.Fatal("Couldn't decode OTP secret, expected at least 32 base64 encoded bytes")
+	}
+
+	alphabet := os.Getenv("OTP_ALPHABET")
+	if alphabet == "" {
+		alphabet = "0123456789"
+	}
+
+	length := envInt("OTP_LENGTH", 6)
+	if length < 4 || len(alphabet) < 2 || len(alphabet) > 256 {
+		log.Fatal("OTP needs a length of at least 4 and an alphabet of 2 to 256 characters")
+	}
+
+	if strings.IndexFunc(alphabet, func(r rune) bool { return r > unicode.MaxASCII }) >= 0 {
+		log.Fatal("OTP alphabet has to be ASCII")
+	}
+
+	return OTP{
+		ResendCooldown:    envDuration("OTP_RESEND_COOLDOWN", time.Minute),
+		EmailHourlyQuota:  envInt("OTP_EMAIL_HOURLY_QUOTA", 5),
+		IPHourlyQuota:     envInt("OTP_IP_HOURLY_QUOTA", 20),
+		MaxVerifyAttempts: envInt("OTP_MAX_VERIFY_ATTEMPTS", 5),
+		Length:            int(length),
+		Alphabet:          alphabet,
+		Secret:            secret,
+	}
+}
+
+type RouteLimit struct {
+	Requests int64
+	Period   time.Duration
+}
+
+// RateLimit holds the limits of the rate limited routes by name.
+type RateLimit struct {
+	Routes map[string]RouteLimit
+}
+
+func NewRateLimit() RateLimit {
+	routes := map[string]RouteLimit{
+		"login":     {Requests: 20, Period: time.Minute},
+		"register":  {Requests: 10, Period: time.Hour},
+		"otp_send":  {Requests: 10, Period: 10 * time.Minute},
+		"otp_email": {Requests: 5, Period: 10 * time.Minute},
+		"otp_user":  {Requests: 5, Period: 10 * time.Minute},
+	}
+
+	// RATE_LIMITS overrides them with comma separated
+	// <name>=<requests>/<period> entries, e.g. login=20/1m.
+	for _, entry := range strings.Split(os.Getenv("RATE_LIMITS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(entry, "=")
+		requestsStr, periodStr, ok2 := strings.Cut(value, "/")
+		if !ok || !ok2 {
+			log.Fatalf("Couldn't parse rate limit %s, expected <name>=<requests>/<period>", entry)
+		}
+
+		requests, err := strconv.ParseInt(requestsStr, 10, 64)
+		if err != nil || requests < 1 {
+			log.Fatalf("Couldn't parse requests of rate limit %s", name)
+		}
+
+		period, err := time.ParseDuration(periodStr)
+		if err != nil || period <= 0 {
+			log.Fatalf("Couldn't parse period of rate limit %s", name)
+		}
+
+		routes[name] = RouteLimit{Requests: requests, Period: period}
+	}
+
+	return RateLimit{Routes: routes}
+}
+
+// Outbox configures the workers delivering queued messages.
+type Outbox struct {
+	Workers      int64
+	PollInterval time.Duration
+	// Lease is how long a worker has to deliver a message it claimed before
+	// another one may pick it up.
+	Lease time.Duration
+	// MaxAttempts is the number of failed deliveries after which a message
+	// is dead-lettered. Retries wait BaseDelay, doubling up to MaxDelay.
+	MaxAttempts int64
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+func NewOutbox() Outbox {
+	return Outbox{
+		Workers:      envInt("OUTBOX_WORKERS", 4),
+		PollInterval: envDuration("OUTBOX_POLL_INTERVAL", time.Second),
+		Lease:        envDuration("OUTBOX_LEASE", time.Minute),
+		MaxAttempts:  envInt("OUTBOX_MAX_ATTEMPTS", 8),
+		BaseDelay:    envDuration("OUTBOX_BASE_DELAY", 10*time.Second),
+		MaxDelay:     envDuration("OUTBOX_MAX_DELAY", time.Hour),
+	}
+}
+
+// Templates configures the message templates. The built-in ones are used
+// when Dir is empty.
+type Templates struct {
+	Dir string
+	// DefaultLocale is used when none of the recipient's locales has a
+	// template.
+	DefaultLocale string
+}
+
+func NewTemplates() Templates {
+	defaultLocale := os.Getenv("TEMPLATES_DEFAULT_LOCALE")
+	if defaultLocale == "" {
+		defaultLocale = "en"
+	}
+
+	return Templates{
+		Dir:           os.Getenv("TEMPLATES_DIR"),
+		DefaultLocale: defaultLocale,
+	}
+}
+
+// envInt reads an optional integer variable.
+func envInt(key string, fallback int64) int64 {
+	str := os.Getenv(key)
+	if str == "" {
+		return fallback
+	}
+
+	value, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		log.Fatalf("Couldn't parse %s", key)
+	}
+
+	return value
+}
+
+// envDuration reads an optional duration variable.
+func envDuration(key string, fallback time.Duration) time.Duration {
+	str := os.Getenv(key)
+	if str == "" {
+		return fallback
+	}
+
+	value, err := time.ParseDuration(str)
+	if err != nil {
+		log.Fatalf("Couldn't parse %s", key)
+	}
+
+	return value
 }
 
 func LoadConfig() Config {
@@ -0,0 +1,115 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"onboarding/pkg/config"
+	"strings"
+	"time"
+)
+
+type EmailNotifier struct {
+	cfg  config.SMTP
+	pool *smtpPool
+}
+
+func NewEmailNotifier(cfg config.SMTP) *EmailNotifier {
+	return &EmailNotifier{cfg: cfg, pool: newSMTPPool(cfg)}
+}
+
+func (n *EmailNotifier) Notify(ctx context.Context, msg Message) error {
+	body, err := buildEmail(n.cfg, msg.To, msg.Subject, msg.Text, msg.HTML, time.Now())
+	if err != nil {
+		return fmt.Errorf("build email: %w", err)
+	}
+
+	if err := n.pool.send(ctx, n.cfg.FromAddress, msg.To, body); err != nil {
+		return fmt.Errorf("send email: %w", err)
+	}
+
+	return nil
+}
+
+// buildEmail builds a multipart/alternative message with the text part
+// first, so clients showing HTML prefer the last one.
+func buildEmail(
+	cfg config.SMTP,
+	to string,
+	subject string,
+	textBody string,
+	htmlBody string,
+	date time.Time,
+) ([]byte, error) {
+	messageID, err := newMessageID(cfg.FromAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=\"UTF-8\"", textBody},
+		{"text/html; charset=\"UTF-8\"", htmlBody},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	from := mail.Address{Name: cfg.FromName, Address: cfg.FromAddress}
+	msg.WriteString("From: " + from.String() + "\r\n")
+	msg.WriteString("To: " + (&mail.Address{Address: to}).String() + "\r\n")
+	msg.WriteString("Date: " + date.Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("Message-ID: " + messageID + "\r\n")
+	msg.WriteString("Subject: " + mime.QEncoding.Encode("UTF-8", subject) + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: multipart/alternative; boundary=\"" + parts.Boundary() + "\"\r\n")
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+// newMessageID makes a unique Message-ID in the domain of the sender.
+func newMessageID(from string) (string, error) {
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = from[at+1:]
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	return "<" + hex.EncodeToString(id) + "@" + domain + ">", nil
+}
@@ -0,0 +1,113 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"onboarding/pkg/config"
+	"strconv"
+	"time"
+)
+
+const (
+	// SignatureHeader carries the hex HMAC-SHA256 of the timestamp, a dot
+	// and the body of a webhook request, keyed with the webhook secret.
+	SignatureHeader = "X-Signature"
+	TimestampHeader = "X-Timestamp"
+)
+
+// SMSNotifier sends text messages through an HTTP SMS gateway, which is
+// POSTed {"from", "to", "body"} with the token as bearer.
+type SMSNotifier struct {
+	cfg    config.SMS
+	client *http.Client
+}
+
+func NewSMSNotifier(cfg config.SMS, client *http.Client) *SMSNotifier {
+	return &SMSNotifier{cfg: cfg, client: client}
+}
+
+func (n *SMSNotifier) Notify(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(map[string]string{
+		"from": n.cfg.From,
+		"to":   msg.To,
+		"body": msg.Text,
+	})
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+n.cfg.Token)
+
+	return post(ctx, n.client, n.cfg.URL, header, body)
+}
+
+// WebhookNotifier POSTs messages to a service of ours that turns them into
+// push notifications. Requests are signed so it can tell they came from us.
+type WebhookNotifier struct {
+	cfg    config.Webhook
+	client *http.Client
+	now    func() time.Time
+}
+
+func NewWebhookNotifier(cfg config.Webhook, client *http.Client) *WebhookNotifier {
+	return &WebhookNotifier{cfg: cfg, client: client, now: time.Now}
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(map[string]string{
+		"to":      msg.To,
+		"subject": msg.Subject,
+		"body":    msg.Text,
+	})
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(n.now().Unix(), 10)
+
+	header := http.Header{}
+	header.Set(TimestampHeader, timestamp)
+	header.Set(SignatureHeader, Sign(n.cfg.Secret, timestamp, body))
+
+	return post(ctx, n.client, n.cfg.URL, header, body)
+}
+
+// Sign computes the signature of a webhook request.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func post(ctx context.Context, client *http.Client, url string, header http.Header, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header = header
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("provider request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("provider responded %d: %s", res.StatusCode, detail)
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+// Package notify delivers messages to users over e-mail, SMS or a webhook
+// that forwards them as push notifications.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"onboarding/pkg/config"
+	"time"
+)
+
+type Channel string
+
+const (
+	ChannelEmail   Channel = "email"
+	ChannelSMS     Channel = "sms"
+	ChannelWebhook Channel = "webhook"
+)
+
+// providerTimeout bounds the requests to the SMS and webhook providers.
+const providerTimeout = 10 * time.Second
+
+var ErrChannelUnavailable = errors.New("notification channel is not configured")
+
+type Message struct {
+	Channel Channel
+	// To is the address on the channel: an e-mail address, an E.164 phone
+	// number, or the user UUID for webhooks.
+	To      string
+	Subject string
+	// HTML is the body of e-mails, Text the body of the channels without
+	// markup.
+	HTML string
+	Text string
+	// ExpiresAt is when a code or link in the message runs out, so it's no
+	// use delivering it later. Zero never expires.
+	ExpiresAt time.Time
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// Sender is a Notifier that tells which channels it can deliver over.
+type Sender interface {
+	Notifier
+	Supports(channel Channel) bool
+}
+
+// Dispatcher hands messages to the notifier of their channel.
+type Dispatcher struct {
+	notifiers map[Channel]Notifier
+}
+
+func NewDispatcher(notifiers map[Channel]Notifier) *Dispatcher {
+	return &Dispatcher{notifiers: notifiers}
+}
+
+// New sets up e-mail and whichever of SMS and webhooks are configured.
+func New(smtpCfg config.SMTP, smsCfg config.SMS, webhookCfg config.Webhook) *Dispatcher {
+	client := &http.Client{Timeout: providerTimeout}
+	notifiers := map[Channel]Notifier{
+		ChannelEmail: NewEmailNotifier(smtpCfg),
+	}
+
+	if smsCfg.URL != "" {
+		notifiers[ChannelSMS] = NewSMSNotifier(smsCfg, client)
+	}
+
+	if webhookCfg.URL != "" {
+		notifiers[ChannelWebhook] = NewWebhookNotifier(webhookCfg, client)
+	}
+
+	return NewDispatcher(notifiers)
+}
+
+// Supports tells whether messages can be sent over the channel.
+func (d *Dispatcher) Supports(channel Channel) bool {
+	_, ok := d.notifiers[channel]
+	return ok
+}
+
+func (d *Dispatcher) Notify(ctx context.Context, msg Message) error {
+	notifier, ok := d.notifiers[msg.Channel]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrChannelUnavailable, msg.Channel)
+	}
+
+	return notifier.Notify(ctx, msg)
+}
@@ -0,0 +1,168 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/mail"
+	"onboarding/pkg/config"
+	"onboarding/pkg/notify/notifytest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSMSNotifier(t *testing.T) {
+	provider := notifytest.NewProvider(t)
+	n := NewSMSNotifier(config.SMS{URL: provider.URL(), Token: "secret-token", From: "Onboarding"}, provider.Client())
+
+	err := n.Notify(context.Background(), Message{
+		Channel: ChannelSMS,
+		To:      "+15550100",
+		Subject: "Sign In",
+		HTML:    "<p>ignored</p>",
+		Text:    "Your code is 123456",
+	})
+	require.NoError(t, err)
+
+	requests := provider.Requests()
+	require.Len(t, requests, 1)
+	require.Equal(t, "Bearer secret-token", requests[0].Header.Get("Authorization"))
+	require.Equal(t, "application/json", requests[0].Header.Get("Content-Type"))
+
+	var body map[string]string
+	requests[0].Decode(t, &body)
+	require.Equal(t, map[string]string{
+		"from": "Onboarding",
+		"to":   "+15550100",
+		"body": "Your code is 123456",
+	}, body)
+}
+
+func TestWebhookNotifierSignsRequests(t *testing.T) {
+	provider := notifytest.NewProvider(t)
+	secret := []byte("webhook-secret")
+	n := NewWebhookNotifier(config.Webhook{URL: provider.URL(), Secret: secret}, provider.Client())
+	n.now = func() time.Time { return time.Unix(1700000000, 0) }
+
+	err := n.Notify(context.Background(), Message{
+		Channel: ChannelWebhook,
+		To:      "c0ffee00-0000-4000-8000-000000000000",
+		Subject: "Sign In",
+		Text:    "Your code is 123456",
+	})
+	require.NoError(t, err)
+
+	requests := provider.Requests()
+	require.Len(t, requests, 1)
+
+	timestamp := requests[0].Header.Get(TimestampHeader)
+	require.Equal(t, strconv.FormatInt(1700000000, 10), timestamp)
+	require.Equal(t, Sign(secret, timestamp, requests[0].Body), requests[0].Header.Get(SignatureHeader))
+	require.NotEqual(t, Sign([]byte("other"), timestamp, requests[0].Body), requests[0].Header.Get(SignatureHeader))
+
+	var body map[string]string
+	requests[0].Decode(t, &body)
+	require.Equal(t, "Sign In", body["subject"])
+	require.Equal(t, "Your code is 123456", body["body"])
+}
+
+func TestProviderFailure(t *testing.T) {
+	provider := notifytest.NewProvider(t)
+	provider.SetStatus(http.StatusServiceUnavailable)
+	n := NewSMSNotifier(config.SMS{URL: provider.URL()}, provider.Client())
+
+	err := n.Notify(context.Background(), Message{Channel: ChannelSMS, To: "+15550100", Text: "hi"})
+	require.ErrorContains(t, err, "503")
+}
+
+func TestDispatcherRoutesByChannel(t *testing.T) {
+	provider := notifytest.NewProvider(t)
+	d := NewDispatcher(map[Channel]Notifier{
+		ChannelSMS: NewSMSNotifier(config.SMS{URL: provider.URL()}, provider.Client()),
+	})
+
+	require.True(t, d.Supports(ChannelSMS))
+	require.False(t, d.Supports(ChannelWebhook))
+
+	require.NoError(t, d.Notify(context.Background(), Message{Channel: ChannelSMS, To: "+15550100", Text: "hi"}))
+	require.Len(t, provider.Requests(), 1)
+
+	err := d.Notify(context.Background(), Message{Channel: ChannelWebhook, To: "someone", Text: "hi"})
+	require.ErrorIs(t, err, ErrChannelUnavailable)
+}
+
+func TestNewOnlySetsUpConfiguredChannels(t *testing.T) {
+	d := New(config.SMTP{}, config.SMS{}, config.Webhook{})
+	require.True(t, d.Supports(ChannelEmail))
+	require.False(t, d.Supports(ChannelSMS))
+	require.False(t, d.Supports(ChannelWebhook))
+
+	d = New(config.SMTP{}, config.SMS{URL: "http://sms.local"}, config.Webhook{URL: "http://push.local"})
+	require.True(t, d.Supports(ChannelSMS))
+	require.True(t, d.Supports(ChannelWebhook))
+}
+
+func TestBuildEmail(t *testing.T) {
+	date := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	raw, err := buildEmail(
+		config.SMTP{FromName: "Onboarding Team – Ünïcode", FromAddress: "noreply@example.com"},
+		"user@example.com",
+		"Ihr Code zum Zurücksetzen",
+		"Verwenden Sie 123456.",
+		"<p>Verwenden Sie <b>123456</b>.</p>",
+		date,
+	)
+	require.NoError(t, err)
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	require.NoError(t, err)
+
+	from, err := msg.Header.AddressList("From")
+	require.NoError(t, err)
+	require.Equal(t, []*mail.Address{{Name: "Onboarding Team – Ünïcode", Address: "noreply@example.com"}}, from)
+
+	to, err := msg.Header.AddressList("To")
+	require.NoError(t, err)
+	require.Equal(t, "user@example.com", to[0].Address)
+
+	sentAt, err := msg.Header.Date()
+	require.NoError(t, err)
+	require.True(t, date.Equal(sentAt))
+
+	require.Regexp(t, `^<[0-9a-f]{32}@example\.com>$`, msg.Header.Get("Message-ID"))
+	require.Equal(t, "1.0", msg.Header.Get("MIME-Version"))
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	require.Equal(t, "Ihr Code zum Zurücksetzen", subject)
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/alternative", mediaType)
+
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	var types, bodies []string
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		// The reader undoes the quoted-printable encoding.
+		body, err := io.ReadAll(part)
+		require.NoError(t, err)
+
+		types = append(types, part.Header.Get("Content-Type"))
+		bodies = append(bodies, string(body))
+	}
+
+	require.Equal(t, []string{`text/plain; charset="UTF-8"`, `text/html; charset="UTF-8"`}, types)
+	require.Equal(t, []string{"Verwenden Sie 123456.", "<p>Verwenden Sie <b>123456</b>.</p>"}, bodies)
+}
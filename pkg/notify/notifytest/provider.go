@@ -0,0 +1,85 @@
+// Package notifytest provides a local stand-in for the SMS and push
+// providers, so notifications can be tested without sending any.
+package notifytest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// Request is a request the provider received.
+type Request struct {
+	Header http.Header
+	Body   []byte
+}
+
+// Decode unmarshals the JSON body into v.
+func (r Request) Decode(t testing.TB, v any) {
+	t.Helper()
+
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		t.Fatalf("Couldn't decode provider request: %v", err)
+	}
+}
+
+// Provider records every request it receives and answers with Status.
+type Provider struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	requests []Request
+	status   int
+}
+
+// NewProvider starts a provider that is closed when the test ends.
+func NewProvider(t testing.TB) *Provider {
+	p := &Provider{status: http.StatusAccepted}
+	p.server = httptest.NewServer(http.HandlerFunc(p.serve))
+	t.Cleanup(p.server.Close)
+
+	return p
+}
+
+func (p *Provider) URL() string {
+	return p.server.URL
+}
+
+func (p *Provider) Client() *http.Client {
+	return p.server.Client()
+}
+
+// SetStatus makes the provider answer with the status code, e.g. to test
+// failed deliveries.
+func (p *Provider) SetStatus(status int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.status = status
+}
+
+// Requests returns the requests received so far.
+func (p *Provider) Requests() []Request {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]Request(nil), p.requests...)
+}
+
+func (p *Provider) serve(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	p.mu.Lock()
+	p.requests = append(p.requests, Request{Header: r.Header.Clone(), Body: body})
+	status := p.status
+	p.mu.Unlock()
+
+	w.WriteHeader(status)
+}
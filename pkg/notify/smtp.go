@@ -0,0 +1,304 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"onboarding/pkg/config"
+	"sync"
+	"time"
+)
+
+// smtpPool keeps up to cfg.PoolSize connections to the SMTP server and
+// reuses the idle ones, so a burst of messages doesn't log in for each. It's
+// safe for concurrent use.
+type smtpPool struct {
+	cfg       config.SMTP
+	tlsConfig *tls.Config
+	// slots holds a token for every connection in use.
+	slots chan struct{}
+
+	mu   sync.Mutex
+	idle []*smtpConn
+}
+
+type smtpConn struct {
+	conn      net.Conn
+	client    *smtp.Client
+	idleSince time.Time
+}
+
+func newSMTPPool(cfg config.SMTP) *smtpPool {
+	size := cfg.PoolSize
+	if size < 1 {
+		size = 1
+	}
+
+	return &smtpPool{
+		cfg:       cfg,
+		tlsConfig: &tls.Config{ServerName: cfg.Host},
+		slots:     make(chan struct{}, size),
+	}
+}
+
+// send delivers body from the envelope sender to the recipient. A connection
+// is only put back when the server is still in a state to take the next
+// message.
+func (p *smtpPool) send(ctx context.Context, from string, to string, body []byte) error {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-p.slots }()
+
+	c, err := p.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Cancelling ctx interrupts whatever exchange is under way.
+	stop := context.AfterFunc(ctx, func() { c.conn.SetDeadline(time.Now()) })
+	err = c.send(from, to, body)
+	if !stop() {
+		c.client.Close()
+		return errors.Join(err, ctx.Err())
+	}
+
+	if err != nil {
+		// The server refused the message but the session is fine as long as
+		// it takes a reset.
+		var protocolErr *textproto.Error
+		if errors.As(err, &protocolErr) && c.client.Reset() == nil {
+			p.put(c)
+		} else {
+			c.client.Close()
+		}
+		return err
+	}
+
+	p.put(c)
+	return nil
+}
+
+// get returns an idle connection that still answers, or dials a new one.
+func (p *smtpPool) get(ctx context.Context) (*smtpConn, error) {
+	for {
+		c := p.popIdle()
+		if c == nil {
+			return p.dial(ctx)
+		}
+
+		if err := p.setDeadline(ctx, c.conn); err != nil {
+			c.client.Close()
+			return nil, err
+		}
+
+		if time.Since(c.idleSince) > p.cfg.IdleTimeout {
+			p.quit(c)
+			continue
+		}
+
+		// The server may have dropped the connection while it was idle.
+		if err := c.client.Noop(); err != nil {
+			c.client.Close()
+			continue
+		}
+
+		return c, nil
+	}
+}
+
+func (p *smtpPool) popIdle() *smtpConn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.idle) == 0 {
+		return nil
+	}
+
+	c := p.idle[len(p.idle)-1]
+	p.idle = p.idle[:len(p.idle)-1]
+	return c
+}
+
+func (p *smtpPool) put(c *smtpConn) {
+	c.conn.SetDeadline(time.Time{})
+	c.idleSince = time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.idle = append(p.idle, c)
+}
+
+// quit ends the session politely, and closes the connection anyway when the
+// server doesn't answer.
+func (p *smtpPool) quit(c *smtpConn) {
+	if err := c.client.Quit(); err != nil {
+		log.Printf("SMTP quit error: %v", err)
+		c.client.Close()
+	}
+}
+
+func (p *smtpPool) dial(ctx context.Context) (*smtpConn, error) {
+	addr := net.JoinHostPort(p.cfg.Host, p.cfg.Port)
+	dialer := &net.Dialer{Timeout: p.cfg.Timeout}
+
+	var conn net.Conn
+	var err error
+	if p.cfg.Security == config.SMTPSecurityTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: p.tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("smtp dial error: %w", err)
+	}
+
+	if err := p.setDeadline(ctx, conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// Cancelling ctx interrupts the handshake.
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, p.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("smtp client error: %w", err)
+	}
+
+	if err := p.handshake(client); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return &smtpConn{conn: conn, client: client}, nil
+}
+
+func (p *smtpPool) handshake(client *smtp.Client) error {
+	if p.cfg.HeloName != "" {
+		if err := client.Hello(p.cfg.HeloName); err != nil {
+			return fmt.Errorf("smtp hello error: %w", err)
+		}
+	}
+
+	if p.cfg.Security == config.SMTPSecuritySTARTTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server doesn't support STARTTLS")
+		}
+
+		if err := client.StartTLS(p.tlsConfig); err != nil {
+			return fmt.Errorf("smtp starttls error: %w", err)
+		}
+	}
+
+	auth := p.auth()
+	if auth == nil {
+		return nil
+	}
+
+	if err := client.Auth(auth); err != nil {
+		return fmt.Errorf("smtp auth error: %w", err)
+	}
+
+	return nil
+}
+
+func (p *smtpPool) auth() smtp.Auth {
+	switch p.cfg.Auth {
+	case config.SMTPAuthLogin:
+		return &loginAuth{username: p.cfg.Username, password: p.cfg.Password, host: p.cfg.Host}
+	case config.SMTPAuthCRAMMD5:
+		return smtp.CRAMMD5Auth(p.cfg.Username, p.cfg.Password)
+	case config.SMTPAuthNone:
+		return nil
+	default:
+		return smtp.PlainAuth("", p.cfg.Username, p.cfg.Password, p.cfg.Host)
+	}
+}
+
+// setDeadline bounds the next exchange by cfg.Timeout and the deadline of
+// ctx, whichever comes first.
+func (p *smtpPool) setDeadline(ctx context.Context, conn net.Conn) error {
+	var deadline time.Time
+	if p.cfg.Timeout > 0 {
+		deadline = time.Now().Add(p.cfg.Timeout)
+	}
+
+	if d, ok := ctx.Deadline(); ok && (deadline.IsZero() || d.Before(deadline)) {
+		deadline = d
+	}
+
+	return conn.SetDeadline(deadline)
+}
+
+func (c *smtpConn) send(from string, to string, body []byte) error {
+	if err := c.client.Mail(from); err != nil {
+		return err
+	}
+
+	if err := c.client.Rcpt(to); err != nil {
+		return err
+	}
+
+	w, err := c.client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(body); err != nil {
+		w.Close()
+		return err
+	}
+
+	return w.Close()
+}
+
+// loginAuth is the LOGIN mechanism, which some providers offer instead of
+// PLAIN. Like smtp.PlainAuth it only sends the password over TLS or to
+// localhost.
+type loginAuth struct {
+	username string
+	password string
+	host     string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	switch string(fromServer) {
+	case "Username:", "User Name\x00":
+		return []byte(a.username), nil
+	case "Password:", "Password\x00":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected server challenge %q", fromServer)
+	}
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
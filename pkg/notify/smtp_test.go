@@ -0,0 +1,329 @@
+package notify
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"math/big"
+	"net"
+	"net/textproto"
+	"onboarding/pkg/config"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type receivedEmail struct {
+	from string
+	to   string
+	data string
+	tls  bool
+}
+
+// smtpServer is an SMTP server on localhost that takes every message,
+// except those to reject, from username with password.
+type smtpServer struct {
+	listener  net.Listener
+	tlsConfig *tls.Config
+	username  string
+	password  string
+	reject    string
+	// closeAfterMessage drops the connection once a message is in.
+	closeAfterMessage bool
+
+	mu       sync.Mutex
+	accepted int
+	received []receivedEmail
+}
+
+func newSMTPServer(t *testing.T, options ...func(s *smtpServer)) *smtpServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	s := &smtpServer{listener: listener, username: "mailer", password: "secret"}
+	for _, option := range options {
+		option(s)
+	}
+	go s.serve()
+
+	return s
+}
+
+func (s *smtpServer) config() config.SMTP {
+	_, port, _ := net.SplitHostPort(s.listener.Addr().String())
+
+	return config.SMTP{
+		Host:        "127.0.0.1",
+		Port:        port,
+		Security:    config.SMTPSecurityNone,
+		Auth:        config.SMTPAuthPlain,
+		HeloName:    "test.example.com",
+		FromName:    "Onboarding",
+		FromAddress: "noreply@example.com",
+		Username:    s.username,
+		Password:    s.password,
+		PoolSize:    2,
+		IdleTimeout: time.Minute,
+		Timeout:     time.Second,
+	}
+}
+
+func (s *smtpServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.accepted++
+		s.mu.Unlock()
+
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpServer) handle(conn net.Conn) {
+	defer func() { conn.Close() }()
+
+	text := textproto.NewConn(conn)
+	reply := func(line string) { text.PrintfLine("%s", line) }
+	isTLS := false
+	authenticated := false
+	var from, to string
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			text.PrintfLine("250-localhost")
+			if s.tlsConfig != nil && !isTLS {
+				text.PrintfLine("250-STARTTLS")
+			}
+			reply("250 AUTH PLAIN LOGIN")
+		case "STARTTLS":
+			reply("220 Ready")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			text = textproto.NewConn(conn)
+			isTLS = true
+		case "AUTH":
+			authenticated = s.authenticate(text, arg)
+			if !authenticated {
+				reply("535 Authentication failed")
+				continue
+			}
+			reply("235 Authenticated")
+		case "MAIL":
+			if !authenticated {
+				reply("530 Authentication required")
+				continue
+			}
+			from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			reply("250 OK")
+		case "RCPT":
+			to = strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>")
+			if to == s.reject {
+				reply("550 No such user")
+				continue
+			}
+			reply("250 OK")
+		case "DATA":
+			reply("354 Go ahead")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.received = append(s.received, receivedEmail{from: from, to: to, data: string(data), tls: isTLS})
+			s.mu.Unlock()
+			reply("250 Queued")
+			if s.closeAfterMessage {
+				return
+			}
+		case "RSET", "NOOP":
+			from, to = "", ""
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Unknown command")
+		}
+	}
+}
+
+func (s *smtpServer) authenticate(text *textproto.Conn, arg string) bool {
+	mechanism, initial, _ := strings.Cut(arg, " ")
+	switch mechanism {
+	case "PLAIN":
+		decoded, err := base64.StdEncoding.DecodeString(initial)
+		return err == nil && string(decoded) == "\x00"+s.username+"\x00"+s.password
+	case "LOGIN":
+		var answers []string
+		for _, challenge := range []string{"Username:", "Password:"} {
+			text.PrintfLine("334 %s", base64.StdEncoding.EncodeToString([]byte(challenge)))
+			line, err := text.ReadLine()
+			if err != nil {
+				return false
+			}
+			decoded, err := base64.StdEncoding.DecodeString(line)
+			if err != nil {
+				return false
+			}
+			answers = append(answers, string(decoded))
+		}
+		return answers[0] == s.username && answers[1] == s.password
+	default:
+		return false
+	}
+}
+
+func (s *smtpServer) connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.accepted
+}
+
+func (s *smtpServer) messages() []receivedEmail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]receivedEmail(nil), s.received...)
+}
+
+// selfSignedTLS returns the server side of a certificate for 127.0.0.1 and a
+// client config trusting it.
+func selfSignedTLS(t *testing.T) (*tls.Config, *tls.Config) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	server := &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	client := &tls.Config{ServerName: "127.0.0.1", RootCAs: roots}
+	return server, client
+}
+
+func sendTestEmail(t *testing.T, n *EmailNotifier, to string) error {
+	t.Helper()
+
+	return n.Notify(context.Background(), Message{
+		Channel: ChannelEmail,
+		To:      to,
+		Subject: "Your code",
+		Text:    "Use 123456.",
+		HTML:    "<p>Use <b>123456</b>.</p>",
+	})
+}
+
+func TestEmailNotifierReusesConnection(t *testing.T) {
+	server := newSMTPServer(t)
+	n := NewEmailNotifier(server.config())
+
+	require.NoError(t, sendTestEmail(t, n, "first@example.com"))
+	require.NoError(t, sendTestEmail(t, n, "second@example.com"))
+
+	require.Equal(t, 1, server.connections())
+
+	messages := server.messages()
+	require.Len(t, messages, 2)
+	require.Equal(t, "noreply@example.com", messages[0].from)
+	require.Equal(t, "first@example.com", messages[0].to)
+	require.Equal(t, "second@example.com", messages[1].to)
+	require.Contains(t, messages[0].data, `From: "Onboarding" <noreply@example.com>`)
+}
+
+func TestEmailNotifierStartTLS(t *testing.T) {
+	serverTLS, clientTLS := selfSignedTLS(t)
+	server := newSMTPServer(t, func(s *smtpServer) { s.tlsConfig = serverTLS })
+
+	cfg := server.config()
+	cfg.Security = config.SMTPSecuritySTARTTLS
+	cfg.Auth = config.SMTPAuthLogin
+
+	n := NewEmailNotifier(cfg)
+	n.pool.tlsConfig = clientTLS
+
+	require.NoError(t, sendTestEmail(t, n, "user@example.com"))
+
+	messages := server.messages()
+	require.Len(t, messages, 1)
+	require.True(t, messages[0].tls)
+}
+
+func TestEmailNotifierRequiresStartTLS(t *testing.T) {
+	server := newSMTPServer(t)
+
+	cfg := server.config()
+	cfg.Security = config.SMTPSecuritySTARTTLS
+
+	err := sendTestEmail(t, NewEmailNotifier(cfg), "user@example.com")
+	require.ErrorContains(t, err, "STARTTLS")
+	require.Empty(t, server.messages())
+}
+
+func TestEmailNotifierWrongPassword(t *testing.T) {
+	server := newSMTPServer(t)
+
+	cfg := server.config()
+	cfg.Password = "wrong"
+
+	err := sendTestEmail(t, NewEmailNotifier(cfg), "user@example.com")
+	require.ErrorContains(t, err, "smtp auth error")
+}
+
+func TestEmailNotifierKeepsConnectionAfterRejection(t *testing.T) {
+	server := newSMTPServer(t, func(s *smtpServer) { s.reject = "unknown@example.com" })
+	n := NewEmailNotifier(server.config())
+
+	require.Error(t, sendTestEmail(t, n, "unknown@example.com"))
+	require.NoError(t, sendTestEmail(t, n, "user@example.com"))
+
+	require.Equal(t, 1, server.connections())
+	require.Len(t, server.messages(), 1)
+}
+
+func TestEmailNotifierRedialsDroppedConnection(t *testing.T) {
+	server := newSMTPServer(t, func(s *smtpServer) { s.closeAfterMessage = true })
+	n := NewEmailNotifier(server.config())
+
+	require.NoError(t, sendTestEmail(t, n, "first@example.com"))
+	require.NoError(t, sendTestEmail(t, n, "second@example.com"))
+
+	require.Equal(t, 2, server.connections())
+	require.Len(t, server.messages(), 2)
+}
@@ -0,0 +1,195 @@
+// Package outbox queues messages so they are delivered in the background,
+// retried with backoff when a channel fails and dead-lettered when retries
+// run out.
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"onboarding/pkg/config"
+	"onboarding/pkg/notify"
+	"sync"
+	"time"
+)
+
+// errExpired is the cause recorded for messages that expired before they
+// could be delivered.
+const errExpired = "expired before it was delivered"
+
+// Message is a queued notification as handed to a worker.
+type Message struct {
+	ID           int64
+	Notification notify.Message
+	// Attempts counts the deliveries tried so far, the current one included.
+	Attempts int64
+}
+
+type Store interface {
+	Enqueue(ctx context.Context, msg notify.Message) error
+	// Claim leases up to limit messages that are due, and counts an attempt
+	// for each. A message whose lease runs out is due again, so one lost with
+	// its worker is still delivered.
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]Message, error)
+	MarkSent(ctx context.Context, id int64) error
+	// MarkFailed records why the delivery failed. The message is retried at
+	// retryAt, or dead-lettered when retryAt is nil. A dead message that
+	// expires loses its bodies.
+	MarkFailed(ctx context.Context, id int64, cause string, retryAt *time.Time) error
+}
+
+// Queue is a notify.Sender that enqueues messages instead of delivering
+// them. Messages are only accepted for the channels sender supports.
+type Queue struct {
+	store  Store
+	sender notify.Sender
+}
+
+func NewQueue(store Store, sender notify.Sender) *Queue {
+	return &Queue{store: store, sender: sender}
+}
+
+func (q *Queue) Supports(channel notify.Channel) bool {
+	return q.sender.Supports(channel)
+}
+
+func (q *Queue) Notify(ctx context.Context, msg notify.Message) error {
+	if !q.sender.Supports(msg.Channel) {
+		return fmt.Errorf("%w: %s", notify.ErrChannelUnavailable, msg.Channel)
+	}
+
+	if err := q.store.Enqueue(ctx, msg); err != nil {
+		return fmt.Errorf("enqueue message: %w", err)
+	}
+
+	return nil
+}
+
+// Worker delivers the queued messages with a pool of goroutines.
+type Worker struct {
+	store    Store
+	notifier notify.Notifier
+	cfg      config.Outbox
+	now      func() time.Time
+}
+
+func NewWorker(store Store, notifier notify.Notifier, cfg config.Outbox) *Worker {
+	return &Worker{store: store, notifier: notifier, cfg: cfg, now: time.Now}
+}
+
+// Run delivers messages until ctx is done, and waits for the deliveries in
+// flight before it returns.
+func (w *Worker) Run(ctx context.Context) {
+	jobs := make(chan Message)
+
+	var wg sync.WaitGroup
+	for range w.cfg.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for msg := range jobs {
+				w.deliver(ctx, msg)
+			}
+		}()
+	}
+
+	defer func() {
+		close(jobs)
+		wg.Wait()
+	}()
+
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// A full batch means more messages may be due, so the next one is
+		// claimed right away.
+		full := w.dispatch(ctx, jobs)
+		if full && ctx.Err() == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatch claims a batch of messages as large as the pool and hands them to
+// the workers. It tells whether the batch was full.
+func (w *Worker) dispatch(ctx context.Context, jobs chan<- Message) bool {
+	limit := int(w.cfg.Workers)
+	messages, err := w.store.Claim(ctx, limit, w.cfg.Lease)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Outbox claim failed: %v", err)
+		}
+		return false
+	}
+
+	for _, msg := range messages {
+		select {
+		case jobs <- msg:
+		case <-ctx.Done():
+			// Unsent messages are picked up again once their lease is over.
+			return false
+		}
+	}
+
+	return len(messages) == limit
+}
+
+func (w *Worker) deliver(ctx context.Context, msg Message) {
+	if expiresAt := msg.Notification.ExpiresAt; !expiresAt.IsZero() && !w.now().Before(expiresAt) {
+		if err := w.store.MarkFailed(ctx, msg.ID, errExpired, nil); err != nil {
+			log.Printf("Outbox message %d expired but not marked: %v", msg.ID, err)
+		}
+		return
+	}
+
+	// A delivery can't outlast its lease, or the message could be sent twice.
+	sendCtx, cancel := context.WithTimeout(ctx, w.cfg.Lease)
+	defer cancel()
+
+	sendErr := w.notifier.Notify(sendCtx, msg.Notification)
+	if sendErr == nil {
+		// The message is marked even when shutting down, so it isn't sent
+		// again.
+		if err := w.store.MarkSent(context.WithoutCancel(ctx), msg.ID); err != nil {
+			log.Printf("Outbox message %d was sent but not marked: %v", msg.ID, err)
+		}
+		return
+	}
+
+	if ctx.Err() != nil {
+		// Shutting down isn't the channel's fault. The message is claimed
+		// again once the lease is over.
+		return
+	}
+
+	var retryAt *time.Time
+	if msg.Attempts < w.cfg.MaxAttempts {
+		at := w.now().Add(Backoff(w.cfg, msg.Attempts))
+		retryAt = &at
+	}
+
+	if err := w.store.MarkFailed(ctx, msg.ID, sendErr.Error(), retryAt); err != nil {
+		log.Printf("Outbox message %d failed (%v) but not marked: %v", msg.ID, sendErr, err)
+	}
+}
+
+// Backoff is how long to wait after the attempts-th failed delivery. It
+// doubles from BaseDelay with every failure, up to MaxDelay.
+func Backoff(cfg config.Outbox, attempts int64) time.Duration {
+	delay := cfg.BaseDelay
+	for range attempts - 1 {
+		if delay >= cfg.MaxDelay {
+			break
+		}
+		delay *= 2
+	}
+
+	return min(delay, cfg.MaxDelay)
+}
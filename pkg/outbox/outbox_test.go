@@ -0,0 +1,253 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"onboarding/pkg/config"
+	"onboarding/pkg/notify"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type storedMessage struct {
+	Message
+	sent    bool
+	dead    bool
+	cause   string
+	retryAt *time.Time
+}
+
+// memoryStore keeps the queue in memory. A message is due while it has no
+// retryAt, tests clear it to make a retry due.
+type memoryStore struct {
+	mu       sync.Mutex
+	messages []*storedMessage
+}
+
+func (s *memoryStore) Enqueue(ctx context.Context, msg notify.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages = append(s.messages, &storedMessage{
+		Message: Message{ID: int64(len(s.messages) + 1), Notification: msg},
+	})
+	return nil
+}
+
+func (s *memoryStore) Claim(ctx context.Context, limit int, lease time.Duration) ([]Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var claimed []Message
+	for _, m := range s.messages {
+		if len(claimed) == limit {
+			break
+		}
+		if m.sent || m.dead || m.retryAt != nil {
+			continue
+		}
+		m.Attempts++
+		// Claimed messages aren't due again until they are marked.
+		leased := time.Now().Add(lease)
+		m.retryAt = &leased
+		claimed = append(claimed, m.Message)
+	}
+
+	return claimed, nil
+}
+
+func (s *memoryStore) MarkSent(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages[id-1].sent = true
+	return nil
+}
+
+func (s *memoryStore) MarkFailed(ctx context.Context, id int64, cause string, retryAt *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := s.messages[id-1]
+	m.cause = cause
+	m.retryAt = retryAt
+	m.dead = retryAt == nil
+	return nil
+}
+
+func (s *memoryStore) get(id int64) storedMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return *s.messages[id-1]
+}
+
+type fakeNotifier struct {
+	mu       sync.Mutex
+	err      error
+	channels map[notify.Channel]bool
+	sent     []notify.Message
+}
+
+func (n *fakeNotifier) Supports(channel notify.Channel) bool {
+	return n.channels[channel]
+}
+
+func (n *fakeNotifier) Notify(ctx context.Context, msg notify.Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.err != nil {
+		return n.err
+	}
+	n.sent = append(n.sent, msg)
+	return nil
+}
+
+func (n *fakeNotifier) count() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return len(n.sent)
+}
+
+var testConfig = config.Outbox{
+	Workers:      2,
+	PollInterval: 10 * time.Millisecond,
+	Lease:        time.Minute,
+	MaxAttempts:  3,
+	BaseDelay:    time.Second,
+	MaxDelay:     3 * time.Second,
+}
+
+func TestQueueOnlyAcceptsSupportedChannels(t *testing.T) {
+	store := &memoryStore{}
+	q := NewQueue(store, &fakeNotifier{channels: map[notify.Channel]bool{notify.ChannelEmail: true}})
+
+	require.True(t, q.Supports(notify.ChannelEmail))
+	require.False(t, q.Supports(notify.ChannelSMS))
+
+	err := q.Notify(context.Background(), notify.Message{Channel: notify.ChannelEmail, To: "user@example.com"})
+	require.NoError(t, err)
+
+	err = q.Notify(context.Background(), notify.Message{Channel: notify.ChannelSMS, To: "+15550100"})
+	require.ErrorIs(t, err, notify.ErrChannelUnavailable)
+
+	require.Len(t, store.messages, 1)
+	require.Equal(t, "user@example.com", store.messages[0].Notification.To)
+}
+
+func TestWorkerDeliversQueuedMessages(t *testing.T) {
+	store := &memoryStore{}
+	for range 5 {
+		require.NoError(t, store.Enqueue(context.Background(), notify.Message{Channel: notify.ChannelEmail}))
+	}
+
+	notifier := &fakeNotifier{}
+	w := NewWorker(store, notifier, testConfig)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		w.Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool { return notifier.count() == 5 }, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+
+	for id := range int64(5) {
+		m := store.get(id + 1)
+		require.True(t, m.sent)
+		require.Equal(t, int64(1), m.Attempts)
+	}
+}
+
+func TestWorkerRetriesThenDeadLetters(t *testing.T) {
+	store := &memoryStore{}
+	require.NoError(t, store.Enqueue(context.Background(), notify.Message{Channel: notify.ChannelEmail}))
+
+	now := time.Unix(0, 0)
+	w := NewWorker(store, &fakeNotifier{err: errors.New("connection refused")}, testConfig)
+	w.now = func() time.Time { return now }
+
+	for attempt := range testConfig.MaxAttempts {
+		messages, err := store.Claim(context.Background(), 1, testConfig.Lease)
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+
+		w.deliver(context.Background(), messages[0])
+
+		m := store.get(1)
+		require.Equal(t, "connection refused", m.cause)
+		require.False(t, m.sent)
+
+		if attempt+1 < testConfig.MaxAttempts {
+			require.False(t, m.dead)
+			require.Equal(t, now.Add(Backoff(testConfig, attempt+1)), *m.retryAt)
+			// Due again.
+			store.messages[0].retryAt = nil
+			continue
+		}
+
+		require.True(t, m.dead)
+		require.Nil(t, m.retryAt)
+	}
+}
+
+func TestWorkerLeavesMessageOnShutdown(t *testing.T) {
+	store := &memoryStore{}
+	require.NoError(t, store.Enqueue(context.Background(), notify.Message{Channel: notify.ChannelEmail}))
+
+	messages, err := store.Claim(context.Background(), 1, testConfig.Lease)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	w := NewWorker(store, &fakeNotifier{err: context.Canceled}, testConfig)
+	w.deliver(ctx, messages[0])
+
+	// The failure isn't recorded, the message stays leased.
+	m := store.get(1)
+	require.Empty(t, m.cause)
+	require.False(t, m.dead)
+	require.NotNil(t, m.retryAt)
+}
+
+func TestWorkerDeadLettersExpiredMessages(t *testing.T) {
+	now := time.Unix(1000, 0)
+	store := &memoryStore{}
+	require.NoError(t, store.Enqueue(context.Background(), notify.Message{
+		Channel:   notify.ChannelEmail,
+		ExpiresAt: now.Add(-time.Second),
+	}))
+
+	messages, err := store.Claim(context.Background(), 1, testConfig.Lease)
+	require.NoError(t, err)
+
+	notifier := &fakeNotifier{}
+	w := NewWorker(store, notifier, testConfig)
+	w.now = func() time.Time { return now }
+	w.deliver(context.Background(), messages[0])
+
+	// The code in it is no use anymore, so it isn't sent or retried.
+	require.Zero(t, notifier.count())
+	m := store.get(1)
+	require.True(t, m.dead)
+	require.Equal(t, errExpired, m.cause)
+}
+
+func TestBackoff(t *testing.T) {
+	cfg := config.Outbox{BaseDelay: 10 * time.Second, MaxDelay: time.Minute}
+
+	require.Equal(t, 10*time.Second, Backoff(cfg, 1))
+	require.Equal(t, 20*time.Second, Backoff(cfg, 2))
+	require.Equal(t, 40*time.Second, Backoff(cfg, 3))
+	require.Equal(t, time.Minute, Backoff(cfg, 4))
+	require.Equal(t, time.Minute, Backoff(cfg, 100))
+}
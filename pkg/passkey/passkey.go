@@ -0,0 +1,140 @@
+package passkey
+
+import (
+	"errors"
+	"fmt"
+	"onboarding/pkg/config"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+)
+
+// ceremonyTimeout is how long the browser gets to complete a registration or
+// login ceremony. It's enforced on the server too.
+const ceremonyTimeout = 5 * time.Minute
+
+var ErrCloneWarning = errors.New("Authenticator signature counter went backwards, it may have been cloned")
+
+// User adapts an account to webauthn.User. The account UUID is used as the
+// user handle, so a discoverable credential resolves straight to the account.
+type User struct {
+	UUID        uuid.UUID
+	Name        string
+	Credentials []webauthn.Credential
+}
+
+func (u *User) WebAuthnID() []byte {
+	return u.UUID[:]
+}
+
+func (u *User) WebAuthnName() string {
+	return u.Name
+}
+
+func (u *User) WebAuthnDisplayName() string {
+	return u.Name
+}
+
+func (u *User) WebAuthnCredentials() []webauthn.Credential {
+	return u.Credentials
+}
+
+// UserFinder loads the account a discoverable credential belongs to.
+type UserFinder func(userUUID uuid.UUID) (*User, error)
+
+type Passkey interface {
+	BeginRegistration(user *User) (*protocol.CredentialCreation, *webauthn.SessionData, error)
+	FinishRegistration(
+		user *User,
+		session webauthn.SessionData,
+		response *protocol.ParsedCredentialCreationData,
+	) (*webauthn.Credential, error)
+	BeginLogin() (*protocol.CredentialAssertion, *webauthn.SessionData, error)
+	FinishLogin(
+		session webauthn.SessionData,
+		response *protocol.ParsedCredentialAssertionData,
+		findUser UserFinder,
+	) (*User, *webauthn.Credential, error)
+}
+
+type IPasskey struct {
+	webAuthn *webauthn.WebAuthn
+}
+
+func NewPasskey(cfg config.WebAuthn) (Passkey, error) {
+	timeout := webauthn.TimeoutConfig{
+		Enforce:    true,
+		Timeout:    ceremonyTimeout,
+		TimeoutUVD: ceremonyTimeout,
+	}
+
+	w, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.RPID,
+		RPDisplayName: cfg.RPDisplayName,
+		RPOrigins:     cfg.RPOrigins,
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			ResidentKey:      protocol.ResidentKeyRequirementRequired,
+			UserVerification: protocol.VerificationRequired,
+		},
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        timeout,
+			Registration: timeout,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't configure WebAuthn: %w", err)
+	}
+
+	return &IPasskey{webAuthn: w}, nil
+}
+
+// BeginRegistration asks for a discoverable credential and excludes the ones
+// the user already registered, so the same authenticator isn't added twice.
+func (p *IPasskey) BeginRegistration(user *User) (*protocol.CredentialCreation, *webauthn.SessionData, error) {
+	return p.webAuthn.BeginRegistration(
+		user,
+		webauthn.WithExclusions(webauthn.Credentials(user.Credentials).CredentialDescriptors()),
+	)
+}
+
+func (p *IPasskey) FinishRegistration(
+	user *User,
+	session webauthn.SessionData,
+	response *protocol.ParsedCredentialCreationData,
+) (*webauthn.Credential, error) {
+	return p.webAuthn.CreateCredential(user, session, response)
+}
+
+// BeginLogin starts a discoverable login: no user is known yet, the
+// authenticator tells which account the credential belongs to.
+func (p *IPasskey) BeginLogin() (*protocol.CredentialAssertion, *webauthn.SessionData, error) {
+	return p.webAuthn.BeginDiscoverableLogin()
+}
+
+func (p *IPasskey) FinishLogin(
+	session webauthn.SessionData,
+	response *protocol.ParsedCredentialAssertionData,
+	findUser UserFinder,
+) (*User, *webauthn.Credential, error) {
+	handler := func(rawID, userHandle []byte) (webauthn.User, error) {
+		userUUID, err := uuid.FromBytes(userHandle)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user handle: %w", err)
+		}
+
+		return findUser(userUUID)
+	}
+
+	user, credential, err := p.webAuthn.ValidatePasskeyLogin(handler, session, response)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if credential.Authenticator.CloneWarning {
+		return nil, nil, ErrCloneWarning
+	}
+
+	return user.(*User), credential, nil
+}
@@ -0,0 +1,291 @@
+package passkey
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"onboarding/pkg/config"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testRPID   = "example.com"
+	testOrigin = "https://example.com"
+)
+
+// softwareAuthenticator plays the part of a platform authenticator holding a
+// single discoverable ES256 credential.
+type softwareAuthenticator struct {
+	t            *testing.T
+	rpID         string
+	origin       string
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+func newSoftwareAuthenticator(t *testing.T, origin string) *softwareAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	credentialID := make([]byte, 32)
+	_, err = rand.Read(credentialID)
+	require.NoError(t, err)
+
+	return &softwareAuthenticator{
+		t:            t,
+		rpID:         testRPID,
+		origin:       origin,
+		key:          key,
+		credentialID: credentialID,
+	}
+}
+
+func (a *softwareAuthenticator) clientData(ceremony string, challenge string) []byte {
+	clientData, err := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": challenge,
+		"origin":    a.origin,
+	})
+	require.NoError(a.t, err)
+
+	return clientData
+}
+
+// authenticatorData has the user present and user verified flags set, and the
+// attested credential data flag when a credential is attached.
+func (a *softwareAuthenticator) authenticatorData(attestedCredential []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+
+	flags := protocol.FlagUserPresent | protocol.FlagUserVerified
+	if attestedCredential != nil {
+		flags |= protocol.FlagAttestedCredentialData
+	}
+
+	data := append(rpIDHash[:], byte(flags))
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+
+	return append(data, attestedCredential...)
+}
+
+func (a *softwareAuthenticator) create(options *protocol.CredentialCreation) []byte {
+	a.userHandle = options.Response.User.ID.(protocol.URLEncodedBase64)
+
+	ecdhKey, err := a.key.PublicKey.ECDH()
+	require.NoError(a.t, err)
+	point := ecdhKey.Bytes()
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: point[1:33],
+		YCoord: point[33:],
+	})
+	require.NoError(a.t, err)
+
+	attestedCredential := make([]byte, 16)
+	attestedCredential = binary.BigEndian.AppendUint16(attestedCredential, uint16(len(a.credentialID)))
+	attestedCredential = append(attestedCredential, a.credentialID...)
+	attestedCredential = append(attestedCredential, publicKey...)
+
+	attestationObject, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": a.authenticatorData(attestedCredential),
+	})
+	require.NoError(a.t, err)
+
+	return a.credentialJSON(map[string]string{
+		"clientDataJSON":    encode(a.clientData("webauthn.create", options.Response.Challenge.String())),
+		"attestationObject": encode(attestationObject),
+	})
+}
+
+func (a *softwareAuthenticator) get(options *protocol.CredentialAssertion) []byte {
+	a.signCount++
+
+	clientData := a.clientData("webauthn.get", options.Response.Challenge.String())
+	authData := a.authenticatorData(nil)
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(authData, clientDataHash[:]...))
+
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	require.NoError(a.t, err)
+
+	return a.credentialJSON(map[string]string{
+		"clientDataJSON":    encode(clientData),
+		"authenticatorData": encode(authData),
+		"signature":         encode(signature),
+		"userHandle":        encode(a.userHandle),
+	})
+}
+
+func (a *softwareAuthenticator) credentialJSON(response map[string]string) []byte {
+	body, err := json.Marshal(map[string]any{
+		"id":       encode(a.credentialID),
+		"rawId":    encode(a.credentialID),
+		"type":     "public-key",
+		"response": response,
+	})
+	require.NoError(a.t, err)
+
+	return body
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func newTestPasskey(t *testing.T) Passkey {
+	p, err := NewPasskey(config.WebAuthn{
+		RPID:          testRPID,
+		RPDisplayName: "Onboarding",
+		RPOrigins:     []string{testOrigin},
+	})
+	require.NoError(t, err)
+
+	return p
+}
+
+func register(t *testing.T, p Passkey, user *User, authenticator *softwareAuthenticator) (*webauthn.Credential, error) {
+	options, session, err := p.BeginRegistration(user)
+	require.NoError(t, err)
+	require.Equal(t, protocol.URLEncodedBase64(user.UUID[:]), options.Response.User.ID)
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(authenticator.create(options))
+	require.NoError(t, err)
+
+	return p.FinishRegistration(user, *session, parsed)
+}
+
+func login(t *testing.T, p Passkey, user *User, authenticator *softwareAuthenticator) (*User, *webauthn.Credential, error) {
+	options, session, err := p.BeginLogin()
+	require.NoError(t, err)
+	require.Empty(t, options.Response.AllowedCredentials)
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(authenticator.get(options))
+	require.NoError(t, err)
+
+	return p.FinishLogin(*session, parsed, func(userUUID uuid.UUID) (*User, error) {
+		if userUUID != user.UUID {
+			return nil, errors.New("user not found")
+		}
+		return user, nil
+	})
+}
+
+func TestPasskeyRegistrationAndLogin(t *testing.T) {
+	p := newTestPasskey(t)
+	authenticator := newSoftwareAuthenticator(t, testOrigin)
+	user := &User{UUID: uuid.New(), Name: "user@example.com"}
+
+	credential, err := register(t, p, user, authenticator)
+	require.NoError(t, err)
+	require.Equal(t, authenticator.credentialID, credential.ID)
+	require.True(t, credential.Flags.UserVerified)
+
+	user.Credentials = append(user.Credentials, *credential)
+
+	loggedIn, used, err := login(t, p, user, authenticator)
+	require.NoError(t, err)
+	require.Equal(t, user.UUID, loggedIn.UUID)
+	require.Equal(t, credential.ID, used.ID)
+	require.Equal(t, uint32(1), used.Authenticator.SignCount)
+}
+
+func TestPasskeyRegistrationExcludesExistingCredentials(t *testing.T) {
+	p := newTestPasskey(t)
+	authenticator := newSoftwareAuthenticator(t, testOrigin)
+	user := &User{UUID: uuid.New(), Name: "user@example.com"}
+
+	credential, err := register(t, p, user, authenticator)
+	require.NoError(t, err)
+	user.Credentials = append(user.Credentials, *credential)
+
+	options, _, err := p.BeginRegistration(user)
+	require.NoError(t, err)
+	require.Len(t, options.Response.CredentialExcludeList, 1)
+	require.Equal(t, protocol.URLEncodedBase64(credential.ID), options.Response.CredentialExcludeList[0].CredentialID)
+}
+
+func TestPasskeyRegistrationWrongOrigin(t *testing.T) {
+	p := newTestPasskey(t)
+	authenticator := newSoftwareAuthenticator(t, "https://evil.example")
+	user := &User{UUID: uuid.New(), Name: "user@example.com"}
+
+	credential, err := register(t, p, user, authenticator)
+	require.Error(t, err)
+	require.Nil(t, credential)
+}
+
+func TestPasskeyLoginWrongChallenge(t *testing.T) {
+	p := newTestPasskey(t)
+	authenticator := newSoftwareAuthenticator(t, testOrigin)
+	user := &User{UUID: uuid.New(), Name: "user@example.com"}
+
+	credential, err := register(t, p, user, authenticator)
+	require.NoError(t, err)
+	user.Credentials = append(user.Credentials, *credential)
+
+	options, _, err := p.BeginLogin()
+	require.NoError(t, err)
+
+	_, otherSession, err := p.BeginLogin()
+	require.NoError(t, err)
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(authenticator.get(options))
+	require.NoError(t, err)
+
+	_, _, err = p.FinishLogin(*otherSession, parsed, func(uuid.UUID) (*User, error) {
+		return user, nil
+	})
+	require.Error(t, err)
+}
+
+func TestPasskeyLoginUnknownUser(t *testing.T) {
+	p := newTestPasskey(t)
+	authenticator := newSoftwareAuthenticator(t, testOrigin)
+	user := &User{UUID: uuid.New(), Name: "user@example.com"}
+
+	_, err := register(t, p, user, authenticator)
+	require.NoError(t, err)
+
+	// The credential was never stored for the user.
+	_, _, err = login(t, p, &User{UUID: uuid.New()}, authenticator)
+	require.Error(t, err)
+
+	_, _, err = login(t, p, user, authenticator)
+	require.Error(t, err)
+}
+
+func TestPasskeyLoginCloneWarning(t *testing.T) {
+	p := newTestPasskey(t)
+	authenticator := newSoftwareAuthenticator(t, testOrigin)
+	user := &User{UUID: uuid.New(), Name: "user@example.com"}
+
+	credential, err := register(t, p, user, authenticator)
+	require.NoError(t, err)
+
+	credential.Authenticator.SignCount = 10
+	user.Credentials = append(user.Credentials, *credential)
+
+	_, _, err = login(t, p, user, authenticator)
+	require.ErrorIs(t, err, ErrCloneWarning)
+}
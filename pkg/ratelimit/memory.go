@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often counters of idle keys are dropped.
+const sweepInterval = time.Minute
+
+type counter struct {
+	index    int64
+	previous int64
+	current  int64
+	period   time.Duration
+}
+
+// MemoryLimiter keeps the counters in the process, for single node deploys
+// and tests.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	counters  map[string]*counter
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		counters: make(map[string]*counter),
+		now:      time.Now,
+	}
+}
+
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := l.now()
+	w := windowAt(now, limit.Period)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	c, ok := l.counters[key]
+	if !ok || c.period != limit.Period {
+		c = &counter{index: w.index, period: limit.Period}
+		l.counters[key] = c
+	}
+	c.advance(w.index)
+
+	weighted := float64(c.previous)*w.weight(limit.Period) + float64(c.current)
+	allowed := weighted < float64(limit.Requests)
+	if allowed {
+		c.current++
+	}
+
+	return result(allowed, c.previous, c.current, w, limit), nil
+}
+
+// advance moves the counter to the window with the given index.
+func (c *counter) advance(index int64) {
+	switch index - c.index {
+	case 0:
+		return
+	case 1:
+		c.previous = c.current
+	default:
+		c.previous = 0
+	}
+
+	c.current = 0
+	c.index = index
+}
+
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, c := range l.counters {
+		if windowAt(now, c.period).index-c.index > 1 {
+			delete(l.counters, key)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Package ratelimit counts requests with a sliding window: the count of the
+// previous fixed window is weighted by how much of it still overlaps the
+// window ending now and added to the count of the current one. It's as cheap
+// as fixed windows without letting twice the limit through at their edges.
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"math"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Limit allows Requests per Period.
+type Limit struct {
+	Requests int64
+	Period   time.Duration
+}
+
+// Result describes the state of a key after a request was counted, or
+// turned away.
+type Result struct {
+	Allowed   bool
+	Limit     int64
+	Remaining int64
+	// Reset is when the current window ends.
+	Reset time.Duration
+	// RetryAfter is how long a turned away client should wait before the
+	// next request is allowed.
+	RetryAfter time.Duration
+}
+
+type Limiter interface {
+	// Allow counts a request for the key unless it's over the limit.
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// New limits with Redis, falling back to counting in memory when client is
+// nil or Redis fails. The fallback only sees the requests of this node.
+func New(client *redis.Client) Limiter {
+	memory := NewMemoryLimiter()
+	if client == nil {
+		return memory
+	}
+
+	return &fallbackLimiter{
+		primary:  NewRedisLimiter(client),
+		fallback: memory,
+	}
+}
+
+type fallbackLimiter struct {
+	primary  Limiter
+	fallback Limiter
+}
+
+func (l *fallbackLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	result, err := l.primary.Allow(ctx, key, limit)
+	if err == nil {
+		return result, nil
+	}
+
+	log.Printf("Rate limiter falling back to memory: %v", err)
+	return l.fallback.Allow(ctx, key, limit)
+}
+
+// window locates now within the fixed windows of the limit.
+type window struct {
+	index   int64
+	elapsed time.Duration
+}
+
+func windowAt(now time.Time, period time.Duration) window {
+	nanos := now.UnixNano()
+	return window{
+		index:   nanos / int64(period),
+		elapsed: time.Duration(nanos % int64(period)),
+	}
+}
+
+// weight is the share of the previous window still inside the sliding one.
+func (w window) weight(period time.Duration) float64 {
+	return 1 - float64(w.elapsed)/float64(period)
+}
+
+// result works out the headers from the counts of the previous and current
+// windows, the current one including the request if it was allowed.
+func result(allowed bool, previous, current int64, w window, limit Limit) Result {
+	weighted := float64(previous)*w.weight(limit.Period) + float64(current)
+	res := Result{
+		Allowed:   allowed,
+		Limit:     limit.Requests,
+		Remaining: max(0, limit.Requests-int64(math.Ceil(weighted))),
+		Reset:     limit.Period - w.elapsed,
+	}
+
+	if !allowed {
+		res.RetryAfter = retryAfter(previous, current, w, limit)
+	}
+
+	return res
+}
+
+// retryAfter is how long until the weighted count drops below the limit.
+func retryAfter(previous, current int64, w window, limit Limit) time.Duration {
+	requests := float64(limit.Requests)
+	period := float64(limit.Period)
+
+	// The previous window slides out far enough before the current one ends.
+	var at float64
+	if current < limit.Requests && previous > 0 {
+		at = (1 - (requests-float64(current))/float64(previous)) * period
+	} else {
+		// Otherwise the current window becomes the previous one and has to
+		// slide out.
+		at = period + max(0, 1-requests/float64(current))*period
+	}
+
+	// The weighted count has to drop strictly below the limit.
+	return time.Duration(math.Floor(at)) + 1 - w.elapsed
+}
@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLimiter(start time.Time) (*MemoryLimiter, *time.Time) {
+	now := start
+	l := NewMemoryLimiter()
+	l.now = func() time.Time { return now }
+
+	return l, &now
+}
+
+func TestMemoryLimiterAllowsUpToLimit(t *testing.T) {
+	start := time.Unix(0, 0).Add(time.Hour)
+	l, _ := newTestLimiter(start)
+	limit := Limit{Requests: 3, Period: time.Minute}
+
+	for i := range 3 {
+		res, err := l.Allow(context.Background(), "key", limit)
+		require.NoError(t, err)
+		require.True(t, res.Allowed)
+		require.Equal(t, int64(2-i), res.Remaining)
+		require.Equal(t, int64(3), res.Limit)
+		require.Equal(t, time.Minute, res.Reset)
+	}
+
+	res, err := l.Allow(context.Background(), "key", limit)
+	require.NoError(t, err)
+	require.False(t, res.Allowed)
+	require.Zero(t, res.Remaining)
+	require.Greater(t, res.RetryAfter, time.Duration(0))
+
+	// Other keys have their own count.
+	res, err = l.Allow(context.Background(), "other", limit)
+	require.NoError(t, err)
+	require.True(t, res.Allowed)
+}
+
+func TestMemoryLimiterSlidingWindow(t *testing.T) {
+	start := time.Unix(0, 0).Add(time.Hour)
+	l, now := newTestLimiter(start)
+	limit := Limit{Requests: 4, Period: time.Minute}
+
+	for range 4 {
+		res, err := l.Allow(context.Background(), "key", limit)
+		require.NoError(t, err)
+		require.True(t, res.Allowed)
+	}
+
+	// A quarter into the next window three quarters of the previous one
+	// still count, so only one request is let through.
+	*now = start.Add(time.Minute + 15*time.Second)
+
+	res, err := l.Allow(context.Background(), "key", limit)
+	require.NoError(t, err)
+	require.True(t, res.Allowed)
+
+	res, err = l.Allow(context.Background(), "key", limit)
+	require.NoError(t, err)
+	require.False(t, res.Allowed)
+
+	// Two windows later nothing counts anymore.
+	*now = start.Add(3 * time.Minute)
+
+	res, err = l.Allow(context.Background(), "key", limit)
+	require.NoError(t, err)
+	require.True(t, res.Allowed)
+	require.Equal(t, int64(3), res.Remaining)
+}
+
+func TestMemoryLimiterRetryAfter(t *testing.T) {
+	start := time.Unix(0, 0).Add(time.Hour)
+	l, now := newTestLimiter(start)
+	limit := Limit{Requests: 2, Period: time.Minute}
+
+	for _, elapsed := range []time.Duration{0, 10 * time.Second, 50 * time.Second, 70 * time.Second} {
+		*now = start.Add(elapsed)
+
+		for {
+			res, err := l.Allow(context.Background(), "key", limit)
+			require.NoError(t, err)
+			if res.Allowed {
+				continue
+			}
+
+			// Waiting less than RetryAfter isn't enough, waiting for it is.
+			*now = now.Add(res.RetryAfter - time.Millisecond)
+			res, err = l.Allow(context.Background(), "key", limit)
+			require.NoError(t, err)
+			require.False(t, res.Allowed)
+
+			*now = now.Add(time.Millisecond)
+			res, err = l.Allow(context.Background(), "key", limit)
+			require.NoError(t, err)
+			require.True(t, res.Allowed)
+			break
+		}
+	}
+}
+
+func TestMemoryLimiterSweepsIdleKeys(t *testing.T) {
+	start := time.Unix(0, 0).Add(time.Hour)
+	l, now := newTestLimiter(start)
+	limit := Limit{Requests: 1, Period: time.Second}
+
+	_, err := l.Allow(context.Background(), "idle", limit)
+	require.NoError(t, err)
+
+	*now = start.Add(2 * sweepInterval)
+	_, err = l.Allow(context.Background(), "active", limit)
+	require.NoError(t, err)
+
+	require.NotContains(t, l.counters, "idle")
+	require.Contains(t, l.counters, "active")
+}
+
+func TestNewWithoutRedisUsesMemory(t *testing.T) {
+	require.IsType(t, &MemoryLimiter{}, New(nil))
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// allowScript counts the request in KEYS[1], the current window, unless the
+// weighted count with KEYS[2], the previous window, is at the limit. It
+// returns whether the request was allowed and both counts.
+var allowScript = redis.NewScript(`
+local current = tonumber(redis.call("GET", KEYS[1]) or "0")
+local previous = tonumber(redis.call("GET", KEYS[2]) or "0")
+
+if previous * tonumber(ARGV[1]) + current >= tonumber(ARGV[2]) then
+	return {0, previous, current}
+end
+
+current = redis.call("INCR", KEYS[1])
+redis.call("PEXPIRE", KEYS[1], ARGV[3])
+
+return {1, previous, current}
+`)
+
+// RedisLimiter shares the counters between every node. The windows are
+// taken from the clock of the node, so the nodes' clocks should agree.
+type RedisLimiter struct {
+	redis *redis.Client
+	now   func() time.Time
+}
+
+func NewRedisLimiter(redis *redis.Client) *RedisLimiter {
+	return &RedisLimiter{redis: redis, now: time.Now}
+}
+
+func (l *RedisLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	w := windowAt(l.now(), limit.Period)
+
+	keys := []string{
+		fmt.Sprintf("ratelimit:%s:%d", key, w.index),
+		fmt.Sprintf("ratelimit:%s:%d", key, w.index-1),
+	}
+	args := []any{
+		strconv.FormatFloat(w.weight(limit.Period), 'f', -1, 64),
+		limit.Requests,
+		// The current window is still read as the previous one during the
+		// next window.
+		(2 * limit.Period).Milliseconds(),
+	}
+
+	values, err := allowScript.Run(ctx, l.redis, keys, args...).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("redis error: %w", err)
+	}
+
+	return result(values[0] == 1, values[1], values[2], w, limit), nil
+}
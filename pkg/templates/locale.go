@@ -0,0 +1,37 @@
+package templates
+
+import (
+	"context"
+
+	"golang.org/x/text/language"
+)
+
+type localesKey struct{}
+
+// WithLocales keeps the locales a client prefers, e.g. from its
+// Accept-Language header, for the messages sent on its behalf.
+func WithLocales(ctx context.Context, locales ...string) context.Context {
+	return context.WithValue(ctx, localesKey{}, locales)
+}
+
+// Locales returns the locales kept by WithLocales.
+func Locales(ctx context.Context) []string {
+	locales, _ := ctx.Value(localesKey{}).([]string)
+	return locales
+}
+
+// ParseAcceptLanguage returns the locales of an Accept-Language header, most
+// preferred first.
+func ParseAcceptLanguage(header string) []string {
+	tags, _, err := language.ParseAcceptLanguage(header)
+	if err != nil {
+		return nil
+	}
+
+	locales := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		locales = append(locales, tag.String())
+	}
+
+	return locales
+}
@@ -0,0 +1,226 @@
+// Package templates renders the messages sent to users from templates kept
+// per locale, so they can be branded and translated without a code change.
+//
+// Every locale is a directory named by its BCP 47 tag. A message is a pair
+// of files, <name>.txt and <name>.html. The text one also defines the
+// "subject" template. Files starting with an underscore are partials, parsed
+// along with every message of their locale and format.
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"onboarding/pkg/config"
+	"os"
+	"path"
+	"strings"
+	texttemplate "text/template"
+
+	"golang.org/x/text/language"
+)
+
+// The partials start with an underscore, which embed leaves out without all:.
+//
+//go:embed all:default
+var defaultFS embed.FS
+
+var ErrTemplateNotFound = errors.New("message template not found")
+
+// Rendered is a message ready to send.
+type Rendered struct {
+	Locale  string
+	Subject string
+	HTML    string
+	Text    string
+}
+
+type message struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+// Catalog holds the parsed templates of every locale. It's safe for
+// concurrent use.
+type Catalog struct {
+	fallback string
+	locales  []string
+	matcher  language.Matcher
+	messages map[string]map[string]message
+}
+
+// New loads the templates from cfg.Dir, or the built-in ones when it's
+// empty.
+func New(cfg config.Templates) (*Catalog, error) {
+	if cfg.Dir != "" {
+		return Load(os.DirFS(cfg.Dir), cfg.DefaultLocale)
+	}
+
+	fsys, err := fs.Sub(defaultFS, "default")
+	if err != nil {
+		return nil, err
+	}
+
+	return Load(fsys, cfg.DefaultLocale)
+}
+
+// Load parses every template in fsys up front. Messages in a locale that
+// isn't the fallback one may be missing, the fallback locale is used for
+// them.
+func Load(fsys fs.FS, fallback string) (*Catalog, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't read templates: %w", err)
+	}
+
+	c := &Catalog{fallback: fallback, messages: map[string]map[string]message{}}
+
+	var tags []language.Tag
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		tag, err := language.Parse(entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("Template directory %q isn't a locale: %w", entry.Name(), err)
+		}
+
+		messages, err := loadLocale(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		c.messages[entry.Name()] = messages
+		c.locales = append(c.locales, entry.Name())
+		tags = append(tags, tag)
+	}
+
+	if _, ok := c.messages[fallback]; !ok {
+		return nil, fmt.Errorf("Templates have no %q directory for the default locale", fallback)
+	}
+
+	c.matcher = language.NewMatcher(tags)
+	return c, nil
+}
+
+func loadLocale(fsys fs.FS, locale string) (map[string]message, error) {
+	htmlPartials, err := fs.Glob(fsys, path.Join(locale, "_*.html"))
+	if err != nil {
+		return nil, err
+	}
+
+	textPartials, err := fs.Glob(fsys, path.Join(locale, "_*.txt"))
+	if err != nil {
+		return nil, err
+	}
+
+	textFiles, err := fs.Glob(fsys, path.Join(locale, "*.txt"))
+	if err != nil {
+		return nil, err
+	}
+
+	messages := map[string]message{}
+	for _, textFile := range textFiles {
+		base := path.Base(textFile)
+		if strings.HasPrefix(base, "_") {
+			continue
+		}
+
+		name := strings.TrimSuffix(base, ".txt")
+		htmlFile := path.Join(locale, name+".html")
+
+		text, err := texttemplate.New(base).ParseFS(fsys, append([]string{textFile}, textPartials...)...)
+		if err != nil {
+			return nil, fmt.Errorf("Couldn't parse template %s: %w", textFile, err)
+		}
+
+		if text.Lookup("subject") == nil {
+			return nil, fmt.Errorf("Template %s doesn't define a subject", textFile)
+		}
+
+		html, err := htmltemplate.New(name+".html").ParseFS(fsys, append([]string{htmlFile}, htmlPartials...)...)
+		if err != nil {
+			return nil, fmt.Errorf("Couldn't parse template %s: %w", htmlFile, err)
+		}
+
+		messages[name] = message{html: html, text: text}
+	}
+
+	return messages, nil
+}
+
+// Locales lists the locales there are templates for.
+func (c *Catalog) Locales() []string {
+	return c.locales
+}
+
+// Render renders the first of names found in the locale best matching
+// preferences, which are BCP 47 tags in order of preference. It falls back
+// to the default locale when none of them match or the message isn't
+// translated.
+func (c *Catalog) Render(names []string, preferences []string, data any) (Rendered, error) {
+	locales := []string{c.fallback}
+	if locale := c.match(preferences); locale != c.fallback {
+		locales = []string{locale, c.fallback}
+	}
+
+	for _, locale := range locales {
+		for _, name := range names {
+			msg, ok := c.messages[locale][name]
+			if !ok {
+				continue
+			}
+
+			return msg.render(locale, data)
+		}
+	}
+
+	return Rendered{}, fmt.Errorf("%w: %s", ErrTemplateNotFound, strings.Join(names, ", "))
+}
+
+func (c *Catalog) match(preferences []string) string {
+	tags := make([]language.Tag, 0, len(preferences))
+	for _, preference := range preferences {
+		if tag, err := language.Parse(preference); err == nil {
+			tags = append(tags, tag)
+		}
+	}
+
+	if len(tags) == 0 {
+		return c.fallback
+	}
+
+	_, index, confidence := c.matcher.Match(tags...)
+	if confidence == language.No {
+		return c.fallback
+	}
+
+	return c.locales[index]
+}
+
+func (m message) render(locale string, data any) (Rendered, error) {
+	var subject, text, html bytes.Buffer
+
+	if err := m.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Rendered{}, fmt.Errorf("template execute: %w", err)
+	}
+
+	if err := m.text.Execute(&text, data); err != nil {
+		return Rendered{}, fmt.Errorf("template execute: %w", err)
+	}
+
+	if err := m.html.Execute(&html, data); err != nil {
+		return Rendered{}, fmt.Errorf("template execute: %w", err)
+	}
+
+	return Rendered{
+		Locale:  locale,
+		Subject: strings.TrimSpace(subject.String()),
+		HTML:    strings.TrimSpace(html.String()),
+		Text:    strings.TrimSpace(text.String()),
+	}, nil
+}
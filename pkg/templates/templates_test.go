@@ -0,0 +1,161 @@
+package templates
+
+import (
+	"context"
+	"onboarding/pkg/config"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCatalog(t *testing.T) *Catalog {
+	c, err := Load(fstest.MapFS{
+		"en/_layout.html":     {Data: []byte(`{{define "layout"}}<h2>{{template "title" .}}</h2>{{end}}`)},
+		"en/greeting.html":    {Data: []byte(`{{define "title"}}Hello {{.Name}}{{end}}{{template "layout" .}}`)},
+		"en/greeting.txt":     {Data: []byte("{{define \"subject\"}}Hi{{end}}\nHello {{.Name}}\n")},
+		"en/farewell.html":    {Data: []byte(`Bye`)},
+		"en/farewell.txt":     {Data: []byte(`{{define "subject"}}Bye{{end}}Bye`)},
+		"de/_layout.html":     {Data: []byte(`{{define "layout"}}<h1>{{template "title" .}}</h1>{{end}}`)},
+		"de/greeting.html":    {Data: []byte(`{{define "title"}}Hallo {{.Name}}{{end}}{{template "layout" .}}`)},
+		"de/greeting.txt":     {Data: []byte(`{{define "subject"}}Hallo{{end}}Hallo {{.Name}}`)},
+		"pt-BR/greeting.txt":  {Data: []byte(`{{define "subject"}}Olá{{end}}Olá {{.Name}}`)},
+		"pt-BR/greeting.html": {Data: []byte(`Olá {{.Name}}`)},
+	}, "en")
+	require.NoError(t, err)
+
+	return c
+}
+
+func TestCatalogRender(t *testing.T) {
+	c := newTestCatalog(t)
+
+	rendered, err := c.Render([]string{"greeting"}, nil, map[string]string{"Name": "<Ann>"})
+	require.NoError(t, err)
+	require.Equal(t, Rendered{
+		Locale:  "en",
+		Subject: "Hi",
+		HTML:    "<h2>Hello &lt;Ann&gt;</h2>",
+		Text:    "Hello <Ann>",
+	}, rendered)
+}
+
+func TestCatalogRenderMatchesLocale(t *testing.T) {
+	c := newTestCatalog(t)
+	data := map[string]string{"Name": "Ann"}
+
+	testCases := []struct {
+		preferences []string
+		locale      string
+	}{
+		{[]string{"de"}, "de"},
+		{[]string{"de-AT"}, "de"},
+		{[]string{"pt"}, "pt-BR"},
+		{[]string{"fr", "de"}, "de"},
+		{[]string{"fr"}, "en"},
+		{[]string{"not a locale"}, "en"},
+	}
+
+	for _, tc := range testCases {
+		rendered, err := c.Render([]string{"greeting"}, tc.preferences, data)
+		require.NoError(t, err, tc.preferences)
+		require.Equal(t, tc.locale, rendered.Locale, tc.preferences)
+	}
+
+	// Messages that aren't translated fall back to the default locale.
+	rendered, err := c.Render([]string{"farewell"}, []string{"de"}, data)
+	require.NoError(t, err)
+	require.Equal(t, "en", rendered.Locale)
+	require.Equal(t, "Bye", rendered.Subject)
+}
+
+func TestCatalogRenderFirstNameFound(t *testing.T) {
+	c := newTestCatalog(t)
+
+	rendered, err := c.Render([]string{"missing", "farewell"}, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, "Bye", rendered.Text)
+
+	_, err = c.Render([]string{"missing"}, nil, nil)
+	require.ErrorIs(t, err, ErrTemplateNotFound)
+}
+
+func TestLoadRejectsInvalidTemplates(t *testing.T) {
+	testCases := map[string]fstest.MapFS{
+		"no default locale": {
+			"de/greeting.txt":  {Data: []byte(`{{define "subject"}}Hallo{{end}}`)},
+			"de/greeting.html": {Data: []byte(`Hallo`)},
+		},
+		"no subject": {
+			"en/greeting.txt":  {Data: []byte(`Hello`)},
+			"en/greeting.html": {Data: []byte(`Hello`)},
+		},
+		"no html": {
+			"en/greeting.txt": {Data: []byte(`{{define "subject"}}Hi{{end}}`)},
+		},
+		"not a locale": {
+			"en/greeting.txt":     {Data: []byte(`{{define "subject"}}Hi{{end}}`)},
+			"en/greeting.html":    {Data: []byte(`Hello`)},
+			"emails/greeting.txt": {Data: []byte(`{{define "subject"}}Hi{{end}}`)},
+		},
+		"syntax error": {
+			"en/greeting.txt":  {Data: []byte(`{{define "subject"}}Hi{{end}}{{.Name`)},
+			"en/greeting.html": {Data: []byte(`Hello`)},
+		},
+	}
+
+	for name, fsys := range testCases {
+		_, err := Load(fsys, "en")
+		require.Error(t, err, name)
+	}
+}
+
+// The built-in templates have every message in every locale.
+func TestDefaultTemplates(t *testing.T) {
+	c, err := New(config.Templates{DefaultLocale: "en"})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"en", "de"}, c.Locales())
+
+	names := []string{
+		"forgot.otp",
+		"verify.otp",
+		"change_email.otp",
+		"verify_phone.otp",
+		"magic.link",
+		"locked.notice",
+		"change_email.notice",
+		"change_phone.notice",
+		"change_channel.notice",
+	}
+	data := map[string]any{
+		"Code":     "123456",
+		"Minutes":  5,
+		"Link":     "https://example.com/magic?token=abc",
+		"NewEmail": "new@example.com",
+		"Phone":    "+15550100",
+		"Channel":  "sms",
+	}
+
+	for _, locale := range c.Locales() {
+		for _, name := range names {
+			rendered, err := c.Render([]string{name}, []string{locale}, data)
+			require.NoError(t, err, name)
+			require.Equal(t, locale, rendered.Locale, name)
+			require.NotEmpty(t, rendered.Subject, name)
+			require.NotEmpty(t, rendered.HTML, name)
+			require.NotEmpty(t, rendered.Text, name)
+			require.NotContains(t, rendered.HTML, "<no value>", name)
+			require.NotContains(t, rendered.Text, "<no value>", name)
+		}
+	}
+}
+
+func TestLocales(t *testing.T) {
+	require.Nil(t, Locales(context.Background()))
+
+	ctx := WithLocales(context.Background(), "de", "en")
+	require.Equal(t, []string{"de", "en"}, Locales(ctx))
+
+	require.Equal(t, []string{"de-CH", "de", "en"}, ParseAcceptLanguage("en;q=0.5, de-CH, de;q=0.9"))
+	require.Empty(t, ParseAcceptLanguage(""))
+}
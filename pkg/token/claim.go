@@ -17,24 +17,50 @@ func (e JWTError) Error() string {
 const JWTExpirationError = JWTError("JWT token is expired")
 
 type CustomClaims struct {
-	TokenID uuid.UUID `json:"token_id"`
-	UserID  uuid.UUID `json:"user_id"`
-	Scope   Scope     `json:"scope"`
+	TokenID   uuid.UUID `json:"token_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Scope     Scope     `json:"scope"`
+	FamilyID  uuid.UUID `json:"family_id,omitempty"`
+	SessionID uuid.UUID `json:"session_id,omitempty"`
+	// Role is the role of the user when the token was issued.
+	Role string `json:"role,omitempty"`
 	jwt.Claims
 }
 
 type Scope string
 
 const (
-	ScopeAccess  = Scope("access")
-	ScopeRefresh = Scope("refresh")
+	ScopeAccess     = Scope("access")
+	ScopeRefresh    = Scope("refresh")
+	ScopeMFAPending = Scope("mfa_pending")
 )
 
 type Expectation func(parsed CustomClaims) error
 
+type TokenOption func(claim *CustomClaims)
+
+// WithAudience replaces the default audience of the token.
+func WithAudience(audience ...string) TokenOption {
+	return func(claim *CustomClaims) {
+		claim.Audience = jwt.Audience(audience)
+	}
+}
+
+// WithRole carries the role of the user in the token.
+func WithRole(role string) TokenOption {
+	return func(claim *CustomClaims) {
+		claim.Role = role
+	}
+}
+
 type JWTToken struct {
 	SignedToken string
 	Claims      CustomClaims
 	ExpireAt    time.Time
 	Scheme      string
 }
+
+type TokenPair struct {
+	AccessToken  *JWTToken
+	RefreshToken *JWTToken
+}
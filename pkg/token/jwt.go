@@ -1,13 +1,12 @@
 package token
 
 import (
-	"crypto/rsa"
-	"crypto/x509"
+	"crypto"
 	"encoding/json"
-	"encoding/pem"
 	"errors"
 	"fmt"
 	"onboarding/pkg/config"
+	"slices"
 	"time"
 
 	"github.com/go-jose/go-jose/v4"
@@ -19,53 +18,185 @@ const (
 	AuthorizationHeader = "authorization"
 	BearerScheme        = "bearer"
 	JWTClaim            = "jwt_claim"
+	AccessTokenCookie   = "access_token"
+	RefreshTokenCookie  = "refresh_token"
 )
 
 type JWT interface {
-	CreateAccessToken(usrUUID uuid.UUID) (*JWTToken, error)
-	// TODO: IMPLEMENT
-	CreateRefreshToken(usrUUID uuid.UUID) (*JWTToken, error)
+	CreateAccessToken(usrUUID uuid.UUID, sessionID uuid.UUID, opts ...TokenOption) (*JWTToken, error)
+	CreateRefreshToken(usrUUID uuid.UUID, familyID uuid.UUID) (*JWTToken, error)
+	CreateMFAToken(usrUUID uuid.UUID) (*JWTToken, error)
 	VerifyToken(token string, expectation ...Expectation) (*CustomClaims, error)
+	// JWKS returns the public keys tokens can be verified with.
+	JWKS() jose.JSONWebKeySet
 }
 
 type IJWT struct {
 	cfg        config.Token
-	publicKey  *rsa.PublicKey
-	privateKey *rsa.PrivateKey
+	keyID      string
+	algorithm  jose.SignatureAlgorithm
+	algorithms []jose.SignatureAlgorithm
+	publicKey  crypto.PublicKey
+	privateKey crypto.Signer
+	publicKeys map[string]verificationKey
+	jwks       jose.JSONWebKeySet
+}
+
+type verificationKey struct {
+	key       crypto.PublicKey
+	algorithm jose.SignatureAlgorithm
 }
 
 func NewJWT(cfg config.Token) (JWT, error) {
-	j := &IJWT{cfg: cfg}
+	j := &IJWT{cfg: cfg, publicKeys: map[string]verificationKey{}}
 	var err error
 
-	j.publicKey, err = ParseRSAPublicKeyFromPEM(cfg.PublicKey)
+	j.publicKey, err = ParsePublicKeyFromPEM(cfg.PublicKey)
 	if err != nil {
 		return nil, fmt.Errorf("Couldn't parse public key: %w", err)
 	}
 
-	j.privateKey, err = ParseRSAPrivateKeyFromPEM(cfg.PrivateKey)
+	j.privateKey, err = ParsePrivateKeyFromPEM(cfg.PrivateKey)
 	if err != nil {
 		return nil, fmt.Errorf("Couldn't parse private key: %w", err)
 	}
 
+	j.algorithm, err = KeyAlgorithm(j.privateKey.Public())
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't use private key: %w", err)
+	}
+
+	if cfg.Algorithm != "" && jose.SignatureAlgorithm(cfg.Algorithm) != j.algorithm {
+		return nil, fmt.Errorf("Private key can't sign %s, it's a %s key", cfg.Algorithm, j.algorithm)
+	}
+
+	j.keyID = cfg.KeyID
+	if j.keyID == "" {
+		j.keyID, err = KeyThumbprint(j.publicKey)
+		if err != nil {
+			return nil, fmt.Errorf("Couldn't create key id: %w", err)
+		}
+	}
+
+	// Tokens signed by a key the JWKS doesn't publish can't be verified.
+	if publicKey, ok := j.privateKey.Public().(interface{ Equal(crypto.PublicKey) bool }); !ok || !publicKey.Equal(j.publicKey) {
+		return nil, errors.New("Public key doesn't match private key")
+	}
+
+	if err := j.addVerificationKey(j.keyID, j.publicKey); err != nil {
+		return nil, err
+	}
+
+	for _, k := range cfg.VerificationKeys {
+		publicKey, err := ParsePublicKeyFromPEM(k.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("Couldn't parse verification key %s: %w", k.KeyID, err)
+		}
+
+		if err := j.addVerificationKey(k.KeyID, publicKey); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, alg := range cfg.Algorithms {
+		j.algorithms = append(j.algorithms, jose.SignatureAlgorithm(alg))
+	}
+
+	if len(j.algorithms) > 0 && !slices.Contains(j.algorithms, j.algorithm) {
+		return nil, fmt.Errorf("Accepted algorithms don't include the signing algorithm %s", j.algorithm)
+	}
+
+	if len(j.algorithms) == 0 {
+		for _, k := range j.jwks.Keys {
+			alg := jose.SignatureAlgorithm(k.Algorithm)
+			if !slices.Contains(j.algorithms, alg) {
+				j.algorithms = append(j.algorithms, alg)
+			}
+		}
+	}
+
 	return j, nil
 }
 
+func (j *IJWT) addVerificationKey(keyID string, publicKey crypto.PublicKey) error {
+	if _, ok := j.publicKeys[keyID]; ok {
+		return fmt.Errorf("Duplicate key id %s", keyID)
+	}
+
+	alg, err := KeyAlgorithm(publicKey)
+	if err != nil {
+		return fmt.Errorf("Couldn't use key %s: %w", keyID, err)
+	}
+
+	j.publicKeys[keyID] = verificationKey{key: publicKey, algorithm: alg}
+	j.jwks.Keys = append(j.jwks.Keys, jose.JSONWebKey{
+		Key:       publicKey,
+		KeyID:     keyID,
+		Algorithm: string(alg),
+		Use:       "sig",
+	})
+
+	return nil
+}
+
 func (j *IJWT) signer() (jose.Signer, error) {
 	opts := (&jose.SignerOptions{}).WithType("JWT")
 	return jose.NewSigner(
 		jose.SigningKey{
-			Algorithm: jose.RS256,
-			Key:       j.privateKey,
+			Algorithm: j.algorithm,
+			Key: jose.JSONWebKey{
+				Key:   j.privateKey,
+				KeyID: j.keyID,
+			},
 		},
 		opts,
 	)
 }
 
-func (j *IJWT) createJWTToken(claim CustomClaims, duration time.Duration) (*JWTToken, error) {
+// verificationKey picks the public key by the kid header. Tokens signed
+// before key IDs were introduced carry no kid and fall back to the active key.
+// The header algorithm has to be the one the key was published for.
+func (j *IJWT) verificationKey(parsed *jose.JSONWebSignature) (crypto.PublicKey, error) {
+	if len(parsed.Signatures) != 1 {
+		return nil, errors.New("Expected exactly one signature")
+	}
+
+	header := parsed.Signatures[0].Header
+
+	keyID := header.KeyID
+	if keyID == "" {
+		keyID = j.keyID
+	}
+
+	k, ok := j.publicKeys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("Unknown key id %s", keyID)
+	}
+
+	if jose.SignatureAlgorithm(header.Algorithm) != k.algorithm {
+		return nil, fmt.Errorf("Key %s doesn't verify %s", keyID, header.Algorithm)
+	}
+
+	return k.key, nil
+}
+
+func (j *IJWT) JWKS() jose.JSONWebKeySet {
+	return j.jwks
+}
+
+func (j *IJWT) createJWTToken(claim CustomClaims, duration time.Duration, opts ...TokenOption) (*JWTToken, error) {
 	now := time.Now()
 	exp := now.Add(duration)
 
+	claim.Issuer = j.cfg.Issuer
+	if j.cfg.Audience != "" {
+		opts = append([]TokenOption{WithAudience(j.cfg.Audience)}, opts...)
+	}
+
+	for _, opt := range opts {
+		opt(&claim)
+	}
+
 	claim.IssuedAt = jwt.NewNumericDate(now)
 	claim.NotBefore = jwt.NewNumericDate(now)
 	claim.Expiry = jwt.NewNumericDate(exp)
@@ -103,13 +234,16 @@ func AccessTokenExpectation() Expectation {
 	})
 }
 
-func (j *IJWT) CreateAccessToken(usrUUID uuid.UUID) (*JWTToken, error) {
+// CreateAccessToken issues an access token for the default audience unless
+// WithAudience scopes it to other services.
+func (j *IJWT) CreateAccessToken(usrUUID uuid.UUID, sessionID uuid.UUID, opts ...TokenOption) (*JWTToken, error) {
 	claim := CustomClaims{
-		UserID: usrUUID,
-		Scope:  Scope(ScopeAccess),
+		UserID:    usrUUID,
+		Scope:     Scope(ScopeAccess),
+		SessionID: sessionID,
 	}
 
-	return j.createJWTToken(claim, j.cfg.AccessTokenDuration)
+	return j.createJWTToken(claim, j.cfg.AccessTokenDuration, opts...)
 }
 
 func RefreshTokenExpectation() Expectation {
@@ -121,22 +255,50 @@ func RefreshTokenExpectation() Expectation {
 	})
 }
 
-func (j *IJWT) CreateRefreshToken(usrUUID uuid.UUID) (*JWTToken, error) {
+// CreateRefreshToken issues a refresh token belonging to the given family.
+// Every token rotated out of the same login shares its family ID.
+func (j *IJWT) CreateRefreshToken(usrUUID uuid.UUID, familyID uuid.UUID) (*JWTToken, error) {
 	claim := CustomClaims{
-		UserID: usrUUID,
-		Scope:  Scope(ScopeRefresh),
+		UserID:   usrUUID,
+		Scope:    Scope(ScopeRefresh),
+		FamilyID: familyID,
 	}
 
 	return j.createJWTToken(claim, j.cfg.RefreshTokenDuration)
 }
 
+func MFATokenExpectation() Expectation {
+	return Expectation(func(parsed CustomClaims) error {
+		if parsed.Scope != ScopeMFAPending {
+			return fmt.Errorf("Scope %s to have %s", ScopeMFAPending, parsed.Scope)
+		}
+		return nil
+	})
+}
+
+// CreateMFAToken issues the short-lived token handed out after the password
+// check when the user still has to pass a second factor.
+func (j *IJWT) CreateMFAToken(usrUUID uuid.UUID) (*JWTToken, error) {
+	claim := CustomClaims{
+		UserID: usrUUID,
+		Scope:  ScopeMFAPending,
+	}
+
+	return j.createJWTToken(claim, j.cfg.MFATokenDuration)
+}
+
 func (j *IJWT) VerifyToken(token string, expectations ...Expectation) (*CustomClaims, error) {
-	parsed, err := jose.ParseSigned(token, []jose.SignatureAlgorithm{jose.RS256})
+	parsed, err := jose.ParseSigned(token, j.algorithms)
 	if err != nil {
 		return nil, fmt.Errorf("Couldn't parse signed token: %w", err)
 	}
 
-	rawPayload, err := parsed.Verify(j.publicKey)
+	publicKey, err := j.verificationKey(parsed)
+	if err != nil {
+		return nil, fmt.Errorf("verification: %w", err)
+	}
+
+	rawPayload, err := parsed.Verify(publicKey)
 	if err != nil {
 		return nil, fmt.Errorf("verification: %w", err)
 	}
@@ -146,7 +308,15 @@ func (j *IJWT) VerifyToken(token string, expectations ...Expectation) (*CustomCl
 		return nil, fmt.Errorf("Couldn't unmarshal claims: %w", err)
 	}
 
-	if err := c.Validate(jwt.Expected{Time: time.Now().UTC()}); err != nil {
+	expected := jwt.Expected{
+		Issuer: j.cfg.Issuer,
+		Time:   time.Now().UTC(),
+	}
+	if j.cfg.Audience != "" {
+		expected.AnyAudience = jwt.Audience{j.cfg.Audience}
+	}
+
+	if err := c.ValidateWithLeeway(expected, j.cfg.Leeway); err != nil {
 		if err.Error() == jwt.ErrExpired.Error() {
 			return nil, JWTExpirationError
 		}
@@ -178,44 +348,3 @@ func (j *IJWT) VerifyToken(token string, expectations ...Expectation) (*CustomCl
 
 	return &c, nil
 }
-
-func ParseRSAPrivateKeyFromPEM(pemStr string) (*rsa.PrivateKey, error) {
-	block, _ := pem.Decode([]byte(pemStr))
-	if block == nil {
-		return nil, errors.New("Invalid pem")
-	}
-
-	if k, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
-		return k, nil
-	}
-
-	ifc, err := x509.ParsePKCS8PrivateKey(block.Bytes)
-	if err != nil {
-		return nil, err
-	}
-
-	k, ok := ifc.(*rsa.PrivateKey)
-	if !ok {
-		return nil, errors.New("Not RSA private key")
-	}
-
-	return k, nil
-}
-
-func ParseRSAPublicKeyFromPEM(pemStr string) (*rsa.PublicKey, error) {
-	block, _ := pem.Decode([]byte(pemStr))
-	if block == nil {
-		return nil, errors.New("Invalid pem")
-	}
-
-	ifc, err := x509.ParsePKIXPublicKey(block.Bytes)
-	if err == nil {
-		k, ok := ifc.(*rsa.PublicKey)
-		if !ok {
-			return nil, errors.New("Not RSA public key")
-		}
-		return k, nil
-	}
-
-	return x509.ParsePKCS1PublicKey(block.Bytes)
-}
@@ -27,7 +27,7 @@ func TestExpiredJWTToken(t *testing.T) {
 	require.NoError(t, err)
 
 	usrUUID := uuid.New()
-	token, err := jwtImpl.CreateAccessToken(usrUUID)
+	token, err := jwtImpl.CreateAccessToken(usrUUID, uuid.New())
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
 
@@ -37,6 +37,313 @@ func TestExpiredJWTToken(t *testing.T) {
 	require.Nil(t, claim)
 }
 
+func TestRefreshJWTToken(t *testing.T) {
+	private, public := common.GenerateRSAKey(t)
+
+	cfg := config.Token{
+		AccessTokenDuration:  time.Minute,
+		RefreshTokenDuration: time.Hour,
+		PrivateKey:           private,
+		PublicKey:            public,
+	}
+
+	jwtImpl, err := NewJWT(cfg)
+	require.NoError(t, err)
+
+	usrUUID := uuid.New()
+	familyID := uuid.New()
+	token, err := jwtImpl.CreateRefreshToken(usrUUID, familyID)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.WithinDuration(t, time.Now().Add(cfg.RefreshTokenDuration), token.ExpireAt, time.Second)
+
+	claim, err := jwtImpl.VerifyToken(token.SignedToken, RefreshTokenExpectation())
+	require.NoError(t, err)
+	require.Equal(t, usrUUID, claim.UserID)
+	require.Equal(t, ScopeRefresh, claim.Scope)
+	require.Equal(t, familyID, claim.FamilyID)
+
+	claim, err = jwtImpl.VerifyToken(token.SignedToken, AccessTokenExpectation())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Failed expectation")
+	require.Nil(t, claim)
+}
+
+func TestMFAJWTToken(t *testing.T) {
+	private, public := common.GenerateRSAKey(t)
+
+	jwtImpl, err := NewJWT(config.Token{
+		MFATokenDuration: 5 * time.Minute,
+		PrivateKey:       private,
+		PublicKey:        public,
+	})
+	require.NoError(t, err)
+
+	usrUUID := uuid.New()
+	token, err := jwtImpl.CreateMFAToken(usrUUID)
+	require.NoError(t, err)
+
+	claim, err := jwtImpl.VerifyToken(token.SignedToken, MFATokenExpectation())
+	require.NoError(t, err)
+	require.Equal(t, usrUUID, claim.UserID)
+	require.Equal(t, ScopeMFAPending, claim.Scope)
+
+	claim, err = jwtImpl.VerifyToken(token.SignedToken, AccessTokenExpectation())
+	require.Error(t, err)
+	require.Nil(t, claim)
+}
+
+func TestJWTKeyRotation(t *testing.T) {
+	oldPrivate, oldPublic := common.GenerateRSAKey(t)
+	newPrivate, newPublic := common.GenerateRSAKey(t)
+
+	oldJWT, err := NewJWT(config.Token{
+		AccessTokenDuration: time.Minute,
+		PrivateKey:          oldPrivate,
+		PublicKey:           oldPublic,
+		KeyID:               "old",
+	})
+	require.NoError(t, err)
+
+	newJWT, err := NewJWT(config.Token{
+		AccessTokenDuration: time.Minute,
+		PrivateKey:          newPrivate,
+		PublicKey:           newPublic,
+		VerificationKeys: []config.VerificationKey{
+			{KeyID: "old", PublicKey: oldPublic},
+		},
+	})
+	require.NoError(t, err)
+
+	jwks := newJWT.JWKS()
+	require.Len(t, jwks.Keys, 2)
+	require.Len(t, jwks.Key("old"), 1)
+	require.True(t, jwks.Keys[0].IsPublic())
+
+	usrUUID := uuid.New()
+	oldToken, err := oldJWT.CreateAccessToken(usrUUID, uuid.New())
+	require.NoError(t, err)
+
+	claim, err := newJWT.VerifyToken(oldToken.SignedToken, AccessTokenExpectation())
+	require.NoError(t, err)
+	require.Equal(t, usrUUID, claim.UserID)
+
+	newToken, err := newJWT.CreateAccessToken(usrUUID, uuid.New())
+	require.NoError(t, err)
+
+	parsed, err := jose.ParseSigned(newToken.SignedToken, []jose.SignatureAlgorithm{jose.RS256})
+	require.NoError(t, err)
+	require.Equal(t, jwks.Keys[0].KeyID, parsed.Signatures[0].Header.KeyID)
+
+	claim, err = oldJWT.VerifyToken(newToken.SignedToken, AccessTokenExpectation())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Unknown key id")
+	require.Nil(t, claim)
+}
+
+func TestJWTSigningAlgorithms(t *testing.T) {
+	testCases := []struct {
+		name      string
+		algorithm jose.SignatureAlgorithm
+		key       func(t *testing.T) (string, string)
+	}{
+		{
+			name:      "RS256",
+			algorithm: jose.RS256,
+			key:       common.GenerateRSAKey,
+		},
+		{
+			name:      "ES256",
+			algorithm: jose.ES256,
+			key:       generateECKey,
+		},
+		{
+			name:      "EdDSA",
+			algorithm: jose.EdDSA,
+			key:       generateEd25519Key,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			private, public := tc.key(t)
+
+			jwtImpl, err := NewJWT(config.Token{
+				AccessTokenDuration: time.Minute,
+				PrivateKey:          private,
+				PublicKey:           public,
+				Algorithm:           string(tc.algorithm),
+			})
+			require.NoError(t, err)
+			require.Equal(t, string(tc.algorithm), jwtImpl.JWKS().Keys[0].Algorithm)
+
+			usrUUID := uuid.New()
+			token, err := jwtImpl.CreateAccessToken(usrUUID, uuid.New())
+			require.NoError(t, err)
+
+			parsed, err := jose.ParseSigned(token.SignedToken, []jose.SignatureAlgorithm{tc.algorithm})
+			require.NoError(t, err)
+			require.Equal(t, string(tc.algorithm), parsed.Signatures[0].Header.Algorithm)
+
+			claim, err := jwtImpl.VerifyToken(token.SignedToken, AccessTokenExpectation())
+			require.NoError(t, err)
+			require.Equal(t, usrUUID, claim.UserID)
+		})
+	}
+}
+
+func TestJWTAlgorithmNotAccepted(t *testing.T) {
+	ecPrivate, ecPublic := generateECKey(t)
+	rsaPrivate, rsaPublic := common.GenerateRSAKey(t)
+
+	signingJWT, err := NewJWT(config.Token{
+		AccessTokenDuration: time.Minute,
+		PrivateKey:          ecPrivate,
+		PublicKey:           ecPublic,
+		KeyID:               "ec",
+	})
+	require.NoError(t, err)
+
+	token, err := signingJWT.CreateAccessToken(uuid.New(), uuid.New())
+	require.NoError(t, err)
+
+	// The verifier knows the key but only accepts its own algorithm.
+	verifyingJWT, err := NewJWT(config.Token{
+		AccessTokenDuration: time.Minute,
+		PrivateKey:          rsaPrivate,
+		PublicKey:           rsaPublic,
+		Algorithms:          []string{string(jose.RS256)},
+		VerificationKeys: []config.VerificationKey{
+			{KeyID: "ec", PublicKey: ecPublic},
+		},
+	})
+	require.NoError(t, err)
+
+	claim, err := verifyingJWT.VerifyToken(token.SignedToken, AccessTokenExpectation())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `unexpected signature algorithm "ES256"`)
+	require.Nil(t, claim)
+
+	_, err = NewJWT(config.Token{
+		PrivateKey: ecPrivate,
+		PublicKey:  ecPublic,
+		Algorithm:  string(jose.EdDSA),
+	})
+	require.Error(t, err)
+
+	// A list leaving out the signing algorithm would reject every own token.
+	_, err = NewJWT(config.Token{
+		PrivateKey: ecPrivate,
+		PublicKey:  ecPublic,
+		Algorithms: []string{string(jose.RS256)},
+	})
+	require.ErrorContains(t, err, "signing algorithm ES256")
+}
+
+func TestJWTKeyPairMismatch(t *testing.T) {
+	private, _ := generateECKey(t)
+	_, otherPublic := generateECKey(t)
+
+	_, err := NewJWT(config.Token{PrivateKey: private, PublicKey: otherPublic})
+	require.ErrorContains(t, err, "Public key doesn't match private key")
+}
+
+func TestJWTIssuerAndAudience(t *testing.T) {
+	private, public := common.GenerateRSAKey(t)
+
+	newJWT := func(issuer string, audience string) JWT {
+		jwtImpl, err := NewJWT(config.Token{
+			AccessTokenDuration: time.Minute,
+			PrivateKey:          private,
+			PublicKey:           public,
+			Issuer:              issuer,
+			Audience:            audience,
+		})
+		require.NoError(t, err)
+		return jwtImpl
+	}
+
+	onboarding := newJWT("https://auth.example.com", "onboarding")
+	billing := newJWT("https://auth.example.com", "billing")
+	otherIssuer := newJWT("https://other.example.com", "onboarding")
+
+	token, err := onboarding.CreateAccessToken(uuid.New(), uuid.New())
+	require.NoError(t, err)
+	require.Equal(t, "https://auth.example.com", token.Claims.Issuer)
+	require.Equal(t, jwt.Audience{"onboarding"}, token.Claims.Audience)
+
+	_, err = onboarding.VerifyToken(token.SignedToken, AccessTokenExpectation())
+	require.NoError(t, err)
+
+	_, err = billing.VerifyToken(token.SignedToken, AccessTokenExpectation())
+	require.ErrorIs(t, err, jwt.ErrInvalidAudience)
+
+	_, err = otherIssuer.VerifyToken(token.SignedToken, AccessTokenExpectation())
+	require.ErrorIs(t, err, jwt.ErrInvalidIssuer)
+
+	billingToken, err := onboarding.CreateAccessToken(uuid.New(), uuid.New(), WithAudience("billing"))
+	require.NoError(t, err)
+
+	_, err = billing.VerifyToken(billingToken.SignedToken, AccessTokenExpectation())
+	require.NoError(t, err)
+
+	_, err = onboarding.VerifyToken(billingToken.SignedToken, AccessTokenExpectation())
+	require.ErrorIs(t, err, jwt.ErrInvalidAudience)
+}
+
+func TestJWTRoleClaim(t *testing.T) {
+	private, public := common.GenerateRSAKey(t)
+
+	jwtImpl, err := NewJWT(config.Token{
+		AccessTokenDuration: time.Minute,
+		PrivateKey:          private,
+		PublicKey:           public,
+	})
+	require.NoError(t, err)
+
+	token, err := jwtImpl.CreateAccessToken(uuid.New(), uuid.New(), WithRole("admin"))
+	require.NoError(t, err)
+
+	claim, err := jwtImpl.VerifyToken(token.SignedToken, AccessTokenExpectation())
+	require.NoError(t, err)
+	require.Equal(t, "admin", claim.Role)
+
+	token, err = jwtImpl.CreateAccessToken(uuid.New(), uuid.New())
+	require.NoError(t, err)
+
+	claim, err = jwtImpl.VerifyToken(token.SignedToken, AccessTokenExpectation())
+	require.NoError(t, err)
+	require.Empty(t, claim.Role)
+}
+
+func TestJWTLeeway(t *testing.T) {
+	private, public := common.GenerateRSAKey(t)
+
+	cfg := config.Token{
+		AccessTokenDuration: -30 * time.Second,
+		PrivateKey:          private,
+		PublicKey:           public,
+	}
+
+	jwtImpl, err := NewJWT(cfg)
+	require.NoError(t, err)
+
+	token, err := jwtImpl.CreateAccessToken(uuid.New(), uuid.New())
+	require.NoError(t, err)
+
+	_, err = jwtImpl.VerifyToken(token.SignedToken, AccessTokenExpectation())
+	require.EqualError(t, err, JWTExpirationError.Error())
+
+	cfg.Leeway = time.Minute
+	jwtImpl, err = NewJWT(cfg)
+	require.NoError(t, err)
+
+	_, err = jwtImpl.VerifyToken(token.SignedToken, AccessTokenExpectation())
+	require.NoError(t, err)
+}
+
 func TestInvalidJWTToken(t *testing.T) {
 	testCases := []struct {
 		name        string
@@ -0,0 +1,129 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+// KeyAlgorithm returns the signature algorithm tokens are signed with for the
+// given key type: RS256 for RSA, ES256 for ECDSA P-256 and EdDSA for Ed25519.
+func KeyAlgorithm(publicKey crypto.PublicKey) (jose.SignatureAlgorithm, error) {
+	switch k := publicKey.(type) {
+	case *rsa.PublicKey:
+		return jose.RS256, nil
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() {
+			return "", fmt.Errorf("Unsupported ECDSA curve %s", k.Curve.Params().Name)
+		}
+		return jose.ES256, nil
+	case ed25519.PublicKey:
+		return jose.EdDSA, nil
+	default:
+		return "", fmt.Errorf("Unsupported key type %T", publicKey)
+	}
+}
+
+// ParsePrivateKeyFromPEM accepts PKCS1 RSA, SEC1 ECDSA and PKCS8 RSA, ECDSA
+// or Ed25519 private keys.
+func ParsePrivateKeyFromPEM(pemStr string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(pemStr))
+	if block == nil {
+		return nil, errors.New("Invalid pem")
+	}
+
+	if k, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return k, nil
+	}
+
+	if k, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return k, nil
+	}
+
+	ifc, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	k, ok := ifc.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("Unsupported private key type %T", ifc)
+	}
+
+	return k, nil
+}
+
+// ParsePublicKeyFromPEM accepts PKIX RSA, ECDSA or Ed25519 public keys and
+// PKCS1 RSA public keys.
+func ParsePublicKeyFromPEM(pemStr string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(pemStr))
+	if block == nil {
+		return nil, errors.New("Invalid pem")
+	}
+
+	if k, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return k, nil
+	}
+
+	return x509.ParsePKCS1PublicKey(block.Bytes)
+}
+
+// KeyThumbprint derives a key ID from the RFC 7638 thumbprint of the key.
+func KeyThumbprint(publicKey crypto.PublicKey) (string, error) {
+	thumbprint, err := (&jose.JSONWebKey{Key: publicKey}).Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(thumbprint), nil
+}
+
+func ParseRSAPrivateKeyFromPEM(pemStr string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(pemStr))
+	if block == nil {
+		return nil, errors.New("Invalid pem")
+	}
+
+	if k, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return k, nil
+	}
+
+	ifc, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	k, ok := ifc.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("Not RSA private key")
+	}
+
+	return k, nil
+}
+
+func ParseRSAPublicKeyFromPEM(pemStr string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(pemStr))
+	if block == nil {
+		return nil, errors.New("Invalid pem")
+	}
+
+	ifc, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err == nil {
+		k, ok := ifc.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("Not RSA public key")
+		}
+		return k, nil
+	}
+
+	return x509.ParsePKCS1PublicKey(block.Bytes)
+}
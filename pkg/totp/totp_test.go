@@ -0,0 +1,65 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeRFC6238(t *testing.T) {
+	// RFC 6238 appendix B SHA1 vectors, truncated to 6 digits.
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	testCases := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1111111111, code: "050471"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+	}
+
+	for _, tc := range testCases {
+		code, err := Code(secret, Step(time.Unix(tc.unix, 0)))
+		require.NoError(t, err)
+		require.Equal(t, tc.code, code)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+
+	now := time.Now()
+	previous, err := Code(secret, Step(now)-1)
+	require.NoError(t, err)
+
+	step, ok, err := Validate(secret, previous, now, 1)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, Step(now)-1, step)
+
+	_, ok, err = Validate(secret, previous, now, 0)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, _, err = Validate("not base32!", previous, now, 1)
+	require.Error(t, err)
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Onboarding", "user@example.com", "JBSWY3DPEHPK3PXP")
+
+	parsed, err := url.Parse(uri)
+	require.NoError(t, err)
+	require.Equal(t, "otpauth", parsed.Scheme)
+	require.Equal(t, "totp", parsed.Host)
+	require.Equal(t, "/Onboarding:user@example.com", parsed.Path)
+	require.Equal(t, "JBSWY3DPEHPK3PXP", parsed.Query().Get("secret"))
+	require.Equal(t, "Onboarding", parsed.Query().Get("issuer"))
+}